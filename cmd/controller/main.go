@@ -32,16 +32,23 @@ var (
 
 func bindControllerFlags(f *controller.Flags, fs *flag.FlagSet) {
 	fs.StringVar(&f.KeyPrefix, "key-prefix", "sealed-secrets-key", "Prefix used to name keys.")
-	fs.IntVar(&f.KeySize, "key-size", 4096, "Size of encryption key.")
+	fs.StringVar(&f.KeyType, "key-type", controller.KeyTypeRSA, "Type of newly generated sealing keys (rsa|ec-p256|rsa+mlkem768|ec-p256+mlkem768). The +mlkem768 types add an ML-KEM-768 key for post-quantum hybrid sealing. Existing keys of any type keep decrypting.")
+	fs.IntVar(&f.KeySize, "key-size", 4096, "Size of encryption key (RSA only).")
 	fs.DurationVar(&f.ValidFor, "key-ttl", 10*365*24*time.Hour, "Duration that certificate is valid for.")
 	fs.StringVar(&f.MyCN, "my-cn", "", "Common name to be used as issuer/subject DN in generated certificate.")
 
 	fs.DurationVar(&f.KeyRenewPeriod, "key-renew-period", defaultKeyRenewPeriod, "New key generation period (automatic rotation deactivated if 0)")
+	fs.StringVar(&f.KeyRenewSchedule, "key-renew-schedule", "", "Cron expression (minute hour day-of-month month day-of-week, e.g. \"0 2 * * MON#1\" for the first Monday of the month at 02:00) of new key generation, in UTC unless prefixed with CRON_TZ=<zone>. Overrides key-renew-period.")
+	fs.DurationVar(&f.KeyActivationGracePeriod, "key-activation-grace-period", 0, "Stage new keys for this long before sealing with them, publishing their certificate at /v1/cert-upcoming.pem meanwhile, so that clients and replicas pick it up first (new keys are used right away if 0).")
+	fs.DurationVar(&f.KeyRenewBeforeExpiry, "key-renew-before-expiry", 0, "Renew the key this long before its certificate expires, if that's earlier than scheduled, and warn when half of it is left (a tenth of key-ttl if 0, deactivated if negative).")
+	fs.StringVar(&f.KeySets, "key-sets", "", "Comma-separated list of named key sets, each with its own keys. Namespaces labelled with sealedsecrets.bitnami.com/key-set=<name> are (un)sealed with the keys of that set, the others with the default keys.")
 	fs.StringVar(&f.KeyOrderPriority, "key-order-priority", defaultKeyOrderPriority, "Ordering of keys based on NotBefore certificate attribute or secret creation timestamp.")
 	fs.BoolVar(&f.AcceptV1Data, "accept-deprecated-v1-data", true, "Accept deprecated V1 data field.")
 	fs.StringVar(&f.KeyCutoffTime, "key-cutoff-time", "", "Create a new key if latest one is older than this cutoff time. RFC1123 format with numeric timezone expected.")
 	fs.BoolVar(&f.NamespaceAll, "all-namespaces", true, "Scan all namespaces or only the current namespace (default=true).")
 	fs.StringVar(&f.AdditionalNamespaces, "additional-namespaces", "", "Comma-separated list of additional namespaces to be scanned.")
+	fs.StringVar(&f.NamespaceSelector, "namespace-selector", "", "Label selector of the namespaces to be scanned, followed as namespaces are created, relabelled or deleted.")
+	fs.StringVar(&f.ExcludeNamespaces, "exclude-namespaces", "", "Comma-separated list of namespaces not to be scanned.")
 	fs.StringVar(&f.LabelSelector, "label-selector", "", "Label selector which can be used to filter sealed secrets.")
 	fs.IntVar(&f.RateLimitPerSecond, "rate-limit", 2, "Number of allowed sustained request per second for verify endpoint")
 	fs.IntVar(&f.RateLimitBurst, "rate-limit-burst", 2, "Number of requests allowed to exceed the rate limit per second for verify endpoint")
@@ -52,6 +59,11 @@ func bindControllerFlags(f *controller.Flags, fs *flag.FlagSet) {
 
 	fs.BoolVar(&f.UpdateStatus, "update-status", true, "beta: if true, the controller will update the status sub-resource whenever it processes a sealed secret")
 	fs.BoolVar(&f.WatchForSecrets, "watch-for-secrets", false, "beta: If this is true, the controller will watch for key secrets. This is useful if you create the key secrets externally.")
+	fs.BoolVar(&f.LeaderElect, "leader-elect", false, "Elect a leader among the controller replicas with a Lease, so that only the leader generates keys and unseals SealedSecrets, while all of them serve the HTTP API. Implies watch-for-secrets.")
+	fs.StringVar(&f.LeaderElectLeaseName, "leader-elect-lease-name", "sealed-secrets-controller", "Name of the Lease used for leader election, in the namespace of the controller.")
+	fs.DurationVar(&f.LeaderElectLeaseDuration, "leader-elect-lease-duration", 15*time.Second, "How long followers wait before taking over from a leader which stopped renewing its Lease.")
+	fs.DurationVar(&f.LeaderElectRenewDeadline, "leader-elect-renew-deadline", 10*time.Second, "How long the leader retries renewing its Lease before giving up the leadership.")
+	fs.DurationVar(&f.LeaderElectRetryPeriod, "leader-elect-retry-period", 2*time.Second, "How long replicas wait between attempts to acquire or renew the Lease.")
 
 	fs.BoolVar(&f.SkipRecreate, "skip-recreate", false, "if true the controller will skip listening for managed secret changes to recreate them. This helps on limited permission environments.")
 
@@ -62,10 +74,28 @@ func bindControllerFlags(f *controller.Flags, fs *flag.FlagSet) {
 	fs.DurationVar(&f.KeyRenewPeriod, "rotate-period", defaultKeyRenewPeriod, "")
 	_ = fs.MarkDeprecated("rotate-period", "please use key-renew-period instead")
 
-	fs.IntVar(&f.MaxRetries, "max-unseal-retries", 5, "Max unseal retries.")
+	fs.IntVar(&f.MaxRetries, "max-unseal-retries", 5, "Max unseal retries after transient errors, e.g. API I/O errors. SealedSecrets which cannot be decrypted wait for a key change instead.")
+	fs.DurationVar(&f.RetryBaseDelay, "unseal-retry-base-delay", 5*time.Millisecond, "Delay before the first unseal retry after a transient error, doubling with each retry.")
+	fs.DurationVar(&f.RetryMaxDelay, "unseal-retry-max-delay", 1000*time.Second, "Maximum delay between unseal retries after transient errors.")
+	fs.IntVar(&f.Workers, "workers", 1, "Number of SealedSecrets unsealed concurrently, across all watched namespaces.")
 
 	fs.Float32Var(&f.KubeClientQPS, "kubeclient-qps", 5, "Kubeclient QPS (negative value disables ratelimiting)")
 	fs.IntVar(&f.KubeClientBurst, "kubeclient-burst", 10, "Kubeclient Burst")
+
+	fs.StringVar(&f.KMSPluginEndpoint, "kms-plugin-endpoint", "", "Unix socket of a key management plugin holding the private keys (unix:///path/to/socket). When set, the controller doesn't generate keys itself and polls the plugin for new ones every key-renew-period.")
+	fs.DurationVar(&f.KMSPluginTimeout, "kms-plugin-timeout", 3*time.Second, "Timeout of calls to the key management plugin.")
+
+	fs.StringVar(&f.CSRSignerName, "csr-signer-name", "", "Have new sealing certificates issued by this signer through the Kubernetes certificates API (CertificateSigningRequest) instead of self-signed. The request has to be approved before the key is used.")
+	fs.DurationVar(&f.CSRTimeout, "csr-timeout", 5*time.Minute, "How long to wait for a certificate signing request to be approved and signed.")
+
+	fs.StringSliceVar(&f.EscrowCerts, "escrow-cert", nil, "PEM certificate of an offline escrow key. Every sealing key is encrypted to the escrow keys and published with --escrow-configmap and/or --escrow-path. Multiple files accepted either via comma separated list or by repetition of the flag.")
+	fs.StringVar(&f.EscrowConfigMap, "escrow-configmap", "", "Name of the ConfigMap, in the controller namespace, the escrowed sealing keys are published to.")
+	fs.StringVar(&f.EscrowPath, "escrow-path", "", "Directory the escrowed sealing keys are written to, one file per key.")
+
+	fs.BoolVar(&f.Reencrypt, "reencrypt", false, "Re-encrypt the SealedSecrets of the cluster for the sealing key in the background, on startup and after each key renewal, so that old keys can be retired. This updates SealedSecret objects in place.")
+	fs.Float64Var(&f.ReencryptRate, "reencrypt-rate", 1, "Maximum number of SealedSecrets re-encrypted per second.")
+
+	fs.BoolVar(&f.PublishSealingKeys, "publish-sealing-keys", false, "Maintain a read-only, cluster-scoped SealingKey resource for each sealing key, without the private key. Requires the SealingKey CRD.")
 }
 
 func bindFlags(f *controller.Flags, fs *flag.FlagSet, gofs *goflag.FlagSet) {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	gocrypto "crypto"
 	"fmt"
 	"io"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/bitnami-labs/sealed-secrets/pkg/buildinfo"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
 	"github.com/bitnami-labs/sealed-secrets/pkg/flagenv"
 	"github.com/bitnami-labs/sealed-secrets/pkg/kubeseal"
 	"github.com/bitnami-labs/sealed-secrets/pkg/pflagenv"
@@ -37,7 +39,10 @@ var (
 )
 
 type cliFlags struct {
-	certURL        string
+	certURLs       []string
+	trustClusterCA bool
+	trustBundles   []string
+	trustPins      []string
 	controllerNs   string
 	controllerName string
 	outputFormat   string
@@ -51,10 +56,15 @@ type cliFlags struct {
 	raw            bool
 	secretName     string
 	fromFile       []string
+	itemKey        string
 	sealingScope   ssv1alpha1.SealingScope
+	compression    crypto.Compression
 	reEncrypt      bool
 	unseal         bool
 	privKeys       []string
+	exportShares   int
+	shareThreshold int
+	shareDir       string
 	help           bool
 }
 
@@ -80,8 +90,10 @@ func initClient(kubeConfigPath string, cfgOverrides *clientcmd.ConfigOverrides,
 }
 
 func bindFlags(f *cliFlags, fs *flag.FlagSet) {
-	// TODO: Verify k8s server signature against cert in kube client config.
-	fs.StringVar(&f.certURL, "cert", "", "Certificate / public key file/URL to use for encryption. Overrides --controller-*")
+	fs.StringArrayVar(&f.certURLs, "cert", nil, "Certificate / public key file/URL to use for encryption. Overrides --controller-*. Repeat to seal for several controllers at once, each of which can unseal the result.")
+	fs.BoolVar(&f.trustClusterCA, "trust-cluster-ca", false, "Refuse sealing certificates which don't chain up to the cluster CA of the kubeconfig.")
+	fs.StringArrayVar(&f.trustBundles, "trust-bundle", nil, "PEM file of CA certificates the sealing certificate has to chain up to. Can be repeated.")
+	fs.StringArrayVar(&f.trustPins, "trust-fingerprint", nil, "SHA-256 fingerprint of a certificate the sealing certificate chain has to contain. Can be repeated to pin several certificates.")
 	fs.StringVar(&f.controllerNs, "controller-namespace", metav1.NamespaceSystem, "Namespace of sealed-secrets controller.")
 	fs.StringVar(&f.controllerName, "controller-name", "sealed-secrets-controller", "Name of sealed-secrets controller.")
 	fs.StringVarP(&f.outputFormat, "format", "o", "json", "Output format for sealed secret. Either json or yaml")
@@ -91,18 +103,23 @@ func bindFlags(f *cliFlags, fs *flag.FlagSet) {
 	fs.BoolVar(&f.allowEmptyData, "allow-empty-data", false, "Allow empty data in the secret object")
 	fs.BoolVar(&f.validateSecret, "validate", false, "Validate that the sealed secret can be decrypted")
 	fs.StringVar(&f.mergeInto, "merge-into", "", "Merge items from secret into an existing sealed secret file, updating the file in-place instead of writing to stdout.")
-	fs.BoolVar(&f.raw, "raw", false, "Encrypt a raw value passed via the --from-* flags instead of the whole secret object")
+	fs.BoolVar(&f.raw, "raw", false, "Encrypt a raw value passed via the --from-* flags instead of the whole secret object. A value bound to its item key with --item-key, or the key of --from-file, only decrypts in a sealed secret carrying the 'sealedsecrets.bitnami.com/bind-item-keys: \"true\"' annotation")
 	fs.StringVar(&f.secretName, "name", "", "Name of the sealed secret (required with --raw and default (strict) scope)")
 	fs.StringSliceVar(&f.fromFile, "from-file", nil, "(only with --raw) Secret items can be sourced from files. Pro-tip: you can use /dev/stdin to read pipe input. This flag tries to follow the same syntax as in kubectl")
+	fs.StringVar(&f.itemKey, "item-key", "", "(only with --raw) Bind the value to this item key, which defaults to the key of --from-file if any. The sealed secret must then carry the 'sealedsecrets.bitnami.com/bind-item-keys: \"true\"' annotation")
 	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to a kube config. Only required if out-of-cluster")
 
 	fs.Var(&f.sealingScope, "scope", "Set the scope of the sealed secret: strict, namespace-wide, cluster-wide (defaults to strict). Mandatory for --raw, otherwise the 'sealedsecrets.bitnami.com/cluster-wide' and 'sealedsecrets.bitnami.com/namespace-wide' annotations on the input secret can be used to select the scope.")
+	fs.Var(&f.compression, "compression", "Compress each value before sealing it: none, gzip, zstd (defaults to none). Values that don't get any smaller stay uncompressed. The 'sealedsecrets.bitnami.com/compression' annotation on the input secret can be used too.")
 	fs.BoolVar(&f.reEncrypt, "rotate", false, "")
 	fs.BoolVar(&f.reEncrypt, "re-encrypt", false, "Re-encrypt the given sealed secret to use the latest cluster key.")
 	_ = fs.MarkDeprecated("rotate", "please use --re-encrypt instead")
 
 	fs.BoolVar(&f.unseal, "recovery-unseal", false, "Decrypt a sealed secrets file obtained from stdin, using the private key passed with --recovery-private-key. Intended to be used in disaster recovery mode.")
-	fs.StringSliceVar(&f.privKeys, "recovery-private-key", nil, "Private key filename used by the --recovery-unseal command. Multiple files accepted either via comma separated list or by repetition of the flag. Either PEM encoded private keys or a backup of a json/yaml encoded k8s sealed-secret controller secret (and v1.List) are accepted. ")
+	fs.StringSliceVar(&f.privKeys, "recovery-private-key", nil, "Private key filename used by the --recovery-unseal command. Multiple files accepted either via comma separated list or by repetition of the flag. Either PEM encoded private keys, a backup of a json/yaml encoded k8s sealed-secret controller secret (and v1.List), a quorum of key share files written by --export-key-shares, or an escrow bundle along with the offline escrow private key are accepted. ")
+	fs.IntVar(&f.exportShares, "export-key-shares", 0, "Split the sealing keys of the controller, or those passed with --recovery-private-key, into this many key share files, any --key-share-threshold of which recover the keys with --recovery-private-key. Intended to be used for break-glass recovery.")
+	fs.IntVar(&f.shareThreshold, "key-share-threshold", 0, "Number of key shares needed to recover the keys (required with --export-key-shares).")
+	fs.StringVar(&f.shareDir, "key-share-dir", ".", "Directory the --export-key-shares files are written to.")
 	fs.BoolVar(&f.help, "help", false, "Print this help message")
 
 	fs.SetOutput(os.Stdout)
@@ -139,6 +156,10 @@ func runCLI(w io.Writer, cfg *config) (err error) {
 		return fmt.Errorf("--from-file requires --raw")
 	}
 
+	if flags.itemKey != "" && !flags.raw {
+		return fmt.Errorf("--item-key requires --raw")
+	}
+
 	var input io.Reader = os.Stdin
 	if flags.inputFileName != "" {
 		// #nosec G304 -- should open user provided file
@@ -181,6 +202,14 @@ func runCLI(w io.Writer, cfg *config) (err error) {
 		w = f
 	}
 
+	if flags.exportShares != 0 {
+		filenames, err := kubeseal.ExportKeyShares(cfg.ctx, cfg.clientConfig, flags.controllerNs, flags.privKeys, flags.exportShares, flags.shareThreshold, flags.shareDir)
+		for _, filename := range filenames {
+			fmt.Fprintln(w, filename)
+		}
+		return err
+	}
+
 	if flags.unseal {
 		return kubeseal.UnsealSealedSecret(w, input, flags.privKeys, flags.outputFormat, scheme.Codecs)
 	}
@@ -196,25 +225,61 @@ func runCLI(w io.Writer, cfg *config) (err error) {
 		return kubeseal.ReEncryptSealedSecret(cfg.ctx, cfg.clientConfig, flags.controllerNs, flags.controllerName, flags.outputFormat, input, w, scheme.Codecs)
 	}
 
-	f, err := kubeseal.OpenCert(cfg.ctx, cfg.clientConfig, flags.controllerNs, flags.controllerName, flags.certURL)
+	trust, err := kubeseal.NewTrustAnchors(cfg.clientConfig, flags.trustClusterCA, flags.trustBundles, flags.trustPins)
 	if err != nil {
 		return err
 	}
-	// #nosec: G307 -- this deferred close is fine because it is not on a writable file
-	defer f.Close()
 
 	if flags.dumpCert {
-		_, err := io.Copy(w, f)
+		if len(flags.certURLs) > 1 {
+			return fmt.Errorf("--fetch-cert accepts at most one --cert")
+		}
+		var certURL string
+		if len(flags.certURLs) == 1 {
+			certURL = flags.certURLs[0]
+		}
+		ns, _, err := cfg.clientConfig.Namespace()
+		if err != nil {
+			return err
+		}
+		f, err := kubeseal.OpenCert(cfg.ctx, cfg.clientConfig, flags.controllerNs, flags.controllerName, ns, certURL)
+		if err != nil {
+			return err
+		}
+		// #nosec: G307 -- this deferred close is fine because it is not on a writable file
+		defer f.Close()
+
+		if trust == nil {
+			_, err = io.Copy(w, f)
+			return err
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if err := trust.VerifyPEM(data); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
 		return err
 	}
 
-	pubKey, err := kubeseal.ParseKey(f)
-	if err != nil {
-		return err
+	// The certificate depends on the key set of the namespace the secret is
+	// sealed for, which is only known once the input is read.
+	var keys kubeseal.KeysFunc = func(namespace string) (gocrypto.PublicKey, error) {
+		return kubeseal.OpenKeys(cfg.ctx, cfg.clientConfig, flags.controllerNs, flags.controllerName, namespace, flags.certURLs, trust)
+	}
+	// Certificates given with --cert are the same for every namespace.
+	if len(flags.certURLs) > 0 {
+		pubKey, err := keys("")
+		if err != nil {
+			return err
+		}
+		keys = kubeseal.StaticKey(pubKey)
 	}
 
 	if flags.mergeInto != "" {
-		return kubeseal.SealMergingInto(cfg.clientConfig, flags.outputFormat, input, flags.mergeInto, scheme.Codecs, pubKey, flags.sealingScope, flags.allowEmptyData)
+		return kubeseal.SealMergingInto(cfg.clientConfig, flags.outputFormat, input, flags.mergeInto, scheme.Codecs, keys, flags.sealingScope, flags.compression, flags.allowEmptyData)
 	}
 
 	if flags.raw {
@@ -238,12 +303,16 @@ func runCLI(w io.Writer, cfg *config) (err error) {
 		}
 
 		var data []byte
+		itemKey := flags.itemKey
 		if len(flags.fromFile) > 0 {
 			if len(flags.fromFile) > 1 {
 				return fmt.Errorf("must provide only one --from-file when encrypting a single item with --raw")
 			}
 
-			_, filename := kubeseal.ParseFromFile(flags.fromFile[0])
+			key, filename := kubeseal.ParseFromFile(flags.fromFile[0])
+			if itemKey == "" {
+				itemKey = key
+			}
 			// #nosec G304 -- should open user provided file
 			data, err = os.ReadFile(filename)
 		} else {
@@ -256,10 +325,14 @@ func runCLI(w io.Writer, cfg *config) (err error) {
 			return err
 		}
 
-		return kubeseal.EncryptSecretItem(w, flags.secretName, ns, data, flags.sealingScope, pubKey)
+		pubKey, err := keys(ns)
+		if err != nil {
+			return err
+		}
+		return kubeseal.EncryptSecretItem(w, flags.secretName, ns, itemKey, data, flags.sealingScope, flags.compression, pubKey)
 	}
 
-	return kubeseal.Seal(cfg.clientConfig, flags.outputFormat, input, w, scheme.Codecs, pubKey, flags.sealingScope, flags.allowEmptyData, flags.secretName, "")
+	return kubeseal.Seal(cfg.clientConfig, flags.outputFormat, input, w, scheme.Codecs, keys, flags.sealingScope, flags.compression, flags.allowEmptyData, flags.secretName, "")
 }
 
 func mainE(w io.Writer, fs *flag.FlagSet, gofs *goflag.FlagSet, args []string) error {
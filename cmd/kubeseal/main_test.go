@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	gocrypto "crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/pem"
@@ -19,6 +20,8 @@ import (
 	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
 	"github.com/bitnami-labs/sealed-secrets/pkg/kubeseal"
 	flag "github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -78,7 +81,7 @@ func testConfig(flags *cliFlags) *config {
 
 func TestMainError(t *testing.T) {
 	badFileName := filepath.Join("this", "file", "cannot", "possibly", "exist", "can", "it?")
-	flags := cliFlags{certURL: badFileName}
+	flags := cliFlags{certURLs: []string{badFileName}}
 
 	err := runCLI(io.Discard, testConfig(&flags))
 	if err == nil || !os.IsNotExist(err) {
@@ -170,7 +173,7 @@ data:
 	flags := cliFlags{
 		inputFileName:  in.Name(),
 		outputFileName: out.Name(),
-		certURL:        certFilename,
+		certURLs:       []string{certFilename},
 	}
 
 	if err := runCLI(&buf, testConfig(&flags)); err != nil {
@@ -223,7 +226,7 @@ metadata:
 	flags := cliFlags{
 		inputFileName:  in.Name(),
 		outputFileName: out.Name(),
-		certURL:        certFilename,
+		certURLs:       []string{certFilename},
 	}
 
 	if err := runCLI(&buf, testConfig(&flags)); err == nil {
@@ -294,7 +297,7 @@ func trySealTestItem(certFilename, secretNS, secretName, secretValue string, sco
 	flags := cliFlags{
 		sealingScope: scope,
 		secretName:   secretName,
-		certURL:      certFilename,
+		certURLs:     []string{certFilename},
 		raw:          true,
 		fromFile:     fromFile,
 	}
@@ -308,6 +311,64 @@ func trySealTestItem(certFilename, secretNS, secretName, secretValue string, sco
 	return buf.String(), nil
 }
 
+func TestRawSealItemKeyFromFile(t *testing.T) {
+	const (
+		secretNS   = "myns"
+		secretName = "mysecret"
+	)
+	_, pk := newTestKeyPairSingle(t)
+	cert, err := crypto.SignKey(rand.Reader, pk, time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFilename, err := writeTempFile(pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(certFilename)
+	dataFile, err := writeTempFile([]byte("supersecret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataFile)
+
+	var buf bytes.Buffer
+	flags := cliFlags{
+		secretName: secretName,
+		certURLs:   []string{certFilename},
+		raw:        true,
+		fromFile:   []string{"password=" + dataFile},
+	}
+	cfg := testConfig(&flags)
+	cfg.clientConfig = &tweakedClientConfig{cfg.clientConfig, secretNS}
+	if err := runCLI(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	fp, err := crypto.PublicKeyFingerprint(&pk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]gocrypto.PrivateKey{fp: pk}
+	for key, wantErr := range map[string]bool{"password": false, "other": true} {
+		ss := &ssv1alpha1.SealedSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        secretName,
+				Namespace:   secretNS,
+				Annotations: map[string]string{ssv1alpha1.SealedSecretBindItemKeysAnnotation: "true"},
+			},
+			Spec: ssv1alpha1.SealedSecretSpec{EncryptedData: map[string]string{key: buf.String()}},
+		}
+		secret, err := ss.Unseal(scheme.Codecs, keys)
+		if gotErr := err != nil; gotErr != wantErr {
+			t.Fatalf("unsealing the value as %q returned error %v", key, err)
+		}
+		if !wantErr && string(secret.Data[key]) != "supersecret" {
+			t.Errorf("got %q, want %q", secret.Data[key], "supersecret")
+		}
+	}
+}
+
 func TestRawSealErrors(t *testing.T) {
 	certFilename, _, cleanup := testingKeypairFiles(t)
 	defer cleanup()
@@ -6,6 +6,7 @@ package integration
 import (
 	"bytes"
 	"context"
+	gocrypto "crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -86,7 +87,7 @@ func compareLastTimes(ss *ssv1alpha1.SealedSecret) bool {
 	return false
 }
 
-func fetchKeys(ctx context.Context, c corev1.SecretsGetter) (map[string]*rsa.PrivateKey, []*x509.Certificate, error) {
+func fetchKeys(ctx context.Context, c corev1.SecretsGetter) (map[string]gocrypto.PrivateKey, []*x509.Certificate, error) {
 	list, err := c.Secrets(*controllerNs).List(ctx, metav1.ListOptions{
 		LabelSelector: keySelector,
 	})
@@ -115,12 +116,15 @@ func fetchKeys(ctx context.Context, c corev1.SecretsGetter) (map[string]*rsa.Pri
 		return nil, nil, fmt.Errorf("failed to read any certificates")
 	}
 
-	rsaPrivKey := privKey.(*rsa.PrivateKey)
-	fp, err := crypto.PublicKeyFingerprint(&rsaPrivKey.PublicKey)
+	pubKey, err := crypto.PublicKey(privKey)
 	if err != nil {
 		return nil, nil, err
 	}
-	privKeys := map[string]*rsa.PrivateKey{fp: rsaPrivKey}
+	fp, err := crypto.PublicKeyFingerprint(pubKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privKeys := map[string]gocrypto.PrivateKey{fp: privKey}
 	return privKeys, certs, nil
 }
 
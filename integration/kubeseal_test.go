@@ -6,7 +6,7 @@ package integration
 import (
 	"bytes"
 	"context"
-	"crypto/rsa"
+	gocrypto "crypto"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
@@ -34,7 +34,7 @@ var _ = Describe("kubeseal", func() {
 	var input *v1.Secret
 	var ss *ssv1alpha1.SealedSecret
 	var args []string
-	var privKeys map[string]*rsa.PrivateKey
+	var privKeys map[string]gocrypto.PrivateKey
 	var certs []*x509.Certificate
 	var config *clientcmdapi.Config
 	var kubeconfigFile string
@@ -2,11 +2,15 @@ package v1alpha1
 
 import (
 	"bytes"
+	gocrypto "crypto"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"text/template"
 
 	v1 "k8s.io/api/core/v1"
@@ -36,6 +40,14 @@ var (
 	// TODO(mkm): remove after a release.
 	AcceptDeprecatedV1Data = false
 
+	// ErrTemplateMismatch indicates the template of a template-bound sealed
+	// secret was changed after it was sealed.
+	ErrTemplateMismatch = errors.New("template doesn't match the one the secret was sealed with")
+
+	// ErrInvalidTemplate indicates a template data item which doesn't parse or
+	// fails to render.
+	ErrInvalidTemplate = errors.New("invalid template")
+
 	sprigFuncMap = sprig.GenericFuncMap() // a singleton for better performance
 )
 
@@ -48,7 +60,7 @@ func init() {
 
 // SealedSecretExpansion has methods to work with SealedSecrets resources.
 type SealedSecretExpansion interface {
-	Unseal(codecs runtimeserializer.CodecFactory, privKeys map[string]*rsa.PrivateKey) (*v1.Secret, error)
+	Unseal(codecs runtimeserializer.CodecFactory, privKeys map[string]gocrypto.PrivateKey) (*v1.Secret, error)
 }
 
 // SealingScope is an enum that declares the mobility of a sealed secret by defining
@@ -103,11 +115,34 @@ func EncryptionLabel(namespace, name string, scope SealingScope) []byte {
 	return []byte(l)
 }
 
+// ItemEncryptionLabel returns the label meant to be used for encrypting the value
+// of the item key of a sealed secret according to scope. Unlike EncryptionLabel,
+// it prevents values from being moved to other items of the same sealed secret.
+func ItemEncryptionLabel(namespace, name string, scope SealingScope, key string) []byte {
+	// Neither namespaces nor names can contain a NUL byte.
+	return fmt.Appendf(EncryptionLabel(namespace, name, scope), "\x00%s", key)
+}
+
 // Returns labels followed by clusterWide followed by namespaceWide.
 func labelFor(o metav1.Object) []byte {
 	return EncryptionLabel(o.GetNamespace(), o.GetName(), SecretScope(o))
 }
 
+// itemLabelFor returns the label for the value of the item key, which only
+// includes the key if the object binds item keys.
+func itemLabelFor(o metav1.Object, key string) []byte {
+	if !ItemKeysBound(o) {
+		return labelFor(o)
+	}
+	return ItemEncryptionLabel(o.GetNamespace(), o.GetName(), SecretScope(o), key)
+}
+
+// ItemKeysBound returns whether the values of a sealed secret are bound to their
+// item keys, as annotated in its metadata.
+func ItemKeysBound(o metav1.Object) bool {
+	return o.GetAnnotations()[SealedSecretBindItemKeysAnnotation] == "true"
+}
+
 // SecretScope returns the scope of a secret to be sealed, as annotated in its metadata.
 func SecretScope(o metav1.Object) SealingScope {
 	if o.GetAnnotations()[SealedSecretClusterWideAnnotation] == "true" {
@@ -128,7 +163,7 @@ func (s *SealedSecret) Scope() SealingScope {
 // provided secret. This encrypts all the secrets into a single encrypted
 // blob and stores it in the `Data` attribute. Keeping this for backward
 // compatibility.
-func NewSealedSecretV1(codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKey, secret *v1.Secret) (*SealedSecret, error) {
+func NewSealedSecretV1(codecs runtimeserializer.CodecFactory, pubKey gocrypto.PublicKey, secret *v1.Secret) (*SealedSecret, error) {
 	info, ok := runtime.SerializerInfoForMediaType(codecs.SupportedMediaTypes(), runtime.ContentTypeJSON)
 	if !ok {
 		return nil, fmt.Errorf("binary can't serialize JSON")
@@ -206,7 +241,15 @@ func StripLastAppliedAnnotations(annotations map[string]string) {
 // NewSealedSecret creates a new SealedSecret object wrapping the
 // provided secret. This encrypts only the values of each secrets
 // individually, so secrets can be updated one by one.
-func NewSealedSecret(codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKey, secret *v1.Secret) (*SealedSecret, error) {
+func NewSealedSecret(codecs runtimeserializer.CodecFactory, pubKey gocrypto.PublicKey, secret *v1.Secret) (*SealedSecret, error) {
+	return NewSealedSecretWithTemplateData(codecs, pubKey, secret, nil)
+}
+
+// NewSealedSecretWithTemplateData is like NewSealedSecret, but the template
+// also renders the given data from the values of the secret. A template-bound
+// SealedSecret is bound to that data as well, so it has to be known when
+// sealing, e.g. when re-encrypting an existing SealedSecret.
+func NewSealedSecretWithTemplateData(codecs runtimeserializer.CodecFactory, pubKey gocrypto.PublicKey, secret *v1.Secret, templateData map[string]string) (*SealedSecret, error) {
 	if SecretScope(secret) != ClusterWideScope && secret.GetNamespace() == "" {
 		return nil, fmt.Errorf("secret must declare a namespace")
 	}
@@ -226,6 +269,7 @@ func NewSealedSecret(codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKe
 		},
 	}
 	secret.ObjectMeta.DeepCopyInto(&s.Spec.Template.ObjectMeta)
+	s.Spec.Template.Data = maps.Clone(templateData)
 
 	// the input secret could come from a real secret object applied with `kubectl apply` or similar tools
 	// which put a copy of the object version at application time in an annotation in order to support
@@ -237,12 +281,44 @@ func NewSealedSecret(codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKe
 	// Cleanup ownerReference (See #243)
 	s.Spec.Template.ObjectMeta.OwnerReferences = nil
 
+	// Item keys are bound unless the secret opts out, and the template only
+	// if it opts in. Like compression, this only concerns sealing, so it has
+	// no place in the template.
+	bindItemKeys := secret.GetAnnotations()[SealedSecretBindItemKeysAnnotation] != "false"
+	bindTemplate := secret.GetAnnotations()[SealedSecretBindTemplateAnnotation] == "true"
+	delete(s.Spec.Template.ObjectMeta.Annotations, SealedSecretBindItemKeysAnnotation)
+	delete(s.Spec.Template.ObjectMeta.Annotations, SealedSecretBindTemplateAnnotation)
+	delete(s.Spec.Template.ObjectMeta.Annotations, SealedSecretCompressionAnnotation)
+
 	// RSA-OAEP will fail to decrypt unless the same label is used
 	// during decryption.
-	label := labelFor(secret)
+	label := func(key string) []byte {
+		if bindItemKeys {
+			return ItemEncryptionLabel(secret.GetNamespace(), secret.GetName(), SecretScope(secret), key)
+		}
+		return labelFor(secret)
+	}
+
+	var opts crypto.EncryptOptions
+	if c, ok := secret.GetAnnotations()[SealedSecretCompressionAnnotation]; ok {
+		compression, err := crypto.ParseCompression(c)
+		if err != nil {
+			return nil, err
+		}
+		opts.Compression = compression
+	}
+
+	// AES-GCM will fail to decrypt unless the template is unchanged.
+	if bindTemplate {
+		digest, err := templateDigest(&s.Spec.Template)
+		if err != nil {
+			return nil, err
+		}
+		opts.AdditionalData = digest
+	}
 
 	for key, value := range secret.Data {
-		ciphertext, err := crypto.HybridEncrypt(rand.Reader, pubKey, value, label)
+		ciphertext, err := crypto.HybridEncryptWithOptions(rand.Reader, pubKey, value, label(key), opts)
 		if err != nil {
 			return nil, err
 		}
@@ -250,7 +326,7 @@ func NewSealedSecret(codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKe
 	}
 
 	for key, value := range secret.StringData {
-		ciphertext, err := crypto.HybridEncrypt(rand.Reader, pubKey, []byte(value), label)
+		ciphertext, err := crypto.HybridEncryptWithOptions(rand.Reader, pubKey, []byte(value), label(key), opts)
 		if err != nil {
 			return nil, err
 		}
@@ -258,12 +334,75 @@ func NewSealedSecret(codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKe
 	}
 
 	s.Annotations = UpdateScopeAnnotations(s.Annotations, SecretScope(secret))
+	if bindItemKeys {
+		s.Annotations[SealedSecretBindItemKeysAnnotation] = "true"
+	}
+	if opts.AdditionalData != nil {
+		s.Annotations[SealedSecretTemplateDigestAnnotation] = formatTemplateDigest(opts.AdditionalData)
+	}
 
 	return s, nil
 }
 
+// TemplateBound returns whether the template of the sealed secret is bound to
+// its encrypted values.
+func (s *SealedSecret) TemplateBound() bool {
+	_, ok := s.Annotations[SealedSecretTemplateDigestAnnotation]
+	return ok
+}
+
+// templateAdditionalData returns the additional data the encrypted values of
+// the sealed secret are bound to: the digest of its current template if it was
+// sealed with one, nil otherwise. The digest is always recomputed, so editing
+// the annotation along with the template doesn't help.
+func (s *SealedSecret) templateAdditionalData() ([]byte, error) {
+	if !s.TemplateBound() {
+		return nil, nil
+	}
+	digest, err := templateDigest(&s.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	if s.Annotations[SealedSecretTemplateDigestAnnotation] != formatTemplateDigest(digest) {
+		return nil, ErrTemplateMismatch
+	}
+	return digest, nil
+}
+
+// templateDigest returns a SHA-256 digest of the parts of a template that end up
+// in the unsealed Secret. The namespace and name are left out since the label
+// already binds them according to the scope.
+func templateDigest(t *SecretTemplateSpec) ([]byte, error) {
+	canonical := struct {
+		Type        v1.SecretType     `json:"type,omitempty"`
+		Immutable   bool              `json:"immutable,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		Data        map[string]string `json:"data,omitempty"`
+	}{
+		Type:        t.Type,
+		Immutable:   t.Immutable != nil && *t.Immutable,
+		Labels:      t.Labels,
+		Annotations: t.Annotations,
+		Data:        t.Data,
+	}
+	// encoding/json sorts map keys, which makes the encoding canonical.
+	b, err := json.Marshal(canonical)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte("sealed-secrets template v1\x00"))
+	h.Write(b)
+	return h.Sum(nil), nil
+}
+
+func formatTemplateDigest(digest []byte) string {
+	return "sha256:" + hex.EncodeToString(digest)
+}
+
 // Unseal decrypts and returns the embedded v1.Secret.
-func (s *SealedSecret) Unseal(codecs runtimeserializer.CodecFactory, privKeys map[string]*rsa.PrivateKey) (*v1.Secret, error) {
+func (s *SealedSecret) Unseal(codecs runtimeserializer.CodecFactory, privKeys map[string]gocrypto.PrivateKey) (*v1.Secret, error) {
 	boolTrue := true
 	smeta := s.GetObjectMeta()
 
@@ -273,6 +412,11 @@ func (s *SealedSecret) Unseal(codecs runtimeserializer.CodecFactory, privKeys ma
 	// namespace/name.
 	label := labelFor(smeta)
 
+	additionalData, err := s.templateAdditionalData()
+	if err != nil {
+		return nil, err
+	}
+
 	var secret v1.Secret
 
 	if s.Spec.Data == nil {
@@ -287,10 +431,10 @@ func (s *SealedSecret) Unseal(codecs runtimeserializer.CodecFactory, privKeys ma
 		for key, value := range s.Spec.EncryptedData {
 			valueBytes, err := base64.StdEncoding.DecodeString(value)
 			if err != nil {
-				errs = append(errs, multierror.Tag(key, err))
+				errs = append(errs, multierror.Tag(key, fmt.Errorf("%w: %w", crypto.ErrMalformed, err)))
 				continue
 			}
-			plaintext, err := crypto.HybridDecrypt(rand.Reader, privKeys, valueBytes, label)
+			plaintext, err := crypto.HybridDecryptWithAD(rand.Reader, privKeys, valueBytes, itemLabelFor(smeta, key), additionalData)
 			if err != nil {
 				errs = append(errs, multierror.Tag(key, err))
 			}
@@ -303,12 +447,12 @@ func (s *SealedSecret) Unseal(codecs runtimeserializer.CodecFactory, privKeys ma
 
 			template, err := template.New(key).Funcs(sprigFuncMap).Parse(value)
 			if err != nil {
-				errs = append(errs, multierror.Tag(key, err))
+				errs = append(errs, multierror.Tag(key, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)))
 				continue
 			}
 			err = template.Execute(&plaintext, data)
 			if err != nil {
-				errs = append(errs, multierror.Tag(key, err))
+				errs = append(errs, multierror.Tag(key, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)))
 			}
 			secret.Data[key] = plaintext.Bytes()
 		}
@@ -318,20 +462,20 @@ func (s *SealedSecret) Unseal(codecs runtimeserializer.CodecFactory, privKeys ma
 		}
 	} else if AcceptDeprecatedV1Data { // Support decrypting old secrets for backward compatibility
 		if len(s.Spec.EncryptedData) > 0 {
-			return nil, fmt.Errorf("cannot use the field 'encryptedData' and the deprecated field 'data' at the same time")
+			return nil, fmt.Errorf("%w: cannot use the field 'encryptedData' and the deprecated field 'data' at the same time", crypto.ErrMalformed)
 		}
 
-		plaintext, err := crypto.HybridDecrypt(rand.Reader, privKeys, s.Spec.Data, label)
+		plaintext, err := crypto.HybridDecryptWithAD(rand.Reader, privKeys, s.Spec.Data, label, additionalData)
 		if err != nil {
 			return nil, err
 		}
 
 		dec := codecs.UniversalDecoder(secret.GroupVersionKind().GroupVersion())
 		if err = runtime.DecodeInto(dec, plaintext, &secret); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %w", crypto.ErrMalformed, err)
 		}
 	} else {
-		return nil, fmt.Errorf("using deprecated 'data' field, use 'encryptedData' or flip the feature flag")
+		return nil, fmt.Errorf("%w: using deprecated 'data' field, use 'encryptedData' or flip the feature flag", crypto.ErrMalformed)
 	}
 
 	// Ensure these are set to what we expect
@@ -2,9 +2,11 @@ package v1alpha1
 
 import (
 	"bytes"
+	gocrypto "crypto"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	mathrand "math/rand"
 	"reflect"
@@ -81,6 +83,9 @@ func TestEncryptionLabel(t *testing.T) {
 		if got, want := string(EncryptionLabel(ns, name, tc.scope)), tc.label; got != want {
 			t.Errorf("got: %q, want: %q", got, want)
 		}
+		if got, want := string(ItemEncryptionLabel(ns, name, tc.scope, "foo")), tc.label+"\x00foo"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
 	}
 }
 
@@ -179,7 +184,7 @@ func testRand() io.Reader {
 	return mathrand.New(mathrand.NewSource(42))
 }
 
-func generateTestKey(t *testing.T, rand io.Reader, bits int) (*rsa.PrivateKey, map[string]*rsa.PrivateKey) {
+func generateTestKey(t *testing.T, rand io.Reader, bits int) (*rsa.PrivateKey, map[string]gocrypto.PrivateKey) {
 	key, err := rsa.GenerateKey(rand, 2048)
 	if err != nil {
 		t.Fatalf("Failed to generate test key: %v", err)
@@ -188,7 +193,7 @@ func generateTestKey(t *testing.T, rand io.Reader, bits int) (*rsa.PrivateKey, m
 	if err != nil {
 		t.Fatalf("Failed to generate fingerprint: %v", err)
 	}
-	keys := map[string]*rsa.PrivateKey{fingerprint: key}
+	keys := map[string]gocrypto.PrivateKey{fingerprint: key}
 	return key, keys
 }
 
@@ -389,6 +394,268 @@ func TestSealRoundTripTemplateData(t *testing.T) {
 	if got := string(secret2.Data["password-json"]); got != string(want) {
 		t.Errorf("got: %q, want: %q", got, want)
 	}
+
+	for _, tmpl := range []string{`{{ index . "foo" `, `{{ fail "no" }}`} {
+		ssecret.Spec.Template.Data = map[string]string{"bar": tmpl}
+		if _, err := ssecret.Unseal(codecs, keys); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("got error %v for template %q, want %v", err, tmpl, ErrInvalidTemplate)
+		}
+	}
+}
+
+func TestSealRoundTripItemKeys(t *testing.T) {
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myname",
+			Namespace: "myns",
+		},
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte("hunter2"),
+		},
+	}
+
+	ssecret, codecs, keys := sealSecret(t, &secret, NewSealedSecret)
+	if !ItemKeysBound(ssecret) {
+		t.Fatalf("sealed secret doesn't bind item keys by default: %v", ssecret.Annotations)
+	}
+	if _, err := ssecret.Unseal(codecs, keys); err != nil {
+		t.Fatalf("Unseal returned error: %v", err)
+	}
+
+	testCases := map[string]func(s *SealedSecret){
+		"swapped": func(s *SealedSecret) {
+			data := s.Spec.EncryptedData
+			data["username"], data["password"] = data["password"], data["username"]
+		},
+		"copied": func(s *SealedSecret) {
+			s.Spec.EncryptedData["token"] = s.Spec.EncryptedData["password"]
+		},
+		"unbound": func(s *SealedSecret) {
+			delete(s.Annotations, SealedSecretBindItemKeysAnnotation)
+		},
+	}
+	for name, tamper := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := ssecret.DeepCopy()
+			tamper(s)
+			if _, err := s.Unseal(codecs, keys); err == nil {
+				t.Errorf("Unseal succeeded")
+			}
+		})
+	}
+}
+
+func TestSealLegacyItemKeys(t *testing.T) {
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myname",
+			Namespace: "myns",
+			Annotations: map[string]string{
+				SealedSecretBindItemKeysAnnotation: "false",
+			},
+		},
+		Data: map[string][]byte{
+			"foo": []byte("bar"),
+		},
+	}
+
+	ssecret, codecs, keys := sealSecret(t, &secret, NewSealedSecret)
+	if ItemKeysBound(ssecret) {
+		t.Fatalf("sealed secret binds item keys after opting out: %v", ssecret.Annotations)
+	}
+	if _, ok := ssecret.Spec.Template.Annotations[SealedSecretBindItemKeysAnnotation]; ok {
+		t.Errorf("sealing directive leaked into the template: %v", ssecret.Spec.Template.Annotations)
+	}
+
+	secret2, err := ssecret.Unseal(codecs, keys)
+	if err != nil {
+		t.Fatalf("Unseal returned error: %v", err)
+	}
+	if got, want := string(secret2.Data["foo"]), "bar"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	ssecret.Annotations[SealedSecretBindItemKeysAnnotation] = "true"
+	if _, err := ssecret.Unseal(codecs, keys); err == nil {
+		t.Errorf("Unseal succeeded after binding item keys")
+	}
+}
+
+func TestSealRoundTripCompression(t *testing.T) {
+	bundle := strings.Repeat("-----BEGIN CERTIFICATE-----\nMIIC...\n-----END CERTIFICATE-----\n", 200)
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myname",
+			Namespace: "myns",
+			Annotations: map[string]string{
+				SealedSecretCompressionAnnotation: "zstd",
+			},
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(bundle),
+		},
+	}
+
+	ssecret, codecs, keys := sealSecret(t, &secret, NewSealedSecret)
+	if got, max := len(ssecret.Spec.EncryptedData["ca.crt"]), len(bundle)/2; got > max {
+		t.Errorf("sealed value is %d bytes, want at most %d", got, max)
+	}
+	if _, ok := ssecret.Spec.Template.Annotations[SealedSecretCompressionAnnotation]; ok {
+		t.Errorf("the template kept the compression annotation: %v", ssecret.Spec.Template.Annotations)
+	}
+
+	secret2, err := ssecret.Unseal(codecs, keys)
+	if err != nil {
+		t.Fatalf("Unseal returned error: %v", err)
+	}
+	if got, want := string(secret2.Data["ca.crt"]), bundle; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	key, _ := generateTestKey(t, testRand(), 2048)
+	secret.Annotations[SealedSecretCompressionAnnotation] = "lz4"
+	if _, err := NewSealedSecret(codecs, &key.PublicKey, &secret); !errors.Is(err, crypto.ErrUnsupportedCompression) {
+		t.Errorf("got error %v, want %v", err, crypto.ErrUnsupportedCompression)
+	}
+}
+
+func TestSealRoundTripBoundTemplate(t *testing.T) {
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myname",
+			Namespace: "myns",
+			Labels: map[string]string{
+				"app": "myapp",
+			},
+			Annotations: map[string]string{
+				SealedSecretBindTemplateAnnotation: "true",
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"foo": []byte("bar"),
+		},
+	}
+
+	ssecret, codecs, keys := sealSecret(t, &secret, NewSealedSecret)
+	if !ssecret.TemplateBound() {
+		t.Fatalf("sealed secret isn't template-bound: %v", ssecret.Annotations)
+	}
+	if _, ok := ssecret.Spec.Template.Annotations[SealedSecretBindTemplateAnnotation]; ok {
+		t.Errorf("the template kept the bind-template annotation: %v", ssecret.Spec.Template.Annotations)
+	}
+
+	secret2, err := ssecret.Unseal(codecs, keys)
+	if err != nil {
+		t.Fatalf("Unseal returned error: %v", err)
+	}
+	if got, want := string(secret2.Data["foo"]), "bar"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	testCases := map[string]func(s *SealedSecret){
+		"type": func(s *SealedSecret) {
+			s.Spec.Template.Type = v1.SecretTypeDockerConfigJson
+		},
+		"labels": func(s *SealedSecret) {
+			s.Spec.Template.Labels["app"] = "otherapp"
+		},
+		"annotations": func(s *SealedSecret) {
+			s.Spec.Template.Annotations[SealedSecretSkipSetOwnerReferencesAnnotation] = "true"
+		},
+		"data": func(s *SealedSecret) {
+			s.Spec.Template.Data = map[string]string{"foo": "not {{ index . \"foo\" }}"}
+		},
+	}
+	for name, tamper := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := ssecret.DeepCopy()
+			tamper(s)
+			if _, err := s.Unseal(codecs, keys); !errors.Is(err, ErrTemplateMismatch) {
+				t.Errorf("got error %v, want %v", err, ErrTemplateMismatch)
+			}
+
+			// Recomputing the digest annotation doesn't help.
+			digest, err := templateDigest(&s.Spec.Template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Annotations[SealedSecretTemplateDigestAnnotation] = formatTemplateDigest(digest)
+			if _, err := s.Unseal(codecs, keys); err == nil {
+				t.Errorf("Unseal succeeded with a recomputed template digest")
+			}
+
+			// Neither does dropping it.
+			delete(s.Annotations, SealedSecretTemplateDigestAnnotation)
+			if _, err := s.Unseal(codecs, keys); err == nil {
+				t.Errorf("Unseal succeeded without the template digest")
+			}
+		})
+	}
+}
+
+func TestSealRoundTripBoundTemplateData(t *testing.T) {
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myname",
+			Namespace: "myns",
+			Annotations: map[string]string{
+				SealedSecretBindTemplateAnnotation: "true",
+			},
+		},
+		Data: map[string][]byte{
+			"foo": []byte("bar"),
+		},
+	}
+	templateData := map[string]string{"url": "db://{{ index . \"foo\" }}"}
+	newSealedSecret := func(codecs serializer.CodecFactory, pubKey gocrypto.PublicKey, secret *v1.Secret) (*SealedSecret, error) {
+		return NewSealedSecretWithTemplateData(codecs, pubKey, secret, templateData)
+	}
+
+	ssecret, codecs, keys := sealSecret(t, &secret, newSealedSecret)
+	secret2, err := ssecret.Unseal(codecs, keys)
+	if err != nil {
+		t.Fatalf("Unseal returned error: %v", err)
+	}
+	if got, want := string(secret2.Data["url"]), "db://bar"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	ssecret.Spec.Template.Data["url"] = "db://{{ index . \"foo\" }}.evil"
+	if _, err := ssecret.Unseal(codecs, keys); !errors.Is(err, ErrTemplateMismatch) {
+		t.Errorf("got error %v, want %v", err, ErrTemplateMismatch)
+	}
+	if templateData["url"] != "db://{{ index . \"foo\" }}" {
+		t.Errorf("the template data passed in was modified: %v", templateData)
+	}
+}
+
+func TestSealUnboundTemplate(t *testing.T) {
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myname",
+			Namespace: "myns",
+		},
+		Data: map[string][]byte{
+			"foo": []byte("bar"),
+		},
+	}
+
+	ssecret, codecs, keys := sealSecret(t, &secret, NewSealedSecret)
+	if ssecret.TemplateBound() {
+		t.Fatalf("sealed secret is template-bound without opting in")
+	}
+
+	// Marking it as bound afterwards doesn't work either.
+	digest, err := templateDigest(&ssecret.Spec.Template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssecret.Annotations[SealedSecretTemplateDigestAnnotation] = formatTemplateDigest(digest)
+	if _, err := ssecret.Unseal(codecs, keys); err == nil {
+		t.Errorf("Unseal succeeded after binding the template")
+	}
 }
 
 func TestTemplateWithoutEncryptedData(t *testing.T) {
@@ -574,6 +841,9 @@ func TestRejectBothEncryptedDataAndDeprecatedV1Data(t *testing.T) {
 		if needle := "at the same time"; err == nil || !strings.Contains(err.Error(), needle) {
 			t.Fatalf("Expecting error: %v to contain %q", err, needle)
 		}
+		if !errors.Is(err, crypto.ErrMalformed) {
+			t.Errorf("got error %v, want %v", err, crypto.ErrMalformed)
+		}
 	}))
 
 	t.Run("RejectDeprecatedV1Data", testWithAcceptDeprecatedV1Data(false, func(t *testing.T) {
@@ -609,13 +879,16 @@ func TestInvalidBase64(t *testing.T) {
 	if !strings.Contains(err.Error(), "foo") {
 		t.Errorf("Expecting error: %q to contain field %q", err, "foo")
 	}
+	if !errors.Is(err, crypto.ErrMalformed) {
+		t.Errorf("got error %v, want %v", err, crypto.ErrMalformed)
+	}
 
 	if strings.Contains(err.Error(), "decrypt") {
 		t.Errorf("Expecting error: %q to not contain %q (invalid base64 should skip decryption)", err, "decrypt")
 	}
 }
 
-func sealSecret(t *testing.T, secret *v1.Secret, newSealedSecret func(serializer.CodecFactory, *rsa.PublicKey, *v1.Secret) (*SealedSecret, error)) (*SealedSecret, serializer.CodecFactory, map[string]*rsa.PrivateKey) {
+func sealSecret(t *testing.T, secret *v1.Secret, newSealedSecret func(serializer.CodecFactory, gocrypto.PublicKey, *v1.Secret) (*SealedSecret, error)) (*SealedSecret, serializer.CodecFactory, map[string]gocrypto.PrivateKey) {
 	scheme := runtime.NewScheme()
 	codecs := serializer.NewCodecFactory(scheme)
 
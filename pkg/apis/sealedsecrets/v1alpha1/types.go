@@ -35,6 +35,23 @@ const (
 	// SealedSecretSkipSetOwnerReferencesAnnotation is the name for the annotation for
 	// flagging the controller not to set owner reference to secret.
 	SealedSecretSkipSetOwnerReferencesAnnotation = annoNs + "skip-set-owner-references"
+
+	// SealedSecretBindTemplateAnnotation is the name for the annotation for
+	// binding the template of a secret to its encrypted values when sealing it.
+	SealedSecretBindTemplateAnnotation = annoNs + "bind-template"
+
+	// SealedSecretTemplateDigestAnnotation is the name for the annotation
+	// holding the digest of the template a sealed secret was bound to.
+	SealedSecretTemplateDigestAnnotation = annoNs + "template-digest"
+
+	// SealedSecretBindItemKeysAnnotation is the name for the annotation for
+	// binding each encrypted value of a sealed secret to its item key.
+	// Setting it to "false" on a secret opts out when sealing it.
+	SealedSecretBindItemKeysAnnotation = annoNs + "bind-item-keys"
+
+	// SealedSecretCompressionAnnotation is the name for the annotation for
+	// compressing the values of a secret when sealing it: "gzip" or "zstd".
+	SealedSecretCompressionAnnotation = annoNs + "compression"
 )
 
 // SecretTemplateSpec describes the structure a Secret should have
@@ -94,15 +111,17 @@ type SealedSecretConditionType string
 const (
 	// SealedSecretSynced means the SealedSecret has been decrypted and the Secret has been updated successfully.
 	SealedSecretSynced SealedSecretConditionType = "Synced"
+	// SealedSecretReencrypted means the SealedSecret has been re-encrypted for the current sealing key in the background.
+	SealedSecretReencrypted SealedSecretConditionType = "Reencrypted"
 )
 
 // SealedSecretCondition describes the state of a sealed secret at a certain point.
 type SealedSecretCondition struct {
 	// Type of condition for a sealed secret.
-	// Valid value: "Synced"
+	// Valid values: "Synced", "Reencrypted"
 	Type SealedSecretConditionType `json:"type" protobuf:"bytes,1,opt,name=type,casttype=DeploymentConditionType"`
 	// Status of the condition for a sealed secret.
-	// Valid values for "Synced": "True", "False", or "Unknown".
+	// Valid values for "Synced" and "Reencrypted": "True", "False", or "Unknown".
 	Status apiv1.ConditionStatus `json:"status" protobuf:"bytes,2,opt,name=status,casttype=k8s.io/api/core/v1.ConditionStatus"`
 	// The last time this condition was updated.
 	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty" protobuf:"bytes,6,opt,name=lastUpdateTime"`
@@ -156,6 +175,60 @@ type SealedSecretList struct {
 	Items []SealedSecret `json:"items"`
 }
 
+// SealingKeyStatus describes a key pair held by the sealed-secrets controller.
+type SealingKeyStatus struct {
+	// KeyName is the name of the Secret holding the key, or its ID in the key management plugin.
+	KeyName string `json:"keyName"`
+	// Fingerprint of the public key, as found in the encrypted values sealed for it.
+	Fingerprint string `json:"fingerprint"`
+	// NotBefore of the certificate of the key.
+	NotBefore metav1.Time `json:"notBefore"`
+	// NotAfter of the certificate of the key.
+	NotAfter metav1.Time `json:"notAfter"`
+	// OrderingTime is the time the controller orders keys by to pick the most recent one.
+	OrderingTime metav1.Time `json:"orderingTime"`
+	// State is the lifecycle state of the key.
+	// Valid values: "active", "pinned", "decrypt-only", "retired".
+	State string `json:"state"`
+	// Sealing is true for the key new SealedSecrets are sealed for.
+	Sealing bool `json:"sealing"`
+	// DecryptedSealedSecrets is the number of SealedSecrets the key decrypted
+	// when they were last unsealed.
+	DecryptedSealedSecrets int `json:"decryptedSealedSecrets"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Fingerprint",type="string",JSONPath=".status.fingerprint"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Sealing",type="boolean",JSONPath=".status.sealing"
+// +kubebuilder:printcolumn:name="Decrypted",type="integer",JSONPath=".status.decryptedSealedSecrets"
+// +kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.notAfter"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +genclient
+// +genclient:nonNamespaced
+
+// SealingKey exposes a key pair of the sealed-secrets controller, without the
+// private key. It is maintained by the controller and meant to be read only.
+type SealingKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Status *SealingKeyStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SealingKeyList represents a list of SealingKeys.
+type SealingKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []SealingKey `json:"items"`
+}
+
 // ByCreationTimestamp is used to sort a list of secrets.
 type ByCreationTimestamp []apiv1.Secret
 
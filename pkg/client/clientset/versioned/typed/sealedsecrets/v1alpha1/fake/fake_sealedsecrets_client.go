@@ -16,6 +16,10 @@ func (c *FakeBitnamiV1alpha1) SealedSecrets(namespace string) v1alpha1.SealedSec
 	return &FakeSealedSecrets{c, namespace}
 }
 
+func (c *FakeBitnamiV1alpha1) SealingKeys() v1alpha1.SealingKeyInterface {
+	return &FakeSealingKeys{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeBitnamiV1alpha1) RESTClient() rest.Interface {
@@ -13,6 +13,7 @@ import (
 type BitnamiV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	SealedSecretsGetter
+	SealingKeysGetter
 }
 
 // BitnamiV1alpha1Client is used to interact with features provided by the bitnami.com group.
@@ -24,6 +25,10 @@ func (c *BitnamiV1alpha1Client) SealedSecrets(namespace string) SealedSecretInte
 	return newSealedSecrets(c, namespace)
 }
 
+func (c *BitnamiV1alpha1Client) SealingKeys() SealingKeyInterface {
+	return newSealingKeys(c)
+}
+
 // NewForConfig creates a new BitnamiV1alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
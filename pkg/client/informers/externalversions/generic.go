@@ -39,6 +39,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=bitnami.com, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("sealedsecrets"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Bitnami().V1alpha1().SealedSecrets().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("sealingkeys"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Bitnami().V1alpha1().SealingKeys().Informer()}, nil
 
 	}
 
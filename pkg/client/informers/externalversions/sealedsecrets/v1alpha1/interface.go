@@ -10,6 +10,8 @@ import (
 type Interface interface {
 	// SealedSecrets returns a SealedSecretInformer.
 	SealedSecrets() SealedSecretInformer
+	// SealingKeys returns a SealingKeyInformer.
+	SealingKeys() SealingKeyInformer
 }
 
 type version struct {
@@ -27,3 +29,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) SealedSecrets() SealedSecretInformer {
 	return &sealedSecretInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// SealingKeys returns a SealingKeyInformer.
+func (v *version) SealingKeys() SealingKeyInformer {
+	return &sealingKeyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -9,3 +9,7 @@ type SealedSecretListerExpansion interface{}
 // SealedSecretNamespaceListerExpansion allows custom methods to be added to
 // SealedSecretNamespaceLister.
 type SealedSecretNamespaceListerExpansion interface{}
+
+// SealingKeyListerExpansion allows custom methods to be added to
+// SealingKeyLister.
+type SealingKeyListerExpansion interface{}
@@ -2,15 +2,21 @@ package controller
 
 import (
 	"context"
-	"crypto/rsa"
+	gocrypto "crypto"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -34,6 +40,7 @@ import (
 	ssscheme "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/scheme"
 	ssv1alpha1client "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/typed/sealedsecrets/v1alpha1"
 	ssinformer "github.com/bitnami-labs/sealed-secrets/pkg/client/informers/externalversions"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
 	"github.com/bitnami-labs/sealed-secrets/pkg/multidocyaml"
 )
 
@@ -53,42 +60,111 @@ const (
 	// is because it is encrypted with the wrong key or has been
 	// renamed from its original namespace/name.
 	ErrUnsealFailed = "ErrUnsealFailed"
+
+	// ErrTemplateMismatch is used as part of the Event 'reason' and the
+	// Synced condition 'reason' when the template of a template-bound
+	// SealedSecret was changed after sealing.
+	ErrTemplateMismatch = "ErrTemplateMismatch"
+
+	// ErrKeyRetired is used as part of the Event 'reason' and the
+	// Synced condition 'reason' when a SealedSecret can only be
+	// unsealed with keys that have been retired.
+	ErrKeyRetired = "ErrKeyRetired"
 )
 
 var (
 	// ErrCast happens when a K8s any type cannot be casted to the expected type.
 	ErrCast = errors.New("cast error")
 
+	// ErrRetiredKey happens when a SealedSecret was sealed for retired keys only.
+	ErrRetiredKey = errors.New("sealed for retired keys")
+
 	maxRetries = 5
 )
 
+// permanentError wraps the unsealing errors which retrying won't fix, e.g.
+// when a SealedSecret isn't sealed for any known key or its template is
+// invalid. Such SealedSecrets are only unsealed again when the keys change,
+// or when they're updated.
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error {
+	return e.error
+}
+
+// isPermanent reports whether retrying to unseal won't fix an error.
+func isPermanent(err error) bool {
+	var perm permanentError
+	return errors.As(err, &perm)
+}
+
+// unsealRateLimiter returns the rate limiter of the retries of the transient
+// unsealing errors, e.g. API I/O errors. The delay doubles with each failure
+// of a SealedSecret from baseDelay up to maxDelay, and the overall rate of
+// retries is bounded like with the default controller rate limiter.
+func unsealRateLimiter(baseDelay, maxDelay time.Duration) workqueue.TypedRateLimiter[string] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[string](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[string]{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
 // Controller implements the main sealed-secrets-controller loop.
 type Controller struct {
-	queue       workqueue.TypedRateLimitingInterface[string]
-	ssInformer  cache.SharedIndexInformer
-	sInformer   cache.SharedIndexInformer
-	kInformer   cache.SharedIndexInformer
-	sclient     v1.SecretsGetter
-	ssclient    ssv1alpha1client.SealedSecretsGetter
-	recorder    record.EventRecorder
-	keyRegistry *KeyRegistry
+	queue     workqueue.TypedRateLimitingInterface[string]
+	kInformer cache.SharedIndexInformer
+	sclient   v1.SecretsGetter
+	ssclient  ssv1alpha1client.SealedSecretsGetter
+	ssclients ssclientset.Interface
+	recorder  record.EventRecorder
+	// keySets holds the keys (un)sealing the SealedSecrets of each namespace.
+	keySets *keySetRegistries
+	// kInformerStarted makes sure kInformer is started once, by Run or
+	// before, by RunKeyInformer.
+	kInformerStarted sync.Once
+	// informerFactories returns the informer factories of the SealedSecrets
+	// and Secrets of a namespace. The latter is nil when Secrets aren't
+	// watched.
+	informerFactories func(namespace string) (ssinformer.SharedInformerFactory, informers.SharedInformerFactory)
+	// workers is the number of SealedSecrets unsealed concurrently.
+	workers int
+
+	// mu guards namespaces and runCtx, which change while running.
+	mu sync.RWMutex
+	// namespaces holds the informers of each watched namespace, or of all
+	// namespaces under v1.NamespaceAll.
+	namespaces map[string]*namespaceInformers
+	// runCtx is set by Run, and is done when the controller stops.
+	runCtx context.Context
 
 	oldGCBehavior bool // feature flag to revert to old behavior where we delete the secrets instead of relying on owners reference.
 	updateStatus  bool // feature flag that enables updating the status subresource.
 }
 
-// NewController returns the main sealed-secrets controller loop.
+// namespaceInformers are the informers of the SealedSecrets and Secrets of a
+// watched namespace.
+type namespaceInformers struct {
+	ssInformer cache.SharedIndexInformer
+	sInformer  cache.SharedIndexInformer
+	// cancel stops the informers once they run.
+	cancel context.CancelFunc
+}
+
+// NewController returns the main sealed-secrets controller loop. It doesn't
+// unseal anything until WatchNamespace is called.
 func NewController(
 	clientset kubernetes.Interface,
 	ssclientset ssclientset.Interface,
-	ssinformer ssinformer.SharedInformerFactory,
-	sinformer informers.SharedInformerFactory,
+	informerFactories func(namespace string) (ssinformer.SharedInformerFactory, informers.SharedInformerFactory),
 	kinformer informers.SharedInformerFactory,
 	keyRegistry *KeyRegistry,
 	maxRetriesConfig int,
+	retryBaseDelay, retryMaxDelay time.Duration,
 	keyOrderPriority string,
 ) (*Controller, error) {
-	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]())
+	queue := workqueue.NewTypedRateLimitingQueue(unsealRateLimiter(retryBaseDelay, retryMaxDelay))
 
 	utilruntime.Must(ssscheme.AddToScheme(scheme.Scheme))
 	eventBroadcaster := record.NewBroadcaster()
@@ -99,50 +175,182 @@ func NewController(
 	eventBroadcaster.StartRecordingToSink(&v1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "sealed-secrets"})
 
-	ssInformer, err := watchSealedSecrets(ssinformer, queue)
-	if err != nil {
-		return nil, err
+	maxRetries = maxRetriesConfig
+
+	c := &Controller{
+		queue:             queue,
+		sclient:           clientset.CoreV1(),
+		ssclient:          ssclientset.BitnamiV1alpha1(),
+		ssclients:         ssclientset,
+		recorder:          recorder,
+		keySets:           newKeySetRegistries(keyRegistry),
+		informerFactories: informerFactories,
+		workers:           1,
+		namespaces:        map[string]*namespaceInformers{},
 	}
 
-	var sInformer cache.SharedIndexInformer
-	if sinformer != nil {
-		sInformer, err = watchSecrets(sinformer, ssclientset, queue)
+	if kinformer != nil {
+		// The key sets may be replaced until the controller runs.
+		registryOf := func(secret *corev1.Secret) *KeyRegistry {
+			return c.keySets.forKeySecret(secret)
+		}
+		var err error
+		c.kInformer, err = watchKeySecrets(kinformer, registryOf, keyOrderPriority, requeueDependents(c.sealedSecrets, queue, c.registryFor))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	var kInformer cache.SharedIndexInformer
-	if kinformer != nil {
-		kInformer, err = watchKeySecrets(kinformer, keyRegistry, keyOrderPriority)
+	return c, nil
+}
+
+// WatchNamespace starts unsealing the SealedSecrets of a namespace, or of all
+// namespaces if it's v1.NamespaceAll. This method can be called by another
+// goroutine, before or while the controller runs.
+func (c *Controller) WatchNamespace(namespace string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.namespaces[namespace]; ok {
+		return nil
+	}
+
+	ssinformer, sinformer := c.informerFactories(namespace)
+	ssInformer, err := watchSealedSecrets(ssinformer, c.queue)
+	if err != nil {
+		return err
+	}
+	var sInformer cache.SharedIndexInformer
+	if sinformer != nil {
+		sInformer, err = watchSecrets(sinformer, c.ssclients, c.queue)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	maxRetries = maxRetriesConfig
+	ni := &namespaceInformers{ssInformer: ssInformer, sInformer: sInformer}
+	c.namespaces[namespace] = ni
+	if c.runCtx != nil {
+		c.runInformers(ni)
+	}
+	return nil
+}
+
+// UnwatchNamespace stops unsealing the SealedSecrets of a namespace. This
+// method can be called by another goroutine.
+func (c *Controller) UnwatchNamespace(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ni, ok := c.namespaces[namespace]
+	if !ok {
+		return
+	}
+	if ni.cancel != nil {
+		ni.cancel()
+	}
+	delete(c.namespaces, namespace)
+
+	// The informer won't tell about these SealedSecrets any more.
+	for _, obj := range ni.ssInformer.GetStore().List() {
+		if ssecret, ok := obj.(*ssv1alpha1.SealedSecret); ok {
+			UnregisterCondition(ssecret)
+			if key, err := cache.MetaNamespaceKeyFunc(ssecret); err == nil {
+				c.forgetDecryption(key)
+			}
+		}
+	}
+}
+
+// runInformers runs the informers of a namespace until the controller stops
+// or the namespace is unwatched. c.mu must be held.
+func (c *Controller) runInformers(ni *namespaceInformers) {
+	ctx, cancel := context.WithCancel(c.runCtx)
+	ni.cancel = cancel
+	go ni.ssInformer.Run(ctx.Done())
+	if ni.sInformer != nil {
+		go ni.sInformer.Run(ctx.Done())
+	}
+}
+
+// informersFor returns the informers watching the SealedSecrets of a
+// namespace, or nil if it isn't watched.
+func (c *Controller) informersFor(namespace string) *namespaceInformers {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if ni, ok := c.namespaces[namespace]; ok {
+		return ni
+	}
+	return c.namespaces[corev1.NamespaceAll]
+}
+
+// sealedSecrets returns the SealedSecrets of all watched namespaces.
+func (c *Controller) sealedSecrets() []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var objs []interface{}
+	for _, ni := range c.namespaces {
+		objs = append(objs, ni.ssInformer.GetStore().List()...)
+	}
+	return objs
+}
 
-	return &Controller{
-		ssInformer:  ssInformer,
-		sInformer:   sInformer,
-		kInformer:   kInformer,
-		queue:       queue,
-		sclient:     clientset.CoreV1(),
-		ssclient:    ssclientset.BitnamiV1alpha1(),
-		recorder:    recorder,
-		keyRegistry: keyRegistry,
-	}, nil
+// registryFor returns the KeyRegistry of the key set of a namespace.
+func (c *Controller) registryFor(namespace string) (*KeyRegistry, error) {
+	return c.keySets.forNamespace(namespace)
 }
 
-func watchKeySecrets(kinformer informers.SharedInformerFactory, registry *KeyRegistry, keyOrderPriority string) (cache.SharedIndexInformer, error) {
+// forgetDecryption forgets about the SealedSecret with the given cache key in
+// every key set, as it may have been moved from one to another.
+func (c *Controller) forgetDecryption(key string) {
+	for _, kr := range c.keySets.all() {
+		kr.recordDecryption(key, nil)
+	}
+}
+
+// watchKeySecrets keeps the registries returned by registryOf up to date with
+// the key Secrets, and calls keysChanged with the fingerprints of the keys
+// added, removed or changed by each event. Relabelling a Secret so that it's
+// no longer selected deletes it from the informer's point of view. Secrets
+// without registry are ignored.
+func watchKeySecrets(kinformer informers.SharedInformerFactory, registryOf func(*corev1.Secret) *KeyRegistry, keyOrderPriority string, keysChanged func(registry *KeyRegistry, fingerprints []string)) (cache.SharedIndexInformer, error) {
+	update := func(obj interface{}) {
+		secret := obj.(*corev1.Secret)
+		registry := registryOf(secret)
+		if registry == nil {
+			slog.Warn("ignoring key of an unknown key set", "secretname", secret.Name, "keyset", secret.Labels[SealedSecretsKeySetLabel])
+			return
+		}
+		changed, err := registryUpdateKeyWithSecret(secret, registry, keyOrderPriority)
+		if err != nil {
+			slog.Error("failed to register key", "error", err)
+		}
+		if len(changed) > 0 {
+			keysChanged(registry, changed)
+		}
+	}
 	kInformer := kinformer.Core().V1().Secrets().Informer()
 	_, err := kInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			err := registryNewKeyWithSecret(obj.(*corev1.Secret), registry, keyOrderPriority)
-			if err != nil {
-				slog.Error("failed to register key", "error", err)
+		AddFunc: update,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(*corev1.Secret).ResourceVersion == newObj.(*corev1.Secret).ResourceVersion {
+				return
+			}
+			update(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			secret, ok := obj.(*corev1.Secret)
+			if !ok {
 				return
 			}
+			registry := registryOf(secret)
+			if registry == nil {
+				return
+			}
+			if changed := registryDeleteKeyWithSecret(secret, registry); len(changed) > 0 {
+				keysChanged(registry, changed)
+			}
 		},
 	})
 	if err != nil {
@@ -151,6 +359,28 @@ func watchKeySecrets(kinformer informers.SharedInformerFactory, registry *KeyReg
 	return kInformer, nil
 }
 
+// requeueDependents returns a function queueing the SealedSecrets returned by
+// sealedSecrets which were last decrypted by one of the given keys of a
+// registry, or not decrypted at all. registryFor tells the registry of the
+// SealedSecrets of a namespace.
+func requeueDependents(sealedSecrets func() []interface{}, queue workqueue.TypedRateLimitingInterface[string], registryFor func(namespace string) (*KeyRegistry, error)) func(registry *KeyRegistry, fingerprints []string) {
+	return func(registry *KeyRegistry, fingerprints []string) {
+		for _, obj := range sealedSecrets() {
+			key, err := cache.MetaNamespaceKeyFunc(obj)
+			if err != nil {
+				continue
+			}
+			ns, _, _ := cache.SplitMetaNamespaceKey(key)
+			if kr, err := registryFor(ns); err != nil || kr != registry {
+				continue
+			}
+			if registry.dependsOn(key, fingerprints) {
+				queue.Add(key)
+			}
+		}
+	}
+}
+
 func watchSealedSecrets(ssinformer ssinformer.SharedInformerFactory, queue workqueue.TypedRateLimitingInterface[string]) (cache.SharedIndexInformer, error) {
 	ssInformer := ssinformer.Bitnami().V1alpha1().SealedSecrets().Informer()
 	_, err := ssInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -242,23 +472,28 @@ func watchSecrets(sinformer informers.SharedInformerFactory, ssclientset ssclien
 }
 
 // HasSynced returns true once this controller has completed an
-// initial resource listing.
+// initial resource listing of each watched namespace.
 func (c *Controller) HasSynced() bool {
-	var synced bool
-	if c.sInformer == nil {
-		synced = c.ssInformer.HasSynced()
-	} else {
-		synced = c.ssInformer.HasSynced() && c.sInformer.HasSynced()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, ni := range c.namespaces {
+		if !ni.ssInformer.HasSynced() || (ni.sInformer != nil && !ni.sInformer.HasSynced()) {
+			return false
+		}
 	}
-	return synced
+	return true
 }
 
 // LastSyncResourceVersion is the resource version observed when last
-// synced with the underlying store. The value returned is not
-// synchronized with access to the underlying store and is not
-// thread-safe.
-func (c *Controller) LastSyncResourceVersion() string {
-	return c.ssInformer.LastSyncResourceVersion()
+// synced with the SealedSecrets of a watched namespace. The value
+// returned is not synchronized with access to the underlying store and
+// is not thread-safe.
+func (c *Controller) LastSyncResourceVersion(namespace string) string {
+	ni := c.informersFor(namespace)
+	if ni == nil {
+		return ""
+	}
+	return ni.ssInformer.LastSyncResourceVersion()
 }
 
 // Run begins processing items, and will continue until a value is
@@ -269,26 +504,40 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 
 	defer c.queue.ShutDown()
 
-	go c.ssInformer.Run(stopCh)
-	if c.sInformer != nil {
-		go c.sInformer.Run(stopCh)
-	}
-	if c.kInformer != nil {
-		go c.kInformer.Run(stopCh)
+	c.mu.Lock()
+	c.runCtx = wait.ContextForChannel(stopCh)
+	for _, ni := range c.namespaces {
+		c.runInformers(ni)
 	}
+	c.mu.Unlock()
+	c.RunKeyInformer(stopCh)
 
 	if !cache.WaitForCacheSync(stopCh, c.HasSynced) {
 		utilruntime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 		return
 	}
 
-	wait.Until(func() {
-		c.runWorker(context.Background())
-	}, time.Second, stopCh)
+	for range c.workers {
+		go wait.Until(func() {
+			c.runWorker(context.Background())
+		}, time.Second, stopCh)
+	}
+	<-stopCh
 
 	slog.Error("Shutting down controller")
 }
 
+// RunKeyInformer keeps the key registries in sync with the key Secrets, if they
+// are watched, until stopCh is closed, without unsealing anything, e.g. while
+// another replica is leading. Run calls it too; only the first call counts.
+func (c *Controller) RunKeyInformer(stopCh <-chan struct{}) {
+	c.kInformerStarted.Do(func() {
+		if c.kInformer != nil {
+			go c.kInformer.Run(stopCh)
+		}
+	})
+}
+
 func (c *Controller) runWorker(ctx context.Context) {
 	for c.processNextItem(ctx) {
 		// continue looping
@@ -311,6 +560,10 @@ func (c *Controller) processNextItem(ctx context.Context) bool {
 		slog.Error(formatImmutableError(key))
 		c.queue.Forget(key)
 		utilruntime.HandleError(err)
+	} else if isPermanent(err) {
+		// Retrying won't help until the keys change, which requeues it.
+		slog.Error("Error unsealing, waiting for a key change", "key", key, "error", err)
+		c.queue.Forget(key)
 	} else if c.queue.NumRequeues(key) < maxRetries {
 		slog.Error("Error updating, will retry", "key", key, "error", err)
 		c.queue.AddRateLimited(key)
@@ -326,7 +579,16 @@ func (c *Controller) processNextItem(ctx context.Context) bool {
 
 func (c *Controller) unseal(ctx context.Context, key string) (unsealErr error) {
 	unsealRequestsTotal.Inc()
-	obj, exists, err := c.ssInformer.GetIndexer().GetByKey(key)
+	ns, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	ni := c.informersFor(ns)
+	if ni == nil {
+		// The namespace was unwatched since the SealedSecret was queued.
+		return nil
+	}
+	obj, exists, err := ni.ssInformer.GetIndexer().GetByKey(key)
 	if err != nil {
 		slog.Error("Error fetching object from store", "key", key, "error", err)
 		unsealErrorsTotal.WithLabelValues("fetch", "").Inc()
@@ -334,6 +596,8 @@ func (c *Controller) unseal(ctx context.Context, key string) (unsealErr error) {
 	}
 
 	if !exists {
+		c.forgetDecryption(key)
+
 		// the dependent secret will be GC: by k8s itself, see:
 		// https://kubernetes.io/docs/concepts/workloads/controllers/garbage-collection/#owners-and-dependents
 
@@ -354,7 +618,7 @@ func (c *Controller) unseal(ctx context.Context, key string) (unsealErr error) {
 
 	ssecret, err := convertSealedSecret(obj)
 	if err != nil {
-		return err
+		return permanentError{err}
 	}
 	slog.Info("Updating", "key", key)
 
@@ -372,13 +636,25 @@ func (c *Controller) unseal(ctx context.Context, key string) (unsealErr error) {
 		}
 	}(ctx)
 
-	newSecret, err := c.attemptUnseal(ssecret)
+	keyRegistry, err := c.registryFor(ssecret.GetNamespace())
+	var newSecret *corev1.Secret
+	if err == nil {
+		newSecret, err = attemptUnseal(ssecret, keyRegistry)
+		err = classifyUnsealError(err)
+	} else if errors.Is(err, ErrUnknownKeySet) {
+		err = permanentError{err}
+	}
+	// The namespace may have moved to another key set since it was last
+	// unsealed. Forgetting about it also makes key changes requeue it.
+	c.forgetDecryption(key)
 	if err != nil {
-		c.recorder.Eventf(ssecret, corev1.EventTypeWarning, ErrUnsealFailed, "Failed to unseal: %v", err)
+		c.recorder.Eventf(ssecret, corev1.EventTypeWarning, unsealFailureReason(err), "Failed to unseal: %v", err)
 		unsealErrorsTotal.WithLabelValues("unseal", ssecret.GetNamespace()).Inc()
 		return err
 	}
 
+	keyRegistry.recordDecryption(key, decryptingKeys(ssecret, keyRegistry))
+
 	secret, err := c.sclient.Secrets(ssecret.GetObjectMeta().GetNamespace()).Get(ctx, newSecret.GetObjectMeta().GetName(), metav1.GetOptions{})
 	if k8serrors.IsNotFound(err) {
 		secret, err = c.sclient.Secrets(ssecret.GetObjectMeta().GetNamespace()).Create(ctx, newSecret, metav1.CreateOptions{})
@@ -386,7 +662,7 @@ func (c *Controller) unseal(ctx context.Context, key string) (unsealErr error) {
 	if err != nil {
 		c.recorder.Event(ssecret, corev1.EventTypeWarning, ErrUpdateFailed, err.Error())
 		unsealErrorsTotal.WithLabelValues("update", ssecret.GetNamespace()).Inc()
-		return err
+		return classifyAPIError(err)
 	}
 
 	if !metav1.IsControlledBy(secret, ssecret) && !isAnnotatedToBeManaged(secret) && !isAnnotatedToBePatched(secret) {
@@ -442,7 +718,7 @@ func (c *Controller) unseal(ctx context.Context, key string) (unsealErr error) {
 
 			c.recorder.Event(ssecret, corev1.EventTypeWarning, ErrUpdateFailed, message)
 			unsealErrorsTotal.WithLabelValues("update", ssecret.GetNamespace()).Inc()
-			return err
+			return classifyAPIError(err)
 		}
 	}
 
@@ -487,25 +763,19 @@ func (c *Controller) updateSealedSecretStatus(ctx context.Context, ssecret *ssv1
 
 func updateSealedSecretsStatusConditions(st *ssv1alpha1.SealedSecretStatus, unsealError error) bool {
 	var updateRequired bool
-	cond := func() *ssv1alpha1.SealedSecretCondition {
-		for i := range st.Conditions {
-			if st.Conditions[i].Type == ssv1alpha1.SealedSecretSynced {
-				return &st.Conditions[i]
-			}
-		}
-		st.Conditions = append(st.Conditions, ssv1alpha1.SealedSecretCondition{
-			Type: ssv1alpha1.SealedSecretSynced,
-		})
-		return &st.Conditions[len(st.Conditions)-1]
-	}()
+	cond := statusCondition(st, ssv1alpha1.SealedSecretSynced)
 
 	var status corev1.ConditionStatus
+	var reason string
 	if unsealError == nil {
 		status = corev1.ConditionTrue
 		cond.Message = ""
 	} else {
 		status = corev1.ConditionFalse
 		cond.Message = unsealError.Error()
+		if r := unsealFailureReason(unsealError); r != ErrUnsealFailed {
+			reason = r
+		}
 	}
 
 	cond.LastUpdateTime = metav1.Now()
@@ -515,10 +785,63 @@ func updateSealedSecretsStatusConditions(st *ssv1alpha1.SealedSecretStatus, unse
 		cond.Status = status
 		updateRequired = true
 	}
+	if cond.Reason != reason {
+		cond.Reason = reason
+		updateRequired = true
+	}
 
 	return updateRequired
 }
 
+// statusCondition returns the condition of type t, adding it if it's missing.
+func statusCondition(st *ssv1alpha1.SealedSecretStatus, t ssv1alpha1.SealedSecretConditionType) *ssv1alpha1.SealedSecretCondition {
+	for i := range st.Conditions {
+		if st.Conditions[i].Type == t {
+			return &st.Conditions[i]
+		}
+	}
+	st.Conditions = append(st.Conditions, ssv1alpha1.SealedSecretCondition{Type: t})
+	return &st.Conditions[len(st.Conditions)-1]
+}
+
+// ciphertextsOf returns the encrypted values of a SealedSecret, leaving out
+// the ones which aren't valid base64.
+func ciphertextsOf(ss *ssv1alpha1.SealedSecret) [][]byte {
+	var ciphertexts [][]byte
+	for _, v := range ss.Spec.EncryptedData {
+		if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+			ciphertexts = append(ciphertexts, b)
+		}
+	}
+	if len(ss.Spec.Data) > 0 {
+		ciphertexts = append(ciphertexts, ss.Spec.Data)
+	}
+	return ciphertexts
+}
+
+// compressionOf returns the compression algorithm some value of the
+// SealedSecret was compressed with, if any.
+func compressionOf(ss *ssv1alpha1.SealedSecret) crypto.Compression {
+	for _, ciphertext := range ciphertextsOf(ss) {
+		if c, err := crypto.CiphertextCompression(ciphertext); err == nil && c != crypto.CompressionNone {
+			return c
+		}
+	}
+	return crypto.CompressionNone
+}
+
+// unsealFailureReason returns the Event reason matching an unsealing error.
+func unsealFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ssv1alpha1.ErrTemplateMismatch):
+		return ErrTemplateMismatch
+	case errors.Is(err, ErrRetiredKey):
+		return ErrKeyRetired
+	default:
+		return ErrUnsealFailed
+	}
+}
+
 func isAnnotatedToBeManaged(secret *corev1.Secret) bool {
 	return secret.Annotations[ssv1alpha1.SealedSecretManagedAnnotation] == "true"
 }
@@ -527,6 +850,53 @@ func isAnnotatedToBePatched(secret *corev1.Secret) bool {
 	return secret.Annotations[ssv1alpha1.SealedSecretPatchAnnotation] == "true"
 }
 
+// classifyUnsealError marks the decryption failures which retrying won't fix
+// as permanent: values sealed for unknown or retired keys only, a template
+// which doesn't match or render, or values which fail to authenticate or are
+// malformed, as well as a KMS plugin refusing to decrypt them. Other errors,
+// e.g. a KMS plugin which is unavailable or times out, are transient.
+func classifyUnsealError(err error) error {
+	if err != nil && isDecryptionFailure(err) {
+		return permanentError{err}
+	}
+	return err
+}
+
+// isDecryptionFailure reports whether an unsealing error is a decryption
+// failure. Joined errors, one per value, must all be.
+func isDecryptionFailure(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		for _, e := range errs {
+			if !isDecryptionFailure(e) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	// Only an unavailable or slow KMS plugin is worth retrying.
+	if st, ok := status.FromError(err); ok && st.Code() != codes.OK {
+		return st.Code() != codes.Unavailable && st.Code() != codes.DeadlineExceeded
+	}
+	var unknownKey *crypto.UnknownKeyError
+	return errors.As(err, &unknownKey) ||
+		errors.Is(err, ssv1alpha1.ErrTemplateMismatch) ||
+		errors.Is(err, ssv1alpha1.ErrInvalidTemplate) ||
+		errors.Is(err, ErrRetiredKey) ||
+		errors.Is(err, crypto.ErrAuthentication) ||
+		errors.Is(err, crypto.ErrMalformed)
+}
+
+// classifyAPIError marks the errors of the API server which retrying won't
+// fix, i.e. a Secret rejected because of an invalid template, as permanent.
+// Other API errors are transient.
+func classifyAPIError(err error) error {
+	if k8serrors.IsInvalid(err) {
+		return permanentError{err}
+	}
+	return err
+}
+
 func isImmutableError(err error) bool {
 	return strings.HasSuffix(err.Error(), "field is immutable when `immutable` is set")
 }
@@ -569,20 +939,16 @@ func (c *Controller) Rotate(content []byte) ([]byte, error) {
 	switch s := object.(type) {
 	case *ssv1alpha1.SealedSecret:
 		// Verify metainformation is well set up in Template ObjectMeta and ObjectMeta to avoid unconsistences with the scope during the rotate.
-		// This is going to keep the original scope.
-		if !reflect.DeepEqual(s.ObjectMeta, s.Spec.Template.ObjectMeta) {
+		// This is going to keep the original scope. A template-bound secret cannot be realigned
+		// since that would change its template.
+		if !s.TemplateBound() && !reflect.DeepEqual(s.ObjectMeta, s.Spec.Template.ObjectMeta) {
 			s.ObjectMeta.DeepCopyInto(&s.Spec.Template.ObjectMeta)
 			slog.Warn("Sealed Secret metadata doesn't match. Please align your Sealed Secret metadata")
 		}
 
-		secret, err := c.attemptUnseal(s)
-		if err != nil {
-			return nil, fmt.Errorf("error decrypting secret. %v", err)
-		}
-		latestPrivKey := c.keyRegistry.latestPrivateKey()
-		resealedSecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, &latestPrivKey.PublicKey, secret)
+		resealedSecret, err := c.reseal(s)
 		if err != nil {
-			return nil, fmt.Errorf("error creating new sealed secret. %v", err)
+			return nil, err
 		}
 		data, err := json.Marshal(resealedSecret)
 		if err != nil {
@@ -594,14 +960,117 @@ func (c *Controller) Rotate(content []byte) ([]byte, error) {
 	}
 }
 
+// reseal unseals a SealedSecret and seals the result again for the latest key.
+func (c *Controller) reseal(s *ssv1alpha1.SealedSecret) (*ssv1alpha1.SealedSecret, error) {
+	secret, err := c.attemptUnseal(s)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret. %w", err)
+	}
+	return c.resealUnsealed(s, secret)
+}
+
+// resealUnsealed seals the Secret unsealed from a SealedSecret again for the
+// latest key. Only the encrypted values are sealed again; the template, and
+// the data it renders, is kept as it is.
+func (c *Controller) resealUnsealed(s *ssv1alpha1.SealedSecret, secret *corev1.Secret) (*ssv1alpha1.SealedSecret, error) {
+	keyRegistry, err := c.registryFor(s.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	latestPubKey, err := crypto.PublicKey(keyRegistry.latestPrivateKey())
+	if err != nil {
+		return nil, fmt.Errorf("error reading latest key. %v", err)
+	}
+	// Unsealing also renders the template data, which isn't to be sealed.
+	if s.Spec.Data == nil {
+		for key := range s.Spec.Template.Data {
+			if _, ok := s.Spec.EncryptedData[key]; ok {
+				return nil, fmt.Errorf("cannot reseal the value of %q, which the template overrides", key)
+			}
+			delete(secret.Data, key)
+		}
+	}
+	// The template doesn't say whether it's bound, so keep it bound as the
+	// original was.
+	if s.TemplateBound() {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[ssv1alpha1.SealedSecretBindTemplateAnnotation] = "true"
+	}
+	// Likewise for compression, which only the ciphertexts record.
+	if compression := compressionOf(s); compression != crypto.CompressionNone {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[ssv1alpha1.SealedSecretCompressionAnnotation] = compression.String()
+	}
+	resealedSecret, err := ssv1alpha1.NewSealedSecretWithTemplateData(scheme.Codecs, latestPubKey, secret, s.Spec.Template.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error creating new sealed secret. %v", err)
+	}
+	return resealedSecret, nil
+}
+
+// attemptUnseal tries to unseal a SealedSecret with the keys of the key set
+// of its namespace.
 func (c *Controller) attemptUnseal(ss *ssv1alpha1.SealedSecret) (*corev1.Secret, error) {
-	return attemptUnseal(ss, c.keyRegistry)
+	keyRegistry, err := c.registryFor(ss.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return attemptUnseal(ss, keyRegistry)
 }
 
 func attemptUnseal(ss *ssv1alpha1.SealedSecret, keyRegistry *KeyRegistry) (*corev1.Secret, error) {
-	privateKeys := map[string]*rsa.PrivateKey{}
-	for k, v := range keyRegistry.keys {
+	keys := keyRegistry.snapshot()
+	privateKeys := map[string]gocrypto.PrivateKey{}
+	for k, v := range keys.keys {
+		if v.state == KeyStateRetired {
+			continue
+		}
 		privateKeys[k] = v.private
 	}
-	return ss.Unseal(scheme.Codecs, privateKeys)
+	secret, err := ss.Unseal(scheme.Codecs, privateKeys)
+	if err != nil {
+		if retired := retiredKeysOf(ss, keys); len(retired) > 0 {
+			return nil, fmt.Errorf("%w %s: %v", ErrRetiredKey, strings.Join(retired, ", "), err)
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// retiredKeysOf returns the retired keys that some value of the SealedSecret
+// was sealed for, when none of the other keys it was sealed for is available.
+func retiredKeysOf(ss *ssv1alpha1.SealedSecret, keys *keySet) []string {
+	retired := map[string]bool{}
+	for _, ciphertext := range ciphertextsOf(ss) {
+		fingerprints, err := crypto.SealingKeyFingerprints(ciphertext)
+		if err != nil {
+			continue
+		}
+		var dependsOn []string
+		for _, fp := range fingerprints {
+			k, ok := keys.keys[fp]
+			if !ok {
+				continue
+			}
+			if k.state != KeyStateRetired {
+				dependsOn = nil
+				break
+			}
+			dependsOn = append(dependsOn, fp)
+		}
+		for _, fp := range dependsOn {
+			retired[fp] = true
+		}
+	}
+
+	fingerprints := make([]string, 0, len(retired))
+	for fp := range retired {
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Strings(fingerprints)
+	return fingerprints
 }
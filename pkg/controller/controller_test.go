@@ -2,10 +2,11 @@ package controller
 
 import (
 	"context"
-	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,14 +14,23 @@ import (
 
 	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientfeatures "k8s.io/client-go/features"
+	clientfeaturestesting "k8s.io/client-go/features/testing"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	ktesting "k8s.io/client-go/testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
 )
 
 func TestIsAnnotatedToBePatched(t *testing.T) {
@@ -133,15 +143,15 @@ func TestDefaultConfigDoesNotSkipRecreate(t *testing.T) {
 	ssc := ssfake.NewSimpleClientset()
 	keyRegistry := testKeyRegister(t, context.Background(), clientset, ns)
 
-	got, err := prepareController(clientset, ns, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
+	got, err := prepareController(clientset, []string{ns}, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
 	if err != nil {
 		t.Fatalf("err %v want %v", got, nil)
 	}
 	if got == nil {
 		t.Fatalf("ctrl %v want non nil", got)
 	}
-	if got.sInformer == nil {
-		t.Fatalf("sInformer %v want non nil", got.sInformer)
+	if got.namespaces[ns].sInformer == nil {
+		t.Fatalf("sInformer %v want non nil", got.namespaces[ns].sInformer)
 	}
 }
 
@@ -153,15 +163,15 @@ func TestSkipRecreateConfigDoesSkipIt(t *testing.T) {
 	ssc := ssfake.NewSimpleClientset()
 	keyRegistry := testKeyRegister(t, context.Background(), clientset, ns)
 
-	got, err := prepareController(clientset, ns, keyNs, tweakopts, &Flags{SkipRecreate: true}, ssc, keyRegistry)
+	got, err := prepareController(clientset, []string{ns}, keyNs, tweakopts, &Flags{SkipRecreate: true}, ssc, keyRegistry)
 	if err != nil {
 		t.Fatalf("err %v want %v", got, nil)
 	}
 	if got == nil {
 		t.Fatalf("ctrl %v want non nil", got)
 	}
-	if got.sInformer != nil {
-		t.Fatalf("sInformer %v want nil", got.sInformer)
+	if got.namespaces[ns].sInformer != nil {
+		t.Fatalf("sInformer %v want nil", got.namespaces[ns].sInformer)
 	}
 }
 
@@ -222,13 +232,37 @@ func TestSyncedSecretWithErrorSendsUpdate(t *testing.T) {
 	}
 }
 
+func TestTemplateMismatchSetsReason(t *testing.T) {
+	status := &ssv1alpha1.SealedSecretStatus{
+		Conditions: []ssv1alpha1.SealedSecretCondition{{
+			Type:   ssv1alpha1.SealedSecretSynced,
+			Status: "False",
+		}},
+	}
+	updateRequired := updateSealedSecretsStatusConditions(status, fmt.Errorf("unseal: %w", ssv1alpha1.ErrTemplateMismatch))
+
+	if !updateRequired {
+		t.Fatalf("expected status update, but no update was send")
+	}
+	if got, want := status.Conditions[0].Reason, ErrTemplateMismatch; got != want {
+		t.Errorf("got reason %q, want %q", got, want)
+	}
+
+	if !updateSealedSecretsStatusConditions(status, nil) {
+		t.Fatalf("expected status update, but no update was send")
+	}
+	if got := status.Conditions[0].Reason; got != "" {
+		t.Errorf("got reason %q, want none", got)
+	}
+}
+
 func testKeyRegister(t *testing.T, ctx context.Context, clientset kubernetes.Interface, ns string) *KeyRegistry {
 	t.Helper()
 
 	keyLabel := SealedSecretsKeyLabel
 	prefix := "test-keys"
 	testKeySize := 4096
-	keyRegistry, err := initKeyRegistry(ctx, clientset, rand.Reader, ns, prefix, keyLabel, testKeySize, "CertNotBefore")
+	keyRegistry, err := initKeyRegistry(ctx, clientset, ns, prefix, keyLabel, KeyTypeRSA, testKeySize, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("failed to provision key registry: %v", err)
 	}
@@ -266,15 +300,15 @@ func TestRotate(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	controller, err := prepareController(clientset, ns, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
+	controller, err := prepareController(clientset, []string{ns}, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
 	if err != nil {
 		t.Fatalf("err %v want %v", err, nil)
 	}
 	if controller == nil {
 		t.Fatalf("ctrl %v want non nil", controller)
 	}
-	if controller.sInformer == nil {
-		t.Fatalf("sInformer %v want non nil", controller.sInformer)
+	if controller.namespaces[ns].sInformer == nil {
+		t.Fatalf("sInformer %v want non nil", controller.namespaces[ns].sInformer)
 	}
 
 	secret := &corev1.Secret{
@@ -292,7 +326,7 @@ func TestRotate(t *testing.T) {
 		},
 	}
 
-	cert, err := controller.keyRegistry.getCert()
+	cert, err := controller.keySets.defaultSet.getCert()
 	if err != nil {
 		t.Fatalf("error getting certificate: %v", err)
 	}
@@ -337,15 +371,15 @@ func TestRotateKeepScope(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	controller, err := prepareController(clientset, ns, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
+	controller, err := prepareController(clientset, []string{ns}, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
 	if err != nil {
 		t.Fatalf("err %v want %v", err, nil)
 	}
 	if controller == nil {
 		t.Fatalf("ctrl %v want non nil", controller)
 	}
-	if controller.sInformer == nil {
-		t.Fatalf("sInformer %v want non nil", controller.sInformer)
+	if controller.namespaces[ns].sInformer == nil {
+		t.Fatalf("sInformer %v want non nil", controller.namespaces[ns].sInformer)
 	}
 
 	secret := &corev1.Secret{
@@ -363,7 +397,7 @@ func TestRotateKeepScope(t *testing.T) {
 		},
 	}
 
-	cert, err := controller.keyRegistry.getCert()
+	cert, err := controller.keySets.defaultSet.getCert()
 	if err != nil {
 		t.Fatalf("error getting certificate: %v", err)
 	}
@@ -398,3 +432,335 @@ func TestRotateKeepScope(t *testing.T) {
 		t.Fatalf("Scope from the original and the rotate sealed secret do not match")
 	}
 }
+
+func TestRotateBoundTemplate(t *testing.T) {
+	ns := "some-namespace"
+	keyNs := "some-key-namespace"
+	var tweakopts func(*metav1.ListOptions)
+	clientset := fake.NewClientset()
+	ssc := ssfake.NewSimpleClientset()
+	keyRegistry := testKeyRegister(t, context.Background(), clientset, ns)
+	if _, err := keyRegistry.generateKey(context.Background(), time.Hour, "my-cn", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	controller, err := prepareController(clientset, []string{ns}, keyNs, tweakopts, &Flags{SkipRecreate: false}, ssc, keyRegistry)
+	if err != nil {
+		t.Fatalf("err %v want %v", err, nil)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ss",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "myapp"},
+			Annotations: map[string]string{
+				ssv1alpha1.SealedSecretBindTemplateAnnotation: "true",
+			},
+		},
+		Data: map[string][]byte{
+			"password": []byte("temporal"),
+		},
+	}
+
+	cert, err := controller.keySets.defaultSet.getCert()
+	if err != nil {
+		t.Fatalf("error getting certificate: %v", err)
+	}
+
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, cert.PublicKey, secret)
+	if err != nil {
+		t.Fatalf("error creating sealed secrets: %v", err)
+	}
+
+	prettyEnc, err := prettyEncoder(scheme.Codecs, runtime.ContentTypeJSON, ssv1alpha1.SchemeGroupVersion)
+	if err != nil {
+		t.Fatalf("unexpected pretty encoding: %v", err)
+	}
+
+	data, err := runtime.Encode(prettyEnc, ssecret)
+	if err != nil {
+		t.Fatalf("unexpected encoding the sealed secret: %v", err)
+	}
+
+	out, err := controller.Rotate(data)
+	if err != nil {
+		t.Fatalf("Rotate() returned error: %v", err)
+	}
+
+	s := &ssv1alpha1.SealedSecret{}
+	if err = json.Unmarshal(out, s); err != nil {
+		t.Fatalf("error unmarshalling the rotate sealed secret")
+	}
+	if !s.TemplateBound() {
+		t.Errorf("rotated sealed secret isn't template-bound anymore")
+	}
+	if _, err := attemptUnseal(s, keyRegistry); err != nil {
+		t.Errorf("error unsealing the rotated sealed secret: %v", err)
+	}
+
+	s.Spec.Template.Labels["app"] = "otherapp"
+	if _, err := attemptUnseal(s, keyRegistry); !errors.Is(err, ssv1alpha1.ErrTemplateMismatch) {
+		t.Errorf("got error %v, want %v", err, ssv1alpha1.ErrTemplateMismatch)
+	}
+}
+
+func TestRotateKeepsCompression(t *testing.T) {
+	ns := "some-namespace"
+	clientset := fake.NewClientset()
+	ssc := ssfake.NewSimpleClientset()
+	keyRegistry := testKeyRegister(t, context.Background(), clientset, ns)
+	if _, err := keyRegistry.generateKey(context.Background(), time.Hour, "my-cn", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	controller, err := prepareController(clientset, []string{ns}, "some-key-namespace", nil, &Flags{}, ssc, keyRegistry)
+	if err != nil {
+		t.Fatalf("err %v want %v", err, nil)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ss",
+			Namespace: "default",
+			Annotations: map[string]string{
+				ssv1alpha1.SealedSecretCompressionAnnotation: "gzip",
+			},
+		},
+		Data: map[string][]byte{
+			"bundle": []byte(strings.Repeat("temporal", 100)),
+		},
+	}
+
+	cert, err := controller.keySets.defaultSet.getCert()
+	if err != nil {
+		t.Fatalf("error getting certificate: %v", err)
+	}
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, cert.PublicKey, secret)
+	if err != nil {
+		t.Fatalf("error creating sealed secrets: %v", err)
+	}
+
+	resealed, err := controller.reseal(ssecret)
+	if err != nil {
+		t.Fatalf("reseal() returned error: %v", err)
+	}
+	if _, ok := resealed.Spec.Template.Annotations[ssv1alpha1.SealedSecretCompressionAnnotation]; ok {
+		t.Errorf("the template of the resealed secret has the compression annotation")
+	}
+	if got, want := compressionOf(resealed), crypto.CompressionGzip; got != want {
+		t.Errorf("resealed values compressed with %v, want %v", got, want)
+	}
+}
+
+func TestUnsealRetiredKey(t *testing.T) {
+	const keySize = 2048
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, keySize)
+	key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, time.Hour, "my-cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kr.registerNewKey("k1", key, []*x509.Certificate{cert}, time.Now(), KeyStateDecryptOnly, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("temporal")},
+	}
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, cert.PublicKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decrypt-only keys still unseal.
+	if _, err := attemptUnseal(ssecret, kr); err != nil {
+		t.Fatalf("attemptUnseal() returned error: %v", err)
+	}
+
+	if err := kr.registerNewKey("k1", key, []*x509.Certificate{cert}, time.Now(), KeyStateRetired, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = attemptUnseal(ssecret, kr)
+	if !errors.Is(err, ErrRetiredKey) {
+		t.Fatalf("got error %v, want %v", err, ErrRetiredKey)
+	}
+	if got, want := unsealFailureReason(err), ErrKeyRetired; got != want {
+		t.Errorf("got reason %q, want %q", got, want)
+	}
+
+	status := &ssv1alpha1.SealedSecretStatus{}
+	updateSealedSecretsStatusConditions(status, err)
+	if got, want := status.Conditions[0].Reason, ErrKeyRetired; got != want {
+		t.Errorf("got condition reason %q, want %q", got, want)
+	}
+}
+
+func TestWatchNamespaces(t *testing.T) {
+	// The fake SealedSecrets clientset doesn't stream the initial list of a watch.
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	ctx := context.Background()
+	kr := NewKeyRegistry(nil, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	cert := registerTestKey(t, kr, "k1", time.Hour, time.Time{})
+
+	var sealedSecrets []runtime.Object
+	for _, ns := range []string{"ns1", "ns2", "ns3"} {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: ns},
+			Data:       map[string][]byte{"password": []byte("temporal")},
+		}
+		ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, cert.PublicKey, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sealedSecrets = append(sealedSecrets, ssecret)
+	}
+	clientset := fake.NewClientset()
+	ssc := ssfake.NewSimpleClientset(sealedSecrets...)
+
+	controller, err := prepareController(clientset, []string{"ns1", "ns2"}, "namespace", nil, &Flags{Workers: 2}, ssc, kr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go controller.Run(stop)
+
+	// A single controller unseals the SealedSecrets of every watched namespace.
+	for _, ns := range []string{"ns1", "ns2"} {
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+			_, err := clientset.CoreV1().Secrets(ns).Get(ctx, "ss", metav1.GetOptions{})
+			return err == nil, nil
+		})
+		if err != nil {
+			t.Errorf("the SealedSecret of namespace %s wasn't unsealed", ns)
+		}
+	}
+	if _, err := clientset.CoreV1().Secrets("ns3").Get(ctx, "ss", metav1.GetOptions{}); err == nil {
+		t.Errorf("the SealedSecret of an unwatched namespace was unsealed")
+	}
+
+	// Namespaces can be watched and unwatched while running.
+	if err := controller.WatchNamespace("ns3"); err != nil {
+		t.Fatalf("WatchNamespace() returned error: %v", err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		_, err := clientset.CoreV1().Secrets("ns3").Get(ctx, "ss", metav1.GetOptions{})
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Errorf("the SealedSecret of a newly watched namespace wasn't unsealed")
+	}
+	controller.UnwatchNamespace("ns2")
+	if controller.informersFor("ns2") != nil {
+		t.Errorf("an unwatched namespace still has informers")
+	}
+	if err := controller.unseal(ctx, "ns2/ss"); err != nil {
+		t.Errorf("unseal() of a SealedSecret of an unwatched namespace returned error: %v", err)
+	}
+}
+
+func TestUnsealErrorClassification(t *testing.T) {
+	ctx := context.Background()
+	kr := NewKeyRegistry(nil, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	registerTestKey(t, kr, "k1", time.Hour, time.Time{})
+	_, otherCert, err := generatePrivateKeyAndCert(KeyTypeRSA, 1024, time.Hour, "my-cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seal := func(name string, cert *x509.Certificate) *ssv1alpha1.SealedSecret {
+		t.Helper()
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("temporal")},
+		}
+		ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, cert.PublicKey, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ssecret
+	}
+	cert, err := kr.getCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("create", "secrets", func(action ktesting.Action) (bool, runtime.Object, error) {
+		if action.(ktesting.CreateAction).GetObject().(*corev1.Secret).Name == "timeout" {
+			return true, nil, k8serrors.NewServerTimeout(corev1.Resource("secrets"), "create", 1)
+		}
+		return false, nil, nil
+	})
+	controller, err := prepareController(clientset, []string{"default"}, "namespace", nil, &Flags{MaxRetries: 5}, ssfake.NewSimpleClientset(), kr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.queue.ShutDown()
+	store := controller.namespaces["default"].ssInformer.GetStore()
+	for _, ssecret := range []*ssv1alpha1.SealedSecret{seal("wrong-key", otherCert), seal("timeout", cert)} {
+		if err := store.Add(ssecret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	process := func(key string) {
+		t.Helper()
+		controller.queue.Add(key)
+		if !controller.processNextItem(ctx) {
+			t.Fatalf("processNextItem() stopped")
+		}
+	}
+
+	// A SealedSecret which cannot be decrypted isn't retried...
+	process("default/wrong-key")
+	if got := controller.queue.NumRequeues("default/wrong-key"); got != 0 {
+		t.Errorf("a permanent failure was retried %d times", got)
+	}
+	// ...until the keys change.
+	requeueDependents(controller.sealedSecrets, controller.queue, controller.registryFor)(kr, []string{"new-key"})
+	if got := controller.queue.Len(); got != 2 {
+		t.Errorf("got %d queued SealedSecrets after a key change, want 2", got)
+	}
+	for controller.queue.Len() > 0 {
+		key, _ := controller.queue.Get()
+		controller.queue.Done(key)
+	}
+
+	// API I/O errors are retried.
+	process("default/timeout")
+	if got := controller.queue.NumRequeues("default/timeout"); got != 1 {
+		t.Errorf("a transient failure was retried %d times, want 1", got)
+	}
+
+	if !isPermanent(classifyAPIError(k8serrors.NewInvalid(corev1.SchemeGroupVersion.WithKind("Secret").GroupKind(), "invalid", nil))) {
+		t.Errorf("a Secret rejected as invalid isn't a permanent failure")
+	}
+
+	unknownKey := &crypto.UnknownKeyError{Fingerprints: []string{"other-key"}}
+	for _, err := range []error{
+		errors.Join(unknownKey, ssv1alpha1.ErrTemplateMismatch),
+		fmt.Errorf("%w: function \"nope\" not defined", ssv1alpha1.ErrInvalidTemplate),
+		fmt.Errorf("%w: illegal base64 data at input byte 3", crypto.ErrMalformed),
+		fmt.Errorf("no key could decrypt secret: %w", crypto.ErrAuthentication),
+		fmt.Errorf("no key could decrypt secret: %w", status.Error(codes.NotFound, "unknown key")),
+	} {
+		if !isPermanent(classifyUnsealError(err)) {
+			t.Errorf("%v isn't a permanent failure", err)
+		}
+	}
+	// A value which cannot be decrypted for now makes the whole SealedSecret
+	// transient.
+	for _, err := range []error{
+		context.DeadlineExceeded,
+		errors.Join(unknownKey, fmt.Errorf("no key could decrypt secret: %w", context.DeadlineExceeded)),
+		fmt.Errorf("no key could decrypt secret: %w", status.Error(codes.Unavailable, "plugin is restarting")),
+		status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+	} {
+		if isPermanent(classifyUnsealError(err)) {
+			t.Errorf("%v is a permanent failure", err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	certUtil "k8s.io/client-go/util/cert"
+)
+
+// csrPollInterval is how often a pending CertificateSigningRequest is checked for approval.
+var csrPollInterval = 2 * time.Second
+
+var (
+	// ErrCSRDenied is returned when the CertificateSigningRequest for a sealing key was denied or failed.
+	ErrCSRDenied = errors.New("certificate signing request was not granted")
+)
+
+// requestCertificate has the public key of key certified by signerName through
+// the Kubernetes certificates API. It waits up to timeout for the request to be
+// approved and signed, and returns the issued chain, leaf first.
+func requestCertificate(ctx context.Context, client kubernetes.Interface, key gocrypto.PrivateKey, signerName string, validFor, timeout time.Duration, cn string) ([]*x509.Certificate, error) {
+	der, err := crypto.CreateCertificateRequest(rand.Reader, key, cn)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := classicalPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	usage := certificatesv1.UsageKeyEncipherment
+	if _, ok := pub.(*ecdsa.PublicKey); ok {
+		// EC keys only take part in ECDH key agreement.
+		usage = certificatesv1.UsageKeyAgreement
+	}
+	expirationSeconds := int32(validFor / time.Second)
+
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "sealed-secrets-",
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateRequestBlockType, Bytes: der}),
+			SignerName:        signerName,
+			Usages:            []certificatesv1.KeyUsage{usage},
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	csr, err = client.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate signing request: %w", err)
+	}
+	slog.Info("Waiting for certificate signing request to be approved", "name", csr.Name, "signer", signerName)
+
+	var issued []byte
+	err = wait.PollUntilContextTimeout(ctx, csrPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		csr, err := client.CertificatesV1().CertificateSigningRequests().Get(ctx, csr.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, c := range csr.Status.Conditions {
+			if (c.Type == certificatesv1.CertificateDenied || c.Type == certificatesv1.CertificateFailed) && c.Status == v1.ConditionTrue {
+				return false, fmt.Errorf("%w: %s %s: %s", ErrCSRDenied, csr.Name, c.Type, c.Message)
+			}
+		}
+		issued = csr.Status.Certificate
+		return len(issued) > 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for certificate signing request %s: %w", csr.Name, err)
+	}
+
+	certs, err := certUtil.ParseCertsPEM(issued)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIssuedCertificate(key, pub, certs[0]); err != nil {
+		return nil, fmt.Errorf("certificate signing request %s: %w", csr.Name, err)
+	}
+	return certs, nil
+}
+
+// checkIssuedCertificate makes sure the signer certified the key we asked for.
+func checkIssuedCertificate(key gocrypto.PrivateKey, pub gocrypto.PublicKey, cert *x509.Certificate) error {
+	certPub, ok := cert.PublicKey.(interface{ Equal(gocrypto.PublicKey) bool })
+	if !ok || !certPub.Equal(pub) {
+		return errors.New("issued certificate doesn't match the sealing key")
+	}
+	if _, pq := key.(*crypto.PQPrivateKey); pq {
+		certKey, err := crypto.CertPublicKey(cert)
+		if err != nil {
+			return err
+		}
+		if _, ok := certKey.(*crypto.PQPublicKey); !ok {
+			slog.Warn("The signer dropped the ML-KEM key from the certificate, new secrets are sealed in classical mode", "serial", cert.SerialNumber)
+		}
+	}
+	return nil
+}
+
+func classicalPublicKey(key gocrypto.PrivateKey) (gocrypto.PublicKey, error) {
+	if pq, ok := key.(*crypto.PQPrivateKey); ok {
+		key = pq.Classical
+	}
+	signer, ok := key.(gocrypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedPrivateKey, key)
+	}
+	return signer.Public(), nil
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+	certUtil "k8s.io/client-go/util/cert"
+)
+
+const testSignerName = "example.com/sealed-secrets"
+
+type testSigner struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), testRand())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(testRand(), tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{key: key, cert: cert}
+}
+
+// approve is a reactor which approves and signs CertificateSigningRequests right
+// away, the way an automated approver and signer would.
+func (s *testSigner) approve(action ktesting.Action) (bool, runtime.Object, error) {
+	csr := action.(ktesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+	csr.Name = csr.GenerateName + "test"
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return true, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		Subject:         req.Subject,
+		NotBefore:       time.Now(),
+		NotAfter:        time.Now().Add(time.Duration(*csr.Spec.ExpirationSeconds) * time.Second),
+		KeyUsage:        x509.KeyUsageKeyEncipherment,
+		ExtraExtensions: req.Extensions,
+	}
+	der, err := x509.CreateCertificate(testRand(), tmpl, s.cert, req.PublicKey, s.key)
+	if err != nil {
+		return true, nil, err
+	}
+	csr.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{{Type: certificatesv1.CertificateApproved, Status: v1.ConditionTrue}}
+	csr.Status.Certificate = append(pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: s.cert.Raw})...)
+	return false, nil, nil
+}
+
+func deny(action ktesting.Action) (bool, runtime.Object, error) {
+	csr := action.(ktesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+	csr.Name = csr.GenerateName + "test"
+	csr.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{{Type: certificatesv1.CertificateDenied, Status: v1.ConditionTrue, Message: "not today"}}
+	return false, nil, nil
+}
+
+func TestGenerateKeyWithCSR(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	for _, keyType := range []string{KeyTypeRSA, KeyTypeECP256MLKEM768} {
+		t.Run(keyType, func(t *testing.T) {
+			client := fake.NewClientset()
+			client.PrependReactor("create", "secrets", generateNameReactor)
+			client.PrependReactor("create", "certificatesigningrequests", signer.approve)
+
+			registry := NewKeyRegistry(client, "namespace", "prefix", SealedSecretsKeyLabel, keyType, 2048)
+			registry.csrSignerName = testSignerName
+			registry.csrTimeout = time.Second
+
+			name, err := registry.generateKey(ctx, time.Hour, "my-cn", "", "")
+			if err != nil {
+				t.Fatalf("generateKey() returned error: %v", err)
+			}
+
+			csr := findAction(client, "create", "certificatesigningrequests").(ktesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+			if got, want := csr.Spec.SignerName, testSignerName; got != want {
+				t.Errorf("got signer %q, want %q", got, want)
+			}
+
+			chain, err := registry.getCertChain()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(chain), 2; got != want {
+				t.Fatalf("got %d certificates, want %d", got, want)
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(signer.cert)
+			if _, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+				t.Errorf("issued certificate doesn't verify: %v", err)
+			}
+
+			// The whole chain is stored along with the key.
+			secret, err := client.CoreV1().Secrets("namespace").Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, certs, err := readKey(secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(certs), 2; got != want {
+				t.Errorf("got %d stored certificates, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateKeyWithDeniedCSR(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	client.PrependReactor("create", "secrets", generateNameReactor)
+	client.PrependReactor("create", "certificatesigningrequests", deny)
+
+	registry := NewKeyRegistry(client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 2048)
+	registry.csrSignerName = testSignerName
+	registry.csrTimeout = time.Second
+
+	if _, err := registry.generateKey(ctx, time.Hour, "my-cn", "", ""); !errors.Is(err, ErrCSRDenied) {
+		t.Errorf("got error %v, want %v", err, ErrCSRDenied)
+	}
+	if hasAction(client, "create", "secrets") {
+		t.Errorf("key written despite the denied certificate signing request")
+	}
+	if _, err := registry.getCert(); err == nil {
+		t.Errorf("key registered despite the denied certificate signing request")
+	}
+}
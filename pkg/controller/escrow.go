@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	certUtil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/retry"
+)
+
+// SealedSecretsKeyEscrowLabel marks the ConfigMap holding the escrowed sealing keys.
+const SealedSecretsKeyEscrowLabel = "sealedsecrets.bitnami.com/key-escrow"
+
+// A keyEscrow encrypts the sealing keys to offline escrow public keys and
+// publishes the ciphertexts in a ConfigMap and/or a directory.
+type keyEscrow struct {
+	recipients gocrypto.PublicKey
+	client     kubernetes.Interface
+	namespace  string
+	configMap  string
+	path       string
+}
+
+// newKeyEscrow reads the escrow public keys from the PEM certificates in certFiles.
+func newKeyEscrow(client kubernetes.Interface, namespace string, certFiles []string, configMap, path string) (*keyEscrow, error) {
+	if len(certFiles) == 0 {
+		return nil, errors.New("no escrow certificate given")
+	}
+	if configMap == "" && path == "" {
+		return nil, errors.New("escrowed keys need to be published to a ConfigMap or a path")
+	}
+
+	var recipients crypto.Recipients
+	for _, f := range certFiles {
+		// #nosec G304 -- should open user provided file
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		certs, err := certUtil.ParseCertsPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		pubKey, err := crypto.CertPublicKey(certs[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		recipients = append(recipients, pubKey)
+	}
+
+	e := &keyEscrow{
+		recipients: recipients,
+		client:     client,
+		namespace:  namespace,
+		configMap:  configMap,
+		path:       path,
+	}
+	if len(recipients) == 1 {
+		e.recipients = recipients[0]
+	}
+	return e, nil
+}
+
+// escrow publishes the key called name, of the given key set if it isn't the
+// default one, unless it has been escrowed already. Keys held by a key
+// management plugin cannot be exported and are skipped.
+func (e *keyEscrow) escrow(ctx context.Context, name, keySet string, key gocrypto.PrivateKey, certs []*x509.Certificate) error {
+	classical := key
+	if pq, ok := key.(*crypto.PQPrivateKey); ok {
+		classical = pq.Classical
+	}
+	switch classical.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return nil
+	}
+
+	var block []byte
+	seal := func() ([]byte, error) {
+		if block != nil {
+			return block, nil
+		}
+		data, err := keySecretData(key, certs)
+		if err != nil {
+			return nil, err
+		}
+		labels := map[string]string{SealedSecretsKeyLabel: "active"}
+		if keySet != "" {
+			labels[SealedSecretsKeySetLabel] = keySet
+		}
+		backup, err := json.Marshal(&v1.Secret{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: e.namespace,
+				Labels:    labels,
+			},
+			Data: data,
+			Type: v1.SecretTypeTLS,
+		})
+		if err != nil {
+			return nil, err
+		}
+		block, err = crypto.SealEscrow(rand.Reader, e.recipients, name, backup)
+		clear(backup)
+		return block, err
+	}
+
+	if e.configMap != "" {
+		if err := e.publishToConfigMap(ctx, name, seal); err != nil {
+			return fmt.Errorf("escrowing key %s to ConfigMap %s: %w", name, e.configMap, err)
+		}
+	}
+	if e.path != "" {
+		if err := e.publishToPath(name, seal); err != nil {
+			return fmt.Errorf("escrowing key %s to %s: %w", name, e.path, err)
+		}
+	}
+	return nil
+}
+
+func (e *keyEscrow) publishToConfigMap(ctx context.Context, name string, seal func() ([]byte, error)) error {
+	cms := e.client.CoreV1().ConfigMaps(e.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := cms.Get(ctx, e.configMap, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			block, err := seal()
+			if err != nil {
+				return err
+			}
+			cm = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      e.configMap,
+					Namespace: e.namespace,
+					Labels:    map[string]string{SealedSecretsKeyEscrowLabel: "true"},
+				},
+				Data: map[string]string{name: string(block)},
+			}
+			_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+			if k8serrors.IsAlreadyExists(err) {
+				return k8serrors.NewConflict(v1.Resource("configmaps"), e.configMap, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := cm.Data[name]; ok {
+			return nil
+		}
+		block, err := seal()
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[name] = string(block)
+		_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (e *keyEscrow) publishToPath(name string, seal func() ([]byte, error)) error {
+	filename := filepath.Join(e.path, name+".pem")
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+	block, err := seal()
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a partial file never looks escrowed.
+	tmp := filename + ".tmp"
+	// #nosec G306 -- the escrowed key is encrypted
+	if err := os.WriteFile(tmp, block, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// escrowKeys escrows all the keys of the registry, e.g. the ones discovered on startup.
+func (e *keyEscrow) escrowKeys(ctx context.Context, kr *KeyRegistry) {
+	for _, k := range kr.snapshot().keys {
+		if err := e.escrow(ctx, k.name, kr.keySetName, k.private, k.chain); err != nil {
+			slog.Error("Failed to escrow key", "error", err)
+		}
+	}
+}
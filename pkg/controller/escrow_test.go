@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	gocrypto "crypto"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	certUtil "k8s.io/client-go/util/cert"
+)
+
+func TestEscrowKeys(t *testing.T) {
+	ctx := context.Background()
+	offlineKey, offlineCert, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, err := crypto.PublicKeyFingerprint(&offlineKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline := map[string]gocrypto.PrivateKey{fp: offlineKey}
+	certFile := filepath.Join(t.TempDir(), "offline.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: offlineCert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewClientset()
+	client.PrependReactor("create", "secrets", generateNameReactor)
+	escrowDir := t.TempDir()
+	escrow, err := newKeyEscrow(client, "namespace", []string{certFile}, "sealed-secrets-escrow", escrowDir)
+	if err != nil {
+		t.Fatalf("newKeyEscrow() returned error: %v", err)
+	}
+
+	registry := NewKeyRegistry(client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 2048)
+	registry.escrow = escrow
+	name, err := registry.generateKey(ctx, time.Hour, "my-cn", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("namespace").Get(ctx, "sealed-secrets-escrow", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("escrow ConfigMap not created: %v", err)
+	}
+	if got, want := cm.Labels[SealedSecretsKeyEscrowLabel], "true"; got != want {
+		t.Errorf("got label %q, want %q", got, want)
+	}
+	fromFile, err := os.ReadFile(filepath.Join(escrowDir, name+".pem"))
+	if err != nil {
+		t.Fatalf("escrowed key not written: %v", err)
+	}
+
+	for _, data := range []string{cm.Data[name], string(fromFile)} {
+		block, _ := pem.Decode([]byte(data))
+		if block == nil {
+			t.Fatalf("escrowed key isn't a PEM block: %q", data)
+		}
+		gotName, backup, err := crypto.OpenEscrow(testRand(), offline, block)
+		if err != nil {
+			t.Fatalf("OpenEscrow() returned error: %v", err)
+		}
+		if gotName != name {
+			t.Errorf("got escrowed key %q, want %q", gotName, name)
+		}
+		var secret v1.Secret
+		if err := json.Unmarshal(backup, &secret); err != nil {
+			t.Fatal(err)
+		}
+		key, certs, err := readKey(&secret)
+		if err != nil {
+			t.Fatalf("readKey() of the escrowed key returned error: %v", err)
+		}
+		if !reflect.DeepEqual(key, registry.latestPrivateKey()) || !reflect.DeepEqual(certs, registry.snapshot().mostRecentKey.chain) {
+			t.Errorf("escrowed key doesn't match the generated one")
+		}
+	}
+
+	// Keys are escrowed once.
+	client.ClearActions()
+	escrow.escrowKeys(ctx, registry)
+	if hasAction(client, "update", "configmaps") || hasAction(client, "create", "configmaps") {
+		t.Errorf("already escrowed key escrowed again")
+	}
+}
+
+func TestNewKeyEscrowInvalid(t *testing.T) {
+	if _, err := newKeyEscrow(nil, "namespace", nil, "sealed-secrets-escrow", ""); err == nil {
+		t.Errorf("newKeyEscrow() succeeded without escrow certificates")
+	}
+	if _, err := newKeyEscrow(nil, "namespace", []string{"cert.pem"}, "", ""); err == nil {
+		t.Errorf("newKeyEscrow() succeeded without anywhere to publish to")
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// ErrKeyExpiring is used as part of the Event 'reason' when the
+	// certificate of the sealing key is about to expire without replacement.
+	ErrKeyExpiring = "ErrKeyExpiring"
+
+	// ErrKeyExpired is used as part of the Event 'reason' when the
+	// certificate of the sealing key has expired without replacement.
+	ErrKeyExpired = "ErrKeyExpired"
+)
+
+// ErrCertExpired happens when the certificate of the sealing key has expired
+// and there is no replacement to serve.
+var ErrCertExpired = errors.New("the sealing certificate has expired")
+
+// keyExpiryCheckPeriod is how often the keyExpiryMonitor checks the sealing certificate.
+const keyExpiryCheckPeriod = time.Hour
+
+// A keyExpiryMonitor warns, with an Event on the key Secret, when the
+// certificate of the sealing key gets close to expiry without being renewed,
+// and when it has expired.
+type keyExpiryMonitor struct {
+	keyRegistry *KeyRegistry
+	recorder    record.EventRecorder
+	// window is how long before expiry the monitor starts warning.
+	window time.Duration
+	// warned maps the fingerprints of the keys warned about to the reason.
+	warned map[string]string
+}
+
+func newKeyExpiryMonitor(keyRegistry *KeyRegistry, recorder record.EventRecorder, window time.Duration) *keyExpiryMonitor {
+	return &keyExpiryMonitor{
+		keyRegistry: keyRegistry,
+		recorder:    recorder,
+		window:      window,
+		warned:      map[string]string{},
+	}
+}
+
+// Run checks the sealing certificate every keyExpiryCheckPeriod until ctx is done.
+func (m *keyExpiryMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(keyExpiryCheckPeriod)
+	defer ticker.Stop()
+	for {
+		m.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *keyExpiryMonitor) check(now time.Time) {
+	keys := m.keyRegistry.snapshot()
+	k := keys.sealingKey
+	if k == nil {
+		sealingCertExpiryTimestamp.WithLabelValues(m.keyRegistry.keySetName).Set(0)
+		return
+	}
+	notAfter := k.cert.NotAfter
+	sealingCertExpiryTimestamp.WithLabelValues(m.keyRegistry.keySetName).Set(float64(notAfter.Unix()))
+	if u := keys.upcomingKey; u != nil && u.activation.Before(notAfter) {
+		// The key is replaced in time.
+		return
+	}
+
+	var reason, message string
+	switch {
+	case !now.Before(notAfter):
+		reason = ErrKeyExpired
+		message = fmt.Sprintf("The certificate of sealing key %s expired at %s, secrets cannot be sealed until a new key is generated", k.name, notAfter.Format(time.RFC3339))
+	case now.Add(m.window).After(notAfter):
+		reason = ErrKeyExpiring
+		message = fmt.Sprintf("The certificate of sealing key %s expires at %s and hasn't been renewed", k.name, notAfter.Format(time.RFC3339))
+	default:
+		return
+	}
+	if m.warned[k.fingerprint] == reason {
+		return
+	}
+	m.warned[k.fingerprint] = reason
+
+	slog.Warn(message)
+	key := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: k.name, Namespace: m.keyRegistry.namespace}}
+	m.recorder.Event(key, corev1.EventTypeWarning, reason, message)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func registerTestKey(t *testing.T, kr *KeyRegistry, name string, validFor time.Duration, activation time.Time) *x509.Certificate {
+	t.Helper()
+	key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, 1024, validFor, "my-cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kr.Lock()
+	defer kr.Unlock()
+	if err := kr.registerNewKey(name, key, []*x509.Certificate{cert}, time.Now(), KeyStateActive, activation); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestKeyExpiryMonitor(t *testing.T) {
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 1024)
+	cert := registerTestKey(t, kr, "k1", time.Hour, time.Time{})
+	recorder := record.NewFakeRecorder(10)
+	m := newKeyExpiryMonitor(kr, recorder, 30*time.Minute)
+
+	expectEvent := func(reason string) {
+		t.Helper()
+		select {
+		case event := <-recorder.Events:
+			if !strings.HasPrefix(event, "Warning "+reason+" ") {
+				t.Errorf("got event %q, want a %s warning", event, reason)
+			}
+		default:
+			if reason != "" {
+				t.Errorf("got no event, want a %s warning", reason)
+			}
+			return
+		}
+		if reason == "" {
+			t.Errorf("got an event, want none")
+		}
+	}
+
+	m.check(time.Now())
+	expectEvent("")
+	m.check(cert.NotAfter.Add(-10 * time.Minute))
+	expectEvent(ErrKeyExpiring)
+	m.check(cert.NotAfter.Add(-5 * time.Minute))
+	expectEvent("")
+	m.check(cert.NotAfter)
+	expectEvent(ErrKeyExpired)
+
+	// No warning when a staged key replaces the sealing key in time.
+	registerTestKey(t, kr, "k2", 2*time.Hour, time.Now().Add(time.Minute))
+	m = newKeyExpiryMonitor(kr, recorder, 30*time.Minute)
+	m.check(cert.NotAfter.Add(-10 * time.Minute))
+	expectEvent("")
+}
+
+func TestServeExpiredCert(t *testing.T) {
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 1024)
+	registerTestKey(t, kr, "k1", -time.Minute, time.Time{})
+
+	if _, err := kr.getCertChain(); !errors.Is(err, ErrCertExpired) {
+		t.Errorf("got error %v, want %v", err, ErrCertExpired)
+	}
+
+	// The replacement is served ahead of its activation.
+	cert := registerTestKey(t, kr, "k2", time.Hour, time.Now().Add(time.Hour))
+	certs, err := kr.getCertChain()
+	if err != nil {
+		t.Fatalf("getCertChain() returned error: %v", err)
+	}
+	if !certs[0].Equal(cert) {
+		t.Errorf("the upcoming certificate isn't served in place of the expired one")
+	}
+}
+
+func TestKeyRenewalBeforeExpiry(t *testing.T) {
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 1024)
+	kr.renewBeforeExpiry = 30 * time.Minute
+	cert := registerTestKey(t, kr, "k1", time.Hour, time.Time{})
+	schedule := periodSchedule(24 * time.Hour)
+
+	if got, want := nextKeyRenewal(kr, schedule, time.Time{}), cert.NotAfter.Add(-30*time.Minute); !got.Equal(want) {
+		t.Errorf("got next renewal at %s, want %s", got, want)
+	}
+
+	// A failed renewal of an expiring key is retried shortly.
+	lastRun := cert.NotAfter.Add(-20 * time.Minute)
+	if got, want := nextKeyRenewal(kr, schedule, lastRun), lastRun.Add(keyRenewalRetryPeriod); !got.Equal(want) {
+		t.Errorf("got next renewal at %s, want %s", got, want)
+	}
+}
+
+func TestKeyRenewalBeforeExpiryWithoutSchedule(t *testing.T) {
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 1024)
+	cert := registerTestKey(t, kr, "k1", time.Hour, time.Time{})
+
+	if got := nextKeyRenewal(kr, nil, time.Time{}); !got.IsZero() {
+		t.Errorf("got next renewal at %s without a schedule or an expiry window, want none", got)
+	}
+
+	kr.renewBeforeExpiry = 30 * time.Minute
+	if got, want := nextKeyRenewal(kr, nil, time.Time{}), cert.NotAfter.Add(-30*time.Minute); !got.Equal(want) {
+		t.Errorf("got next renewal at %s, want %s", got, want)
+	}
+}
@@ -2,102 +2,410 @@ package controller
 
 import (
 	"context"
-	"crypto/rsa"
+	gocrypto "crypto"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	"github.com/bitnami-labs/sealed-secrets/pkg/kms"
 	"k8s.io/client-go/kubernetes"
 	certUtil "k8s.io/client-go/util/cert"
 )
 
 // A Key holds the cryptographic key pair and some metadata about it.
 type Key struct {
-	private      *rsa.PrivateKey
+	name         string
+	private      gocrypto.PrivateKey
 	cert         *x509.Certificate
+	chain        []*x509.Certificate
 	fingerprint  string
 	orderingTime time.Time
+	state        KeyState
+	// activation is when the key starts being used for sealing, if it's
+	// staged. The key decrypts right away.
+	activation time.Time
+}
+
+// activeAt reports whether the key is no longer staged at t.
+func (k *Key) activeAt(t time.Time) bool {
+	return !k.activation.After(t)
+}
+
+// expiredAt reports whether the certificate of the key has expired at t.
+func (k *Key) expiredAt(t time.Time) bool {
+	return !t.Before(k.cert.NotAfter)
+}
+
+// A keySet is an immutable snapshot of the keys of a KeyRegistry.
+type keySet struct {
+	// keys maps fingerprints to keys.
+	keys          map[string]*Key
+	mostRecentKey *Key
+	// sealingKey is the most recent pinned key, or else the mostRecentKey.
+	sealingKey *Key
+	// upcomingKey is the staged key which is activated next, if any.
+	upcomingKey *Key
 }
 
 // A KeyRegistry manages the key pairs used to (un)seal secrets.
+//
+// Readers get the keys from a snapshot, which is swapped atomically by the
+// writers, so that unsealing and serving the certificate never wait for, nor
+// race with, key generation. The mutex serializes the writers and guards
+// decryptedBy.
 type KeyRegistry struct {
 	sync.Mutex
-	client        kubernetes.Interface
-	namespace     string
-	keyPrefix     string
-	keyLabel      string
-	keysize       int
-	keys          map[string]*Key
-	mostRecentKey *Key
+	client    kubernetes.Interface
+	namespace string
+	keyPrefix string
+	keyLabel  string
+	keyType   string
+	keysize   int
+	current   atomic.Pointer[keySet]
+
+	// keySetName is the name of the key set of the registry, if it isn't the
+	// default one. Its keys are labelled with it.
+	keySetName string
+
+	// csrSignerName, when set, has new certificates issued through the
+	// Kubernetes certificates API by that signer instead of self-signed.
+	csrSignerName string
+	csrTimeout    time.Duration
+
+	// escrow, when set, escrows new keys to offline public keys.
+	escrow *keyEscrow
+
+	// sealingKeyChanged, when set, is called whenever another key becomes the sealingKey.
+	sealingKeyChanged func()
+
+	// activationGracePeriod, when set, stages new keys: they are published
+	// as upcoming, and only used for sealing once it has elapsed.
+	activationGracePeriod time.Duration
+	activationTimer       *time.Timer
+
+	// renewBeforeExpiry, when set, has keys renewed that long before their
+	// certificate expires, if it's earlier than scheduled.
+	renewBeforeExpiry time.Duration
+
+	// decryptedBy maps the SealedSecrets unsealed by the controllers to the
+	// fingerprints of the keys which decrypted them last.
+	decryptedBy map[string][]string
 }
 
 // NewKeyRegistry creates a new KeyRegistry.
-func NewKeyRegistry(client kubernetes.Interface, namespace, keyPrefix, keyLabel string, keysize int) *KeyRegistry {
-	return &KeyRegistry{
+func NewKeyRegistry(client kubernetes.Interface, namespace, keyPrefix, keyLabel, keyType string, keysize int) *KeyRegistry {
+	kr := &KeyRegistry{
 		client:    client,
 		namespace: namespace,
 		keyPrefix: keyPrefix,
+		keyType:   keyType,
 		keysize:   keysize,
 		keyLabel:  keyLabel,
-		keys:      map[string]*Key{},
+
+		decryptedBy: map[string][]string{},
 	}
+	kr.current.Store(newKeySet(map[string]*Key{}, time.Now()))
+	return kr
+}
+
+// snapshot returns the current keys. The snapshot never changes, so it can be
+// used without locking. This method can be called by another goroutine.
+func (kr *KeyRegistry) snapshot() *keySet {
+	return kr.current.Load()
 }
 
 func (kr *KeyRegistry) generateKey(ctx context.Context, validFor time.Duration, cn string, privateKeyAnnotations string, privateKeyLabels string) (string, error) {
-	key, cert, err := generatePrivateKeyAndCert(kr.keysize, validFor, cn)
+	key, cert, err := generatePrivateKeyAndCert(kr.keyType, kr.keysize, validFor, cn)
 	if err != nil {
 		return "", err
 	}
 	certs := []*x509.Certificate{cert}
-	generatedName, err := writeKey(ctx, kr.client, key, certs, kr.namespace, kr.keyLabel, kr.keyPrefix, privateKeyAnnotations, privateKeyLabels)
+	if kr.csrSignerName != "" {
+		certs, err = requestCertificate(ctx, kr.client, key, kr.csrSignerName, validFor, kr.csrTimeout, cn)
+		if err != nil {
+			return "", err
+		}
+	}
+	// Keys are only staged when there is another one to seal with meanwhile.
+	var activation time.Time
+	var opts []writeKeyOpt
+	if kr.activationGracePeriod > 0 && kr.snapshot().sealingKey != nil {
+		activation = time.Now().Add(kr.activationGracePeriod).Truncate(time.Second)
+		opts = append(opts, writeKeyWithActivationTime(activation))
+	}
+	if kr.keySetName != "" {
+		opts = append(opts, writeKeyWithKeySet(kr.keySetName))
+	}
+	generatedName, err := writeKey(ctx, kr.client, key, certs, kr.namespace, kr.keyLabel, kr.keyPrefix, privateKeyAnnotations, privateKeyLabels, opts...)
 	if err != nil {
 		return "", err
 	}
 	// Only store key to local store if write to k8s worked
-	if err := kr.registerNewKey(generatedName, key, cert, time.Now()); err != nil {
+	kr.Lock()
+	err = kr.registerNewKey(generatedName, key, certs, time.Now(), KeyStateActive, activation)
+	kr.Unlock()
+	if err != nil {
 		return "", err
 	}
-	slog.Info("New key written", "namespace", kr.namespace, "name", generatedName)
-	slog.Info("Certificate generated", "certificate", pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw}))
+	slog.Info("New key written", "namespace", kr.namespace, "name", generatedName, "keyset", kr.keySetName)
+	if !activation.IsZero() {
+		slog.Info("New key staged", "name", generatedName, "activation", activation.Format(time.RFC3339))
+	}
+	if kr.escrow != nil {
+		// The key is safe in its Secret already, escrowing is retried on restart.
+		if err := kr.escrow.escrow(ctx, generatedName, kr.keySetName, key, certs); err != nil {
+			slog.Error("Failed to escrow key", "error", err)
+		}
+	}
+	slog.Info("Certificate generated", "certificate", pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: certs[0].Raw}))
 	return generatedName, nil
 }
 
-func (kr *KeyRegistry) registerNewKey(keyName string, privKey *rsa.PrivateKey, cert *x509.Certificate, orderingTime time.Time) error {
-	fingerprint, err := crypto.PublicKeyFingerprint(&privKey.PublicKey)
+// registerNewKey registers a key pair along with its certificate chain, leaf
+// first. Registering a known key again updates its state, and a key replaces
+// any other key with the same name. A key with a future activation time is
+// staged until then. kr must be locked unless it isn't shared yet.
+func (kr *KeyRegistry) registerNewKey(keyName string, privKey gocrypto.PrivateKey, certs []*x509.Certificate, orderingTime time.Time, state KeyState, activation time.Time) error {
+	pubKey, err := crypto.PublicKey(privKey)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := crypto.PublicKeyFingerprint(pubKey)
 	if err != nil {
 		return err
 	}
 
 	k := &Key{
+		name:         keyName,
 		private:      privKey,
-		cert:         cert,
+		cert:         certs[0],
+		chain:        certs,
 		fingerprint:  fingerprint,
 		orderingTime: orderingTime,
+		state:        state,
+		activation:   activation,
+	}
+	kr.modify(func(keys map[string]*Key) {
+		for fp, other := range keys {
+			if other.name == keyName && fp != fingerprint {
+				delete(keys, fp)
+			}
+		}
+		keys[k.fingerprint] = k
+	})
+	return nil
+}
+
+// unregisterKey forgets the key with the given fingerprint. kr must be locked.
+func (kr *KeyRegistry) unregisterKey(fingerprint string) {
+	kr.modify(func(keys map[string]*Key) {
+		delete(keys, fingerprint)
+	})
+	if kr.snapshot().sealingKey == nil {
+		slog.Warn("No key left to seal with, secrets cannot be sealed until a new key is generated")
+	}
+}
+
+// modify publishes a new snapshot with the keys changed by fn, and calls
+// sealingKeyChanged if another key became the sealingKey. kr must be locked.
+func (kr *KeyRegistry) modify(fn func(keys map[string]*Key)) {
+	previous := kr.snapshot()
+	keys := make(map[string]*Key, len(previous.keys)+1)
+	for fp, k := range previous.keys {
+		keys[fp] = k
 	}
-	kr.keys[k.fingerprint] = k
+	fn(keys)
+	next := newKeySet(keys, time.Now())
+	kr.current.Store(next)
 
-	if kr.mostRecentKey == nil || kr.mostRecentKey.orderingTime.Before(orderingTime) {
-		kr.mostRecentKey = k
+	if kr.activationTimer != nil {
+		kr.activationTimer.Stop()
+		kr.activationTimer = nil
+	}
+	if next.upcomingKey != nil {
+		kr.activationTimer = time.AfterFunc(time.Until(next.upcomingKey.activation), kr.activateStagedKeys)
 	}
 
+	changed := next.sealingKey != nil && (previous.sealingKey == nil || next.sealingKey.fingerprint != previous.sealingKey.fingerprint)
+	if changed && kr.sealingKeyChanged != nil {
+		kr.sealingKeyChanged()
+	}
+}
+
+// keysNamed returns the state of the keys called keyName, by fingerprint.
+func (kr *KeyRegistry) keysNamed(keyName string) map[string]KeyState {
+	states := map[string]KeyState{}
+	for fp, k := range kr.snapshot().keys {
+		if k.name == keyName {
+			states[fp] = k.state
+		}
+	}
+	return states
+}
+
+// activateStagedKeys publishes a new snapshot once a staged key is due.
+func (kr *KeyRegistry) activateStagedKeys() {
+	kr.Lock()
+	defer kr.Unlock()
+	kr.modify(func(map[string]*Key) {})
+}
+
+// newKeySet picks the mostRecentKey among the keys which can seal and aren't
+// staged at now, the sealingKey, and the upcomingKey.
+func newKeySet(keys map[string]*Key, now time.Time) *keySet {
+	var mostRecent, pinned, upcoming *Key
+	for _, k := range keys {
+		if !k.state.sealing() {
+			continue
+		}
+		if !k.activeAt(now) {
+			if upcoming == nil || k.activation.Before(upcoming.activation) {
+				upcoming = k
+			}
+			continue
+		}
+		if mostRecent == nil || mostRecent.orderingTime.Before(k.orderingTime) {
+			mostRecent = k
+		}
+		if k.state == KeyStatePinned && (pinned == nil || pinned.orderingTime.Before(k.orderingTime)) {
+			pinned = k
+		}
+	}
+	ks := &keySet{keys: keys, mostRecentKey: mostRecent, sealingKey: mostRecent, upcomingKey: upcoming}
+	if pinned != nil {
+		ks.sealingKey = pinned
+	}
+	return ks
+}
+
+// registerKMSKeys registers the keys held by a key management plugin which
+// aren't known yet. Their ordering time is the NotBefore of their certificate.
+// It fails if there is still no key to seal with afterwards.
+func (kr *KeyRegistry) registerKMSKeys(ctx context.Context, client *kms.Client) error {
+	keys, err := client.Keys(ctx)
+	if err != nil {
+		return err
+	}
+
+	kr.Lock()
+	defer kr.Unlock()
+
+	for _, k := range keys {
+		fingerprint, err := crypto.PublicKeyFingerprint(k.Public())
+		if err != nil {
+			return err
+		}
+		if _, ok := kr.snapshot().keys[fingerprint]; !ok {
+			if err := kr.registerNewKey(k.ID, k, []*x509.Certificate{k.Certificate}, k.Certificate.NotBefore, KeyStateActive, time.Time{}); err != nil {
+				return err
+			}
+			slog.Info("registered KMS key", "keyid", k.ID, "fingerprint", fingerprint)
+		}
+		if sealingKey := kr.snapshot().sealingKey; k.Current && sealingKey != nil && sealingKey.fingerprint != fingerprint {
+			slog.Warn("The current KMS key is not the sealing key, new secrets keep being sealed for the latter", "keyid", k.ID, "sealing", sealingKey.fingerprint)
+		}
+	}
+	if kr.snapshot().sealingKey == nil {
+		return fmt.Errorf("the KMS plugin has no key to seal with")
+	}
 	return nil
 }
 
-func (kr *KeyRegistry) latestPrivateKey() *rsa.PrivateKey {
-	return kr.mostRecentKey.private
+// dependsOn reports whether the SealedSecret with the given cache key was last
+// decrypted by one of the keys with the given fingerprints, or wasn't
+// decrypted at all. This method can be called by another goroutine.
+func (kr *KeyRegistry) dependsOn(ssKey string, fingerprints []string) bool {
+	kr.Lock()
+	defer kr.Unlock()
+
+	decryptedBy, ok := kr.decryptedBy[ssKey]
+	if !ok {
+		return true
+	}
+	for _, fp := range decryptedBy {
+		if slices.Contains(fingerprints, fp) {
+			return true
+		}
+	}
+	return false
 }
 
-// getCert returns the current certificate. This method can be called by another goroutine.
-func (kr *KeyRegistry) getCert() (*x509.Certificate, error) {
+// recordDecryption records the keys which decrypted the SealedSecret with the
+// given cache key, or forgets about the SealedSecret if there are none. This
+// method can be called by another goroutine.
+func (kr *KeyRegistry) recordDecryption(ssKey string, fingerprints []string) {
 	kr.Lock()
 	defer kr.Unlock()
 
-	if kr.mostRecentKey == nil {
+	if len(fingerprints) == 0 {
+		delete(kr.decryptedBy, ssKey)
+		return
+	}
+	kr.decryptedBy[ssKey] = fingerprints
+}
+
+// latestPrivateKey returns the private key of the sealing key, or nil if
+// there is none. This method can be called by another goroutine.
+func (kr *KeyRegistry) latestPrivateKey() gocrypto.PrivateKey {
+	sealingKey := kr.snapshot().sealingKey
+	if sealingKey == nil {
+		return nil
+	}
+	return sealingKey.private
+}
+
+// servedKey returns the sealing key, or the upcoming key if the certificate
+// of the former has expired.
+func (kr *KeyRegistry) servedKey() (*Key, error) {
+	keys := kr.snapshot()
+	if keys.sealingKey == nil {
 		return nil, fmt.Errorf("key registry has no keys")
 	}
-	return kr.mostRecentKey.cert, nil
+	now := time.Now()
+	if !keys.sealingKey.expiredAt(now) {
+		return keys.sealingKey, nil
+	}
+	if keys.upcomingKey != nil && !keys.upcomingKey.expiredAt(now) {
+		return keys.upcomingKey, nil
+	}
+	return nil, fmt.Errorf("%w: key %s expired at %s", ErrCertExpired, keys.sealingKey.name, keys.sealingKey.cert.NotAfter.Format(time.RFC3339))
+}
+
+// getCert returns the certificate of the sealing key, unless it has expired.
+// This method can be called by another goroutine.
+func (kr *KeyRegistry) getCert() (*x509.Certificate, error) {
+	k, err := kr.servedKey()
+	if err != nil {
+		return nil, err
+	}
+	return k.cert, nil
+}
+
+// getUpcomingCertChain returns the certificate chain of the staged key which
+// is activated next, or nil if there is none. This method can be called by
+// another goroutine.
+func (kr *KeyRegistry) getUpcomingCertChain() ([]*x509.Certificate, error) {
+	upcoming := kr.snapshot().upcomingKey
+	if upcoming == nil {
+		return nil, nil
+	}
+	return upcoming.chain, nil
+}
+
+// getCertChain returns the current certificate followed by the certificates of
+// its issuers, if any. An expired certificate is replaced by the upcoming one
+// if there is one, and not returned otherwise. This method can be called by
+// another goroutine.
+func (kr *KeyRegistry) getCertChain() ([]*x509.Certificate, error) {
+	k, err := kr.servedKey()
+	if err != nil {
+		return nil, err
+	}
+	return k.chain, nil
 }
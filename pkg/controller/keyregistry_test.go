@@ -1,44 +1,282 @@
 package controller
 
 import (
+	"context"
+	gocrypto "crypto"
+	"crypto/x509"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
 )
 
 func TestRegisterNewKey(t *testing.T) {
 	const keySize = 2048
 	validFor := time.Hour
 	cn := "my-cn"
-	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", keySize)
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, keySize)
 
-	if kr.mostRecentKey != nil {
+	if kr.snapshot().mostRecentKey != nil {
 		t.Fatal("this test assumes a new key registry has no keys")
 	}
 
-	key1, cert1, err := generatePrivateKeyAndCert(keySize, validFor, cn)
+	key1, cert1, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, validFor, cn)
 	if err != nil {
 		t.Fatal(err)
 	}
 	t1 := time.Now()
 
-	key2, cert2, err := generatePrivateKeyAndCert(keySize, validFor, cn)
+	key2, cert2, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, validFor, cn)
 	if err != nil {
 		t.Fatal(err)
 	}
 	t2 := time.Now()
 
-	if err := kr.registerNewKey("k2", key2, cert2, t2); err != nil {
+	if err := kr.registerNewKey("k2", key2, []*x509.Certificate{cert2}, t2, KeyStateActive, time.Time{}); err != nil {
 		t.Fatal(err)
 	}
-	if got, want := kr.mostRecentKey.private, key2; got != want {
+	if got, want := kr.snapshot().mostRecentKey.private, key2; got != want {
 		t.Errorf("got: %v, want: %v", got, want)
 	}
 
 	// key1 is older, so it shouldn't replace key2 as the mostRecentKey
-	if err := kr.registerNewKey("k1", key1, cert1, t1); err != nil {
+	if err := kr.registerNewKey("k1", key1, []*x509.Certificate{cert1}, t1, KeyStateActive, time.Time{}); err != nil {
 		t.Fatal(err)
 	}
-	if got, want := kr.mostRecentKey.private, key2; got != want {
+	if got, want := kr.snapshot().mostRecentKey.private, key2; got != want {
 		t.Errorf("got: %v, want: %v", got, want)
 	}
 }
+
+func TestKeyStates(t *testing.T) {
+	const keySize = 2048
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, keySize)
+
+	var privKeys []gocrypto.PrivateKey
+	var certs []*x509.Certificate
+	for range 3 {
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		privKeys = append(privKeys, key)
+		certs = append(certs, cert)
+	}
+	register := func(i int, state KeyState) {
+		t.Helper()
+		if err := kr.registerNewKey(fmt.Sprintf("k%d", i), privKeys[i], certs[i:i+1], time.Unix(int64(i), 0), state, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sealsWith := func(i int) {
+		t.Helper()
+		cert, err := kr.getCert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert != certs[i] {
+			t.Errorf("sealing with the wrong key, want k%d", i)
+		}
+	}
+
+	register(0, KeyStateActive)
+	register(1, KeyStateActive)
+	register(2, KeyStateDecryptOnly)
+	// Decrypt-only keys are never served, even if they are the most recent.
+	sealsWith(1)
+
+	register(0, KeyStatePinned)
+	sealsWith(0)
+	if got, want := kr.snapshot().mostRecentKey.name, "k1"; got != want {
+		t.Errorf("got most recent key %s, want %s", got, want)
+	}
+
+	register(0, KeyStateRetired)
+	sealsWith(1)
+
+	register(1, KeyStateRetired)
+	if _, err := kr.getCert(); err == nil {
+		t.Errorf("getCert() succeeded without any key to seal with")
+	}
+	if kr.snapshot().mostRecentKey != nil {
+		t.Errorf("got most recent key %s, want none", kr.snapshot().mostRecentKey.name)
+	}
+}
+
+// TestKeyRegistryConcurrency rotates keys while unsealing and serving the
+// certificate. Run it with -race.
+func TestKeyRegistryConcurrency(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	client.PrependReactor("create", "secrets", generateNameReactor)
+	kr := NewKeyRegistry(client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	kr.sealingKeyChanged = func() {}
+	if _, err := kr.generateKey(ctx, time.Hour, "my-cn", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := kr.getCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("temporal")},
+	}
+	ss, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, cert.PublicKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const rotations = 5
+	done := make(chan struct{})
+	errs := make(chan error, rotations+1)
+	go func() {
+		defer close(done)
+		for range rotations {
+			if _, err := kr.generateKey(ctx, time.Hour, "my-cn", "", ""); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := attemptUnseal(ss, kr); err != nil {
+					errs <- err
+					return
+				}
+				kr.recordDecryption("default/ss", decryptingKeys(ss, kr))
+				if _, err := kr.getCertChain(); err != nil {
+					errs <- err
+					return
+				}
+				_ = kr.latestPrivateKey()
+				_ = kr.sealingKeyStatuses()
+				_ = kr.dependsOn("default/ss", nil)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got, want := len(kr.snapshot().keys), rotations+1; got != want {
+		t.Errorf("got %d keys, want %d", got, want)
+	}
+}
+
+func TestStagedKeyActivation(t *testing.T) {
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 1024)
+	changed := make(chan struct{}, 10)
+	kr.sealingKeyChanged = func() { changed <- struct{}{} }
+
+	var certs []*x509.Certificate
+	for i := range 2 {
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, 1024, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var activation time.Time
+		if i == 1 {
+			activation = time.Now().Add(500 * time.Millisecond)
+		}
+		kr.Lock()
+		err = kr.registerNewKey(fmt.Sprintf("k%d", i), key, []*x509.Certificate{cert}, time.Now(), KeyStateActive, activation)
+		kr.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+	<-changed
+
+	if got, _ := kr.getCert(); !got.Equal(certs[0]) {
+		t.Errorf("the staged key is used for sealing before its activation")
+	}
+	if got, _ := kr.getUpcomingCertChain(); len(got) != 1 || !got[0].Equal(certs[1]) {
+		t.Errorf("the staged key isn't published as upcoming")
+	}
+
+	// SealedSecrets sealed with the upcoming certificate unseal right away.
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("temporal")},
+	}
+	ss, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, certs[1].PublicKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := attemptUnseal(ss, kr); err != nil {
+		t.Errorf("attemptUnseal() returned error: %v", err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the staged key wasn't activated")
+	}
+	if got, _ := kr.getCert(); !got.Equal(certs[1]) {
+		t.Errorf("the activated key isn't used for sealing")
+	}
+	if got, _ := kr.getUpcomingCertChain(); got != nil {
+		t.Errorf("got an upcoming certificate after the activation")
+	}
+}
+
+func TestGenerateStagedKey(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	client.PrependReactor("create", "secrets", generateNameReactor)
+	kr := NewKeyRegistry(client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	kr.activationGracePeriod = time.Hour
+
+	// The first key is used right away, since there is none to seal with meanwhile.
+	first, err := kr.generateKey(ctx, time.Hour, "my-cn", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := kr.generateKey(ctx, time.Hour, "my-cn", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kr.snapshot().sealingKey.name; got != first {
+		t.Errorf("got sealing key %s, want %s", got, first)
+	}
+	upcoming := kr.snapshot().upcomingKey
+	if upcoming == nil || upcoming.name != second {
+		t.Fatalf("the second key isn't staged")
+	}
+
+	for name, want := range map[string]time.Time{first: {}, second: upcoming.activation} {
+		secret, err := client.CoreV1().Secrets("namespace").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := keyActivationOf(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("got activation time %s for key %s, want %s", got, name, want)
+		}
+	}
+}
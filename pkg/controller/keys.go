@@ -2,10 +2,15 @@ package controller
 
 import (
 	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -20,48 +25,183 @@ import (
 // SealedSecretsKeyLabel is that label used to locate active key pairs used to decrypt sealed secrets.
 const SealedSecretsKeyLabel = "sealedsecrets.bitnami.com/sealed-secrets-key"
 
+// SealedSecretsKeyStateAnnotation sets the lifecycle state of a key pair, see KeyState.
+const SealedSecretsKeyStateAnnotation = "sealedsecrets.bitnami.com/key-state"
+
+// SealedSecretsKeyActivationAnnotation holds the time, in RFC 3339 format, at
+// which a staged key starts being used for sealing.
+const SealedSecretsKeyActivationAnnotation = "sealedsecrets.bitnami.com/key-activation-time"
+
+// KeyState is the lifecycle state of a key pair.
+type KeyState string
+
+const (
+	// KeyStateActive keys decrypt, and the most recent one seals. It's the default.
+	KeyStateActive KeyState = "active"
+	// KeyStatePinned keys are used for sealing in preference to more recent active keys.
+	KeyStatePinned KeyState = "pinned"
+	// KeyStateDecryptOnly keys only decrypt, and are never used for sealing.
+	KeyStateDecryptOnly KeyState = "decrypt-only"
+	// KeyStateRetired keys are neither used for sealing nor for decrypting, e.g. because they are compromised.
+	KeyStateRetired KeyState = "retired"
+)
+
+// keyStateOf returns the lifecycle state of a key secret.
+func keyStateOf(secret *v1.Secret) (KeyState, error) {
+	switch state := KeyState(secret.Annotations[SealedSecretsKeyStateAnnotation]); state {
+	case "":
+		return KeyStateActive, nil
+	case KeyStateActive, KeyStatePinned, KeyStateDecryptOnly, KeyStateRetired:
+		return state, nil
+	default:
+		return "", fmt.Errorf("invalid key state %q, must be one of: %s, %s, %s, %s", state, KeyStateActive, KeyStatePinned, KeyStateDecryptOnly, KeyStateRetired)
+	}
+}
+
+// keyActivationOf returns the activation time of a staged key secret, or the
+// zero time if the key isn't staged.
+func keyActivationOf(secret *v1.Secret) (time.Time, error) {
+	value, ok := secret.Annotations[SealedSecretsKeyActivationAnnotation]
+	if !ok {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid key activation time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// sealing reports whether keys in state s can be used for sealing.
+func (s KeyState) sealing() bool {
+	return s == KeyStateActive || s == KeyStatePinned
+}
+
+const (
+	// KeyTypeRSA selects RSA sealing keys of the configured key size.
+	KeyTypeRSA = "rsa"
+	// KeyTypeECP256 selects EC P-256 sealing keys, used with ECIES.
+	KeyTypeECP256 = "ec-p256"
+	// KeyTypeRSAMLKEM768 selects RSA sealing keys paired with an ML-KEM-768 key (post-quantum hybrid).
+	KeyTypeRSAMLKEM768 = KeyTypeRSA + mlkemKeyTypeSuffix
+	// KeyTypeECP256MLKEM768 selects EC P-256 sealing keys paired with an ML-KEM-768 key (post-quantum hybrid).
+	KeyTypeECP256MLKEM768 = KeyTypeECP256 + mlkemKeyTypeSuffix
+
+	mlkemKeyTypeSuffix = "+mlkem768"
+)
+
 var (
+	// ErrUnsupportedPrivateKey is returned when the private key is neither an RSA nor an EC key.
+	ErrUnsupportedPrivateKey = errors.New("private key is neither an RSA nor an EC key")
+
 	// ErrPrivateKeyNotRSA is returned when the private key is not a valid RSA key.
-	ErrPrivateKeyNotRSA = errors.New("private key is not an RSA key")
+	//
+	// Deprecated: EC keys are supported too. Use ErrUnsupportedPrivateKey,
+	// which this is an alias of.
+	ErrPrivateKeyNotRSA = ErrUnsupportedPrivateKey
 )
 
-func generatePrivateKeyAndCert(keySize int, validFor time.Duration, cn string) (*rsa.PrivateKey, *x509.Certificate, error) {
-	return crypto.GeneratePrivateKeyAndCert(keySize, validFor, cn)
+func validateKeyType(keyType string) (string, error) {
+	switch keyType {
+	case KeyTypeRSA, KeyTypeECP256, KeyTypeRSAMLKEM768, KeyTypeECP256MLKEM768:
+		return keyType, nil
+	default:
+		return "", fmt.Errorf("invalid key type %q, must be one of: %s, %s, %s, %s", keyType, KeyTypeRSA, KeyTypeECP256, KeyTypeRSAMLKEM768, KeyTypeECP256MLKEM768)
+	}
+}
+
+func generatePrivateKeyAndCert(keyType string, keySize int, validFor time.Duration, cn string) (gocrypto.PrivateKey, *x509.Certificate, error) {
+	classicalType, pq := strings.CutSuffix(keyType, mlkemKeyTypeSuffix)
+	if !pq {
+		if classicalType == KeyTypeECP256 {
+			return crypto.GenerateECPrivateKeyAndCert(validFor, cn)
+		}
+		return crypto.GeneratePrivateKeyAndCert(keySize, validFor, cn)
+	}
+
+	var classical gocrypto.Signer
+	var err error
+	if classicalType == KeyTypeECP256 {
+		classical, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		classical, err = rsa.GenerateKey(rand.Reader, keySize)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return crypto.GeneratePQPrivateKeyAndCert(classical, validFor, cn)
 }
 
-func readKey(secret *v1.Secret) (*rsa.PrivateKey, []*x509.Certificate, error) {
+func readKey(secret *v1.Secret) (gocrypto.PrivateKey, []*x509.Certificate, error) {
 	key, err := keyutil.ParsePrivateKeyPEM(secret.Data[v1.TLSPrivateKeyKey])
 	if err != nil {
 		return nil, nil, err
 	}
-	switch rsaKey := key.(type) {
-	case *rsa.PrivateKey:
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
 		certs, err := certUtil.ParseCertsPEM(secret.Data[v1.TLSCertKey])
 		if err != nil {
 			return nil, nil, err
 		}
-		return rsaKey, certs, nil
+		pqKey, err := crypto.WithMLKEMPrivateKeyPEM(key, secret.Data[crypto.MLKEMSecretKey])
+		if err != nil {
+			return nil, nil, err
+		}
+		return pqKey, certs, nil
 	default:
-		return nil, nil, ErrPrivateKeyNotRSA
+		return nil, nil, ErrUnsupportedPrivateKey
+	}
+}
+
+// keySecretData returns the data of the Secret storing key and its certificate chain.
+func keySecretData(key gocrypto.PrivateKey, certs []*x509.Certificate) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	if pq, ok := key.(*crypto.PQPrivateKey); ok {
+		key = pq.Classical
+		data[crypto.MLKEMSecretKey] = crypto.MarshalMLKEMPrivateKeyPEM(pq.MLKEM)
+	}
+	keybytes, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	certbytes := []byte{}
+	for _, cert := range certs {
+		certbytes = append(certbytes, pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw})...)
 	}
+	data[v1.TLSPrivateKeyKey] = keybytes
+	data[v1.TLSCertKey] = certbytes
+	return data, nil
 }
 
 type writeKeyOpt func(*writeKeyOpts)
-type writeKeyOpts struct{ creationTime metav1.Time }
+type writeKeyOpts struct {
+	creationTime metav1.Time
+	activation   time.Time
+	keySet       string
+}
 
 func writeKeyWithCreationTime(t metav1.Time) writeKeyOpt {
 	return func(opts *writeKeyOpts) { opts.creationTime = t }
 }
 
-func writeKey(ctx context.Context, client kubernetes.Interface, key *rsa.PrivateKey, certs []*x509.Certificate, namespace, krLabel, prefix string, additionalAnnotations string, additionalLabels string, optSetters ...writeKeyOpt) (string, error) {
+func writeKeyWithActivationTime(t time.Time) writeKeyOpt {
+	return func(opts *writeKeyOpts) { opts.activation = t }
+}
+
+func writeKeyWithKeySet(name string) writeKeyOpt {
+	return func(opts *writeKeyOpts) { opts.keySet = name }
+}
+
+func writeKey(ctx context.Context, client kubernetes.Interface, key gocrypto.PrivateKey, certs []*x509.Certificate, namespace, krLabel, prefix string, additionalAnnotations string, additionalLabels string, optSetters ...writeKeyOpt) (string, error) {
 	var opts writeKeyOpts
 	for _, o := range optSetters {
 		o(&opts)
 	}
 
-	certbytes := []byte{}
-	for _, cert := range certs {
-		certbytes = append(certbytes, pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw})...)
+	data, err := keySecretData(key, certs)
+	if err != nil {
+		return "", err
 	}
 
 	labels := map[string]string{
@@ -79,6 +219,9 @@ func writeKey(ctx context.Context, client kubernetes.Interface, key *rsa.Private
 			}
 		}
 	}
+	if opts.keySet != "" {
+		labels[SealedSecretsKeySetLabel] = opts.keySet
+	}
 
 	if additionalAnnotations != "" {
 		for _, label := range removeDuplicates(strings.Split(additionalAnnotations, ",")) {
@@ -87,6 +230,9 @@ func writeKey(ctx context.Context, client kubernetes.Interface, key *rsa.Private
 			annotations[key] = value
 		}
 	}
+	if !opts.activation.IsZero() {
+		annotations[SealedSecretsKeyActivationAnnotation] = opts.activation.UTC().Format(time.RFC3339)
+	}
 
 	secret := v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -96,10 +242,7 @@ func writeKey(ctx context.Context, client kubernetes.Interface, key *rsa.Private
 			Annotations:       annotations,
 			CreationTimestamp: opts.creationTime,
 		},
-		Data: map[string][]byte{
-			v1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key)}),
-			v1.TLSCertKey:       certbytes,
-		},
+		Data: data,
 		Type: v1.SecretTypeTLS,
 	}
 
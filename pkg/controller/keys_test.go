@@ -2,9 +2,11 @@ package controller
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"io"
 	mathrand "math/rand"
 	"reflect"
@@ -137,3 +139,98 @@ func TestWriteKey(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteReadECKey(t *testing.T) {
+	ctx := context.Background()
+	key, cert, err := generatePrivateKeyAndCert(KeyTypeECP256, 0, time.Hour, "testcn")
+	if err != nil {
+		t.Fatalf("generatePrivateKeyAndCert() failed: %v", err)
+	}
+
+	client := fake.NewClientset()
+	if _, err := writeKey(ctx, client, key, []*x509.Certificate{cert}, "myns", "label", "mykey", "", ""); err != nil {
+		t.Fatalf("writeKey() failed with: %v", err)
+	}
+
+	secret := findAction(client, "create", "secrets").(ktesting.CreateActionImpl).Object.(*v1.Secret)
+	key2, cert2, err := readKey(secret)
+	if err != nil {
+		t.Fatalf("readKey() failed with: %v", err)
+	}
+	if !reflect.DeepEqual(key, key2) {
+		t.Errorf("Extracted key != original key")
+	}
+	if !reflect.DeepEqual(cert, cert2[0]) {
+		t.Errorf("Extracted cert != original cert")
+	}
+	if _, ok := cert2[0].PublicKey.(*ecdsa.PublicKey); !ok {
+		t.Errorf("got %T public key, want an EC key", cert2[0].PublicKey)
+	}
+}
+
+func TestWriteReadPQKey(t *testing.T) {
+	ctx := context.Background()
+	key, cert, err := generatePrivateKeyAndCert(KeyTypeECP256MLKEM768, 0, time.Hour, "testcn")
+	if err != nil {
+		t.Fatalf("generatePrivateKeyAndCert() failed: %v", err)
+	}
+
+	client := fake.NewClientset()
+	if _, err := writeKey(ctx, client, key, []*x509.Certificate{cert}, "myns", "label", "mykey", "", ""); err != nil {
+		t.Fatalf("writeKey() failed with: %v", err)
+	}
+
+	secret := findAction(client, "create", "secrets").(ktesting.CreateActionImpl).Object.(*v1.Secret)
+	if _, ok := secret.Data[crypto.MLKEMSecretKey]; !ok {
+		t.Errorf("writeKey() didn't persist the ML-KEM key")
+	}
+	if _, err := keyutil.ParsePrivateKeyPEM(secret.Data[v1.TLSPrivateKeyKey]); err != nil {
+		t.Errorf("tls.key is no longer a plain private key: %v", err)
+	}
+
+	key2, _, err := readKey(secret)
+	if err != nil {
+		t.Fatalf("readKey() failed with: %v", err)
+	}
+	pq, ok := key2.(*crypto.PQPrivateKey)
+	if !ok {
+		t.Fatalf("got %T, want *crypto.PQPrivateKey", key2)
+	}
+	if got, want := pq.MLKEM.Bytes(), key.(*crypto.PQPrivateKey).MLKEM.Bytes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extracted ML-KEM key != original key")
+	}
+}
+
+func TestKeyStateOf(t *testing.T) {
+	for anno, want := range map[string]KeyState{
+		"":             KeyStateActive,
+		"pinned":       KeyStatePinned,
+		"decrypt-only": KeyStateDecryptOnly,
+		"retired":      KeyStateRetired,
+	} {
+		secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{SealedSecretsKeyStateAnnotation: anno},
+		}}
+		got, err := keyStateOf(secret)
+		if err != nil {
+			t.Errorf("keyStateOf(%q) returned error: %v", anno, err)
+		}
+		if got != want {
+			t.Errorf("keyStateOf(%q) = %q, want %q", anno, got, want)
+		}
+	}
+
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{SealedSecretsKeyStateAnnotation: "revoked"},
+	}}
+	if _, err := keyStateOf(secret); err == nil {
+		t.Errorf("keyStateOf() accepted an invalid state")
+	}
+}
+
+func TestErrPrivateKeyNotRSA(t *testing.T) {
+	_, err := classicalPublicKey("not a key")
+	if !errors.Is(err, ErrUnsupportedPrivateKey) || !errors.Is(err, ErrPrivateKeyNotRSA) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedPrivateKey)
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+)
+
+// SealedSecretsKeySetLabel assigns a namespace to a named key set, whose keys
+// (un)seal its SealedSecrets. Key Secrets carry it too, to tell which key set
+// they belong to. Namespaces and keys without it belong to the default key set.
+const SealedSecretsKeySetLabel = "sealedsecrets.bitnami.com/key-set"
+
+// ErrUnknownKeySet happens when a namespace is assigned to a key set which
+// isn't configured.
+var ErrUnknownKeySet = errors.New("unknown key set")
+
+// keySetRegistries holds the KeyRegistry of the default key set and of each
+// named key set, so that the secrets of a tenant are only unsealed with the
+// keys of its own key set.
+type keySetRegistries struct {
+	defaultSet *KeyRegistry
+	named      map[string]*KeyRegistry
+	// namespaces is used to look up the key set label of namespaces. It's
+	// only needed when there are named key sets.
+	namespaces corev1listers.NamespaceLister
+}
+
+// newKeySetRegistries returns the registries of the default key set only.
+func newKeySetRegistries(defaultSet *KeyRegistry) *keySetRegistries {
+	return &keySetRegistries{defaultSet: defaultSet, named: map[string]*KeyRegistry{}}
+}
+
+// parseKeySets parses a comma separated list of key set names.
+func parseKeySets(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	names := removeDuplicates(strings.Split(list, ","))
+	for _, name := range names {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid key set name %q: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	return names, nil
+}
+
+// get returns the KeyRegistry of the key set called name, the empty name
+// standing for the default key set.
+func (s *keySetRegistries) get(name string) (*KeyRegistry, error) {
+	if name == "" {
+		return s.defaultSet, nil
+	}
+	kr, ok := s.named[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKeySet, name)
+	}
+	return kr, nil
+}
+
+// forNamespace returns the KeyRegistry of the key set a namespace is assigned
+// to. Without named key sets, and for cluster-wide requests, i.e. an empty
+// namespace, it's the default key set. This method can be called by another
+// goroutine.
+func (s *keySetRegistries) forNamespace(namespace string) (*KeyRegistry, error) {
+	if len(s.named) == 0 || namespace == "" {
+		return s.defaultSet, nil
+	}
+	ns, err := s.namespaces.Get(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("looking up the key set of namespace %s: %w", namespace, err)
+	}
+	kr, err := s.get(ns.Labels[SealedSecretsKeySetLabel])
+	if err != nil {
+		return nil, fmt.Errorf("namespace %s: %w", namespace, err)
+	}
+	return kr, nil
+}
+
+// forKeySecret returns the KeyRegistry that the key held by a key Secret
+// belongs to, or nil if its key set isn't configured.
+func (s *keySetRegistries) forKeySecret(secret *corev1.Secret) *KeyRegistry {
+	kr, err := s.get(secret.Labels[SealedSecretsKeySetLabel])
+	if err != nil {
+		return nil
+	}
+	return kr
+}
+
+// all returns the KeyRegistry of every key set, the default one first.
+func (s *keySetRegistries) all() []*KeyRegistry {
+	names := make([]string, 0, len(s.named))
+	for name := range s.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registries := []*KeyRegistry{s.defaultSet}
+	for _, name := range names {
+		registries = append(registries, s.named[name])
+	}
+	return registries
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func testKeySets(t *testing.T, defaultSet *KeyRegistry, named map[string]*KeyRegistry, namespaces ...*v1.Namespace) *keySetRegistries {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		if err := indexer.Add(ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sets := newKeySetRegistries(defaultSet)
+	sets.named = named
+	sets.namespaces = corev1listers.NewNamespaceLister(indexer)
+	return sets
+}
+
+func testNamespace(name, keySet string) *v1.Namespace {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if keySet != "" {
+		ns.Labels = map[string]string{SealedSecretsKeySetLabel: keySet}
+	}
+	return ns
+}
+
+func TestInitKeySetRegistry(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	client.PrependReactor("create", "secrets", generateNameReactor)
+
+	defaultSet, err := initKeyRegistry(ctx, client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024, "CertNotBefore")
+	if err != nil {
+		t.Fatalf("initKeyRegistry() returned err: %v", err)
+	}
+	teamA, err := initKeySetRegistry(ctx, client, "namespace", "prefix-team-a-", SealedSecretsKeyLabel, "team-a", KeyTypeRSA, 1024, "CertNotBefore")
+	if err != nil {
+		t.Fatalf("initKeySetRegistry() returned err: %v", err)
+	}
+	for _, kr := range []*KeyRegistry{defaultSet, teamA} {
+		if _, err := kr.generateKey(ctx, time.Hour, "my-cn", "", ""); err != nil {
+			t.Fatalf("generateKey() returned err: %v", err)
+		}
+	}
+
+	secret, err := client.CoreV1().Secrets("namespace").Get(ctx, teamA.snapshot().sealingKey.name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := secret.Labels[SealedSecretsKeySetLabel]; got != "team-a" {
+		t.Errorf("got key set label %q, want %q", got, "team-a")
+	}
+
+	// Each key set only reads its own keys back.
+	for keySet, want := range map[string]*KeyRegistry{"": defaultSet, "team-a": teamA} {
+		kr, err := initKeySetRegistry(ctx, client, "namespace", "prefix", SealedSecretsKeyLabel, keySet, KeyTypeRSA, 1024, "CertNotBefore")
+		if err != nil {
+			t.Fatalf("initKeySetRegistry() returned err: %v", err)
+		}
+		keys := kr.snapshot()
+		if len(keys.keys) != 1 || keys.sealingKey.fingerprint != want.snapshot().sealingKey.fingerprint {
+			t.Errorf("key set %q: got %d keys, want the key of the set only", keySet, len(keys.keys))
+		}
+	}
+}
+
+func TestKeySetRegistriesForNamespace(t *testing.T) {
+	defaultSet := NewKeyRegistry(nil, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	teamA := NewKeyRegistry(nil, "namespace", "prefix-team-a-", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	sets := testKeySets(t, defaultSet, map[string]*KeyRegistry{"team-a": teamA},
+		testNamespace("plain", ""),
+		testNamespace("tenant", "team-a"),
+		testNamespace("stray", "team-x"),
+	)
+
+	for namespace, want := range map[string]*KeyRegistry{"": defaultSet, "plain": defaultSet, "tenant": teamA} {
+		got, err := sets.forNamespace(namespace)
+		if err != nil {
+			t.Fatalf("forNamespace(%q) returned error: %v", namespace, err)
+		}
+		if got != want {
+			t.Errorf("forNamespace(%q) returned the registry of another key set", namespace)
+		}
+	}
+	if _, err := sets.forNamespace("stray"); !errors.Is(err, ErrUnknownKeySet) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownKeySet)
+	}
+	if _, err := sets.forNamespace("missing"); err == nil {
+		t.Errorf("forNamespace() succeeded for a missing namespace")
+	}
+
+	keySecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{SealedSecretsKeySetLabel: "team-x"}}}
+	if sets.forKeySecret(keySecret) != nil {
+		t.Errorf("a key of an unknown key set is assigned a registry")
+	}
+}
+
+func TestUnsealWithKeySets(t *testing.T) {
+	defaultSet := NewKeyRegistry(nil, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	teamA := NewKeyRegistry(nil, "namespace", "prefix-team-a-", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+	registerTestKey(t, defaultSet, "default-key", time.Hour, time.Time{})
+	registerTestKey(t, teamA, "team-a-key", time.Hour, time.Time{})
+	c := &Controller{keySets: testKeySets(t, defaultSet, map[string]*KeyRegistry{"team-a": teamA}, testNamespace("tenant", "team-a"))}
+
+	seal := func(kr *KeyRegistry) *ssv1alpha1.SealedSecret {
+		t.Helper()
+		pubKey, err := crypto.PublicKey(kr.latestPrivateKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "tenant"},
+			Data:       map[string][]byte{"foo": []byte("bar")},
+		}
+		ss, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, pubKey, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ss
+	}
+
+	if _, err := c.attemptUnseal(seal(teamA)); err != nil {
+		t.Errorf("a SealedSecret of the key set of its namespace doesn't unseal: %v", err)
+	}
+	if _, err := c.attemptUnseal(seal(defaultSet)); err == nil {
+		t.Errorf("a SealedSecret of another key set unseals")
+	}
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/kms"
+)
+
+// initKMSKeys registers the keys held by a key management plugin, which takes
+// the place of generating keys in-cluster, and polls the plugin for new keys
+// every period. Returns an early trigger function, like initKeyRenewal.
+func initKMSKeys(ctx context.Context, registry *KeyRegistry, client *kms.Client, period time.Duration) (func(), error) {
+	if err := registry.registerKMSKeys(ctx, client); err != nil {
+		return nil, err
+	}
+
+	refresh := func() {
+		if err := registry.registerKMSKeys(ctx, client); err != nil {
+			slog.Error("Failed to refresh KMS keys", "error", err)
+		}
+	}
+	if period == 0 {
+		return refresh, nil
+	}
+	return ScheduleJobWithTrigger(period, period, refresh), nil
+}
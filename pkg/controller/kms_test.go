@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/bitnami-labs/sealed-secrets/pkg/kms"
+	kmsapi "github.com/bitnami-labs/sealed-secrets/pkg/kms/api/v1"
+)
+
+func TestInitKMSKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "kms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	endpoint := "unix://" + filepath.Join(dir, "kms.sock")
+
+	plugin := kms.NewSoftPlugin()
+	first, err := plugin.GenerateKey(2048, time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = kms.Serve(ctx, endpoint, plugin) }()
+
+	client, err := kms.NewClient(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	registry := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 2048)
+	var trigger func()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if trigger, err = initKMSKeys(ctx, registry, client, 0); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("initKMSKeys() returned err: %v", err)
+		}
+	}
+
+	if got, want := registry.snapshot().mostRecentKey.fingerprint, first; got != want {
+		t.Errorf("got most recent key %q, want %q", got, want)
+	}
+	if _, ok := registry.latestPrivateKey().(*kms.Key); !ok {
+		t.Errorf("got %T private key, want a KMS key", registry.latestPrivateKey())
+	}
+
+	time.Sleep(time.Second) // certificate times have a resolution of one second
+	second, err := plugin.GenerateKey(2048, time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger()
+
+	if got, want := len(registry.snapshot().keys), 2; got != want {
+		t.Errorf("got %d keys, want %d", got, want)
+	}
+	if got, want := registry.snapshot().mostRecentKey.fingerprint, second; got != want {
+		t.Errorf("got most recent key %q, want %q", got, want)
+	}
+}
+
+// unavailablePlugin lists the keys of a SoftPlugin, but cannot decrypt.
+type unavailablePlugin struct {
+	*kms.SoftPlugin
+}
+
+func (unavailablePlugin) Decrypt(context.Context, *kmsapi.DecryptRequest) (*kmsapi.DecryptResponse, error) {
+	return nil, status.Error(codes.Unavailable, "plugin is restarting")
+}
+
+func TestUnsealKMSUnavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "kms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	endpoint := "unix://" + filepath.Join(dir, "kms.sock")
+
+	plugin := kms.NewSoftPlugin()
+	if _, err := plugin.GenerateKey(2048, time.Hour, "testcn"); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = kms.Serve(ctx, endpoint, unavailablePlugin{plugin}) }()
+
+	client, err := kms.NewClient(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	registry := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 2048)
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if err = registry.registerKMSKeys(ctx, client); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("registerKMSKeys() returned err: %v", err)
+		}
+	}
+
+	controller, err := prepareController(fake.NewClientset(), []string{"default"}, "namespace", nil, &Flags{MaxRetries: 5}, ssfake.NewSimpleClientset(), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.queue.ShutDown()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("temporal")},
+	}
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, registry.latestPrivateKey().(*kms.Key).Public(), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := controller.namespaces["default"].ssInformer.GetStore().Add(ssecret); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.queue.Add("default/ss")
+	if !controller.processNextItem(ctx) {
+		t.Fatalf("processNextItem() stopped")
+	}
+	if got := controller.queue.NumRequeues("default/ss"); got != 1 {
+		t.Errorf("an unavailable KMS plugin was retried %d times, want 1", got)
+	}
+}
+
+func TestRegisterKMSKeysWithoutSealingKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "kms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	endpoint := "unix://" + filepath.Join(dir, "kms.sock")
+
+	plugin := kms.NewSoftPlugin()
+	if _, err := plugin.GenerateKey(2048, time.Hour, "testcn"); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = kms.Serve(ctx, endpoint, plugin) }()
+
+	client, err := kms.NewClient(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var keys []*kms.Key
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if keys, err = client.Keys(ctx); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Keys() returned err: %v", err)
+		}
+	}
+
+	// The only key, which is the current one of the plugin, cannot seal.
+	registry := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 2048)
+	k := keys[0]
+	if err := registry.registerNewKey(k.ID, k, []*x509.Certificate{k.Certificate}, k.Certificate.NotBefore, KeyStateDecryptOnly, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.registerKMSKeys(ctx, client); err == nil {
+		t.Errorf("registerKMSKeys() succeeded without a key to seal with")
+	}
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// ErrLeadershipLost happens when the leader fails to renew its Lease, e.g.
+// because it lost touch with the API server. The replica has to restart as a
+// follower, since another one may be leading already.
+var ErrLeadershipLost = errors.New("lost the leader election")
+
+// leaderElectionIdentity returns a unique identity of the replica, made of its
+// host name, i.e. the pod name, and a random suffix.
+func leaderElectionIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "sealed-secrets-controller"
+	}
+	return hostname + "_" + string(uuid.NewUUID())
+}
+
+// runLeaderElection campaigns for the Lease called name in namespace until ctx
+// is done, and calls lead once this replica becomes the leader. The context
+// given to lead is cancelled when the leadership is lost. It returns the
+// error of lead, ErrLeadershipLost, or nil once ctx is done, in which case
+// the Lease is released for another replica to take over right away.
+func runLeaderElection(ctx context.Context, client kubernetes.Interface, namespace, name, identity string, leaseDuration, renewDeadline, retryPeriod time.Duration, lead func(ctx context.Context) error) error {
+	electionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	leadErrs := make(chan error, 1)
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				slog.Info("Started leading", "identity", identity)
+				if err := lead(ctx); err != nil {
+					leadErrs <- err
+					cancel()
+				}
+			},
+			OnStoppedLeading: func() {
+				slog.Info("Stopped leading", "identity", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					slog.Info("Following the leader", "leader", leader)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Campaigning for leadership", "lease", name, "identity", identity)
+	le.Run(electionCtx)
+
+	select {
+	case err := <-leadErrs:
+		return err
+	default:
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return ErrLeadershipLost
+}
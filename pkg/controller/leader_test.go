@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunLeaderElection(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	leading := make(chan string, 3)
+	run := func(ctx context.Context, identity string, leadErr error) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- runLeaderElection(ctx, client, "namespace", "lease", identity, 2*time.Second, time.Second, 100*time.Millisecond, func(context.Context) error {
+				leading <- identity
+				return leadErr
+			})
+		}()
+		return done
+	}
+	expectLeader := func(want string) {
+		t.Helper()
+		select {
+		case got := <-leading:
+			if got != want {
+				t.Fatalf("got leader %s, want %s", got, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s didn't become the leader", want)
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	done1 := run(ctx1, "replica-1", nil)
+	expectLeader("replica-1")
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+	done2 := run(ctx2, "replica-2", nil)
+	select {
+	case got := <-leading:
+		t.Fatalf("%s leads along with replica-1", got)
+	case <-time.After(time.Second):
+	}
+
+	// The leader hands over when stopped.
+	cancel1()
+	if err := <-done1; err != nil {
+		t.Errorf("runLeaderElection() returned error: %v", err)
+	}
+	expectLeader("replica-2")
+	cancel2()
+	<-done2
+
+	// A leader which fails to start gives up.
+	errBoom := errors.New("boom")
+	if err := <-run(ctx, "replica-3", errBoom); !errors.Is(err, errBoom) {
+		t.Errorf("got error %v, want %v", err, errBoom)
+	}
+}
@@ -2,9 +2,8 @@ package controller
 
 import (
 	"context"
-	"crypto/rand"
 	"crypto/x509"
-	"io"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -17,8 +16,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/client-go/informers"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned"
 	sealedsecrets "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned"
 	ssinformers "github.com/bitnami-labs/sealed-secrets/pkg/client/informers/externalversions"
+	"github.com/bitnami-labs/sealed-secrets/pkg/kms"
 )
 
 var (
@@ -35,57 +37,91 @@ var (
 
 // Flags to configure the controller.
 type Flags struct {
-	KeyPrefix             string
-	KeySize               int
-	ValidFor              time.Duration
-	MyCN                  string
-	KeyRenewPeriod        time.Duration
-	KeyOrderPriority      string
-	AcceptV1Data          bool
-	KeyCutoffTime         string
-	NamespaceAll          bool
-	AdditionalNamespaces  string
-	LabelSelector         string
-	RateLimitPerSecond    int
-	RateLimitBurst        int
-	OldGCBehavior         bool
-	UpdateStatus          bool
-	SkipRecreate          bool
-	LogInfoToStdout       bool
-	LogLevel              string
-	LogFormat             string
-	PrivateKeyAnnotations string
-	PrivateKeyLabels      string
-	MaxRetries            int
-	WatchForSecrets       bool
-	KubeClientQPS         float32
-	KubeClientBurst       int
+	KeyPrefix                string
+	KeyType                  string
+	KeySize                  int
+	ValidFor                 time.Duration
+	MyCN                     string
+	KeyRenewPeriod           time.Duration
+	KeyRenewSchedule         string
+	KeyActivationGracePeriod time.Duration
+	KeyRenewBeforeExpiry     time.Duration
+	KeySets                  string
+	KeyOrderPriority         string
+	AcceptV1Data             bool
+	KeyCutoffTime            string
+	NamespaceAll             bool
+	AdditionalNamespaces     string
+	NamespaceSelector        string
+	ExcludeNamespaces        string
+	LabelSelector            string
+	RateLimitPerSecond       int
+	RateLimitBurst           int
+	OldGCBehavior            bool
+	UpdateStatus             bool
+	SkipRecreate             bool
+	LogInfoToStdout          bool
+	LogLevel                 string
+	LogFormat                string
+	PrivateKeyAnnotations    string
+	PrivateKeyLabels         string
+	MaxRetries               int
+	Workers                  int
+	RetryBaseDelay           time.Duration
+	RetryMaxDelay            time.Duration
+	WatchForSecrets          bool
+	KubeClientQPS            float32
+	KubeClientBurst          int
+	KMSPluginEndpoint        string
+	KMSPluginTimeout         time.Duration
+	CSRSignerName            string
+	CSRTimeout               time.Duration
+	EscrowCerts              []string
+	EscrowConfigMap          string
+	EscrowPath               string
+	Reencrypt                bool
+	ReencryptRate            float64
+	PublishSealingKeys       bool
+	LeaderElect              bool
+	LeaderElectLeaseName     string
+	LeaderElectLeaseDuration time.Duration
+	LeaderElectRenewDeadline time.Duration
+	LeaderElectRetryPeriod   time.Duration
 }
 
 func initKeyPrefix(keyPrefix string) (string, error) {
 	return validateKeyPrefix(keyPrefix)
 }
 
-func initKeyRegistry(ctx context.Context, client kubernetes.Interface, r io.Reader, namespace, prefix, label string, keysize int, keyOrderPriority string) (*KeyRegistry, error) {
-	slog.Info("Searching for existing private keys")
+func initKeyRegistry(ctx context.Context, client kubernetes.Interface, namespace, prefix, label, keyType string, keysize int, keyOrderPriority string) (*KeyRegistry, error) {
+	return initKeySetRegistry(ctx, client, namespace, prefix, label, "", keyType, keysize, keyOrderPriority)
+}
+
+// initKeySetRegistry creates the KeyRegistry of the key set called keySet, or
+// of the default key set if it's empty, with the existing keys of that set.
+func initKeySetRegistry(ctx context.Context, client kubernetes.Interface, namespace, prefix, label, keySet, keyType string, keysize int, keyOrderPriority string) (*KeyRegistry, error) {
+	slog.Info("Searching for existing private keys", "keyset", keySet)
 	secretList, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: keySelector.String(),
+		LabelSelector: keySetSelector(keySet),
 	})
 	if err != nil {
 		return nil, err
 	}
 	items := secretList.Items
 
-	s, err := client.CoreV1().Secrets(namespace).Get(ctx, prefix, metav1.GetOptions{})
-	if !errors.IsNotFound(err) {
-		if err != nil {
-			return nil, err
+	if keySet == "" {
+		s, err := client.CoreV1().Secrets(namespace).Get(ctx, prefix, metav1.GetOptions{})
+		if !errors.IsNotFound(err) {
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, *s)
+			// TODO(mkm): add the label to the legacy secret to simplify discovery and backups.
 		}
-		items = append(items, *s)
-		// TODO(mkm): add the label to the legacy secret to simplify discovery and backups.
 	}
 
-	keyRegistry := NewKeyRegistry(client, namespace, prefix, label, keysize)
+	keyRegistry := NewKeyRegistry(client, namespace, prefix, label, keyType, keysize)
+	keyRegistry.keySetName = keySet
 	sort.Sort(ssv1alpha1.ByCreationTimestamp(items))
 	for _, secret := range items {
 		err = registryNewKeyWithSecret(&secret, keyRegistry, keyOrderPriority)
@@ -96,22 +132,89 @@ func initKeyRegistry(ctx context.Context, client kubernetes.Interface, r io.Read
 	return keyRegistry, nil
 }
 
+// keySetSelector returns the label selector of the key Secrets of a key set.
+// The ones of the default key set have no key set label.
+func keySetSelector(keySet string) string {
+	if keySet == "" {
+		return keySelector.String() + ",!" + SealedSecretsKeySetLabel
+	}
+	return keySelector.String() + "," + SealedSecretsKeySetLabel + "=" + keySet
+}
+
 func registryNewKeyWithSecret(secret *v1.Secret, keyRegistry *KeyRegistry, keyOrderPriority string) error {
 	key, certs, err := readKey(secret)
 	if err != nil {
-		slog.Error("Error reading key", "secret", secret.Name, "error", err)
+		return fmt.Errorf("error reading key %s: %w", secret.Name, err)
+	}
+
+	state, err := keyStateOf(secret)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", secret.Name, err)
+	}
+	activation, err := keyActivationOf(secret)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", secret.Name, err)
 	}
 
 	// Select ordering time based on the keyOrderPriority flag
 	orderingTime := getKeyOrderPriority(keyOrderPriority, certs[0], secret)
 
-	if err := keyRegistry.registerNewKey(secret.Name, key, certs[0], orderingTime); err != nil {
+	if err := keyRegistry.registerNewKey(secret.Name, key, certs, orderingTime, state, activation); err != nil {
 		return err
 	}
-	slog.Info("registered private key", "secretname", secret.Name)
+	slog.Info("registered private key", "secretname", secret.Name, "state", state)
 	return nil
 }
 
+// registryUpdateKeyWithSecret registers the key of a key Secret which was
+// added or updated, in place of the key it held before if any. If the Secret
+// doesn't hold a valid key any more, its former key is unregistered. It
+// returns the fingerprints of the keys which were added, removed or changed
+// state.
+func registryUpdateKeyWithSecret(secret *v1.Secret, keyRegistry *KeyRegistry, keyOrderPriority string) ([]string, error) {
+	keyRegistry.Lock()
+	defer keyRegistry.Unlock()
+
+	before := keyRegistry.keysNamed(secret.Name)
+	err := registryNewKeyWithSecret(secret, keyRegistry, keyOrderPriority)
+	if err != nil {
+		for fp := range before {
+			keyRegistry.unregisterKey(fp)
+		}
+	}
+	after := keyRegistry.keysNamed(secret.Name)
+
+	var changed []string
+	for fp, state := range before {
+		if s, ok := after[fp]; !ok || s != state {
+			changed = append(changed, fp)
+		}
+	}
+	for fp := range after {
+		if _, ok := before[fp]; !ok {
+			changed = append(changed, fp)
+		}
+	}
+	return changed, err
+}
+
+// registryDeleteKeyWithSecret unregisters the key of a deleted key Secret, and
+// returns its fingerprint.
+func registryDeleteKeyWithSecret(secret *v1.Secret, keyRegistry *KeyRegistry) []string {
+	keyRegistry.Lock()
+	defer keyRegistry.Unlock()
+
+	var removed []string
+	for fp := range keyRegistry.keysNamed(secret.Name) {
+		keyRegistry.unregisterKey(fp)
+		removed = append(removed, fp)
+	}
+	if len(removed) > 0 {
+		slog.Info("unregistered private key", "secretname", secret.Name)
+	}
+	return removed
+}
+
 func getKeyOrderPriority(keyOrderPriority string, cert *x509.Certificate, secret *v1.Secret) time.Time {
 	switch keyOrderPriority {
 	case "CertNotBefore":
@@ -140,12 +243,12 @@ func myNamespace() string {
 }
 
 // Initialises the first key and starts the rotation job. returns an early trigger function.
-// A period of 0 deactivates automatic rotation, but manual rotation (e.g. triggered by SIGUSR1)
-// is still honoured.
-func initKeyRenewal(ctx context.Context, registry *KeyRegistry, period, validFor time.Duration, cutoffTime time.Time, cn string, privateKeyAnnotations string, privateKeyLabels string) (func(), error) {
-	// Create a new key if it's the first key,
+// A nil schedule deactivates periodic rotation, but keys about to expire are still renewed,
+// and manual rotation (e.g. triggered by SIGUSR1) is still honoured.
+func initKeyRenewal(ctx context.Context, registry *KeyRegistry, schedule keyRenewalSchedule, validFor time.Duration, cutoffTime time.Time, cn string, privateKeyAnnotations string, privateKeyLabels string) (func(), error) {
+	// Create a new key if there is none to seal with,
 	// or if it's older than cutoff time.
-	if len(registry.keys) == 0 || registry.mostRecentKey.orderingTime.Before(cutoffTime) {
+	if mostRecentKey := registry.snapshot().mostRecentKey; mostRecentKey == nil || mostRecentKey.orderingTime.Before(cutoffTime) {
 		if _, err := registry.generateKey(ctx, validFor, cn, privateKeyAnnotations, privateKeyLabels); err != nil {
 			return nil, err
 		}
@@ -157,18 +260,121 @@ func initKeyRenewal(ctx context.Context, registry *KeyRegistry, period, validFor
 			slog.Error("Failed to generate new key", "error", err)
 		}
 	}
-	if period == 0 {
-		return keyGenFunc, nil
+	// We'll rotate the key when the renewal following the most recent key is
+	// due, or when its certificate is about to expire.
+	return scheduleKeyRenewal(registry, schedule, keyGenFunc), nil
+}
+
+// keyRenewalRecheckPeriod bounds the time the renewal loop sleeps, so that
+// keys created by other means, e.g. manually, postpone the next renewal.
+const keyRenewalRecheckPeriod = time.Hour
+
+// keyRenewalRetryPeriod is how long the renewal of an expiring key waits
+// after a failed attempt.
+const keyRenewalRetryPeriod = 5 * time.Minute
+
+// scheduleKeyRenewal creates a long-running loop that runs a job whenever the
+// key renewal is due according to schedule. The due time follows the ordering
+// time of the most recent key, or the previous run if that failed, so that
+// restarts don't reset the clock. When new keys are staged, the job runs the
+// activation grace period ahead, so that the new key is activated when due.
+// It returns a trigger function that runs the job early when called.
+func scheduleKeyRenewal(registry *KeyRegistry, schedule keyRenewalSchedule, job func()) func() {
+	trigger := make(chan struct{})
+	go func() {
+		var lastRun, lastDue time.Time
+		for {
+			due := nextKeyRenewal(registry, schedule, lastRun)
+			if !due.Equal(lastDue) {
+				lastDue = due
+				if due.IsZero() {
+					slog.Warn("No key renewal scheduled", "keyset", registry.keySetName)
+					keyRenewalNextTimestamp.WithLabelValues(registry.keySetName).Set(0)
+				} else {
+					slog.Info("Next key renewal scheduled", "keyset", registry.keySetName, "due", due.Format(time.RFC3339))
+					keyRenewalNextTimestamp.WithLabelValues(registry.keySetName).Set(float64(due.Unix()))
+				}
+			}
+
+			start := due.Add(-registry.activationGracePeriod)
+			wait := keyRenewalRecheckPeriod
+			if d := time.Until(start); !due.IsZero() && d < wait {
+				wait = d
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-trigger:
+				timer.Stop()
+			case <-timer.C:
+				if due.IsZero() || time.Now().Before(start) {
+					continue
+				}
+			}
+			lastRun = time.Now()
+			job()
+		}
+	}()
+	return func() {
+		trigger <- struct{}{}
 	}
+}
 
-	// If key rotation is enabled, we'll rotate the key when the most recent
-	// key becomes stale (older than period).
-	mostRecentKeyAge := time.Since(registry.mostRecentKey.orderingTime)
-	initialDelay := period - mostRecentKeyAge
-	if initialDelay < 0 {
-		initialDelay = 0
+// nextKeyRenewal returns when the key renewal following the most recent key,
+// or the staged one, and lastRun, is due. It's due earlier if the certificate
+// of that key is about to expire, which is also the only renewal due without
+// a schedule.
+func nextKeyRenewal(registry *KeyRegistry, schedule keyRenewalSchedule, lastRun time.Time) time.Time {
+	// A run stands for the activation of the key it stages.
+	since := lastRun
+	if !since.IsZero() {
+		since = since.Add(registry.activationGracePeriod)
 	}
-	return ScheduleJobWithTrigger(initialDelay, period, keyGenFunc), nil
+	var newest *Key
+	keys := registry.snapshot()
+	for _, k := range []*Key{keys.mostRecentKey, keys.upcomingKey} {
+		if k == nil {
+			continue
+		}
+		t := k.orderingTime
+		if k.activation.After(t) {
+			t = k.activation
+		}
+		if t.After(since) {
+			since = t
+		}
+		if newest == nil || k == keys.upcomingKey {
+			newest = k
+		}
+	}
+	if since.IsZero() {
+		return time.Now()
+	}
+	var due time.Time
+	if schedule != nil {
+		due = schedule.next(since)
+	}
+
+	if newest != nil && registry.renewBeforeExpiry > 0 {
+		expiring := newest.cert.NotAfter.Add(-registry.renewBeforeExpiry)
+		if retry := lastRun.Add(registry.activationGracePeriod + keyRenewalRetryPeriod); !lastRun.IsZero() && expiring.Before(retry) {
+			expiring = retry
+		}
+		if due.IsZero() || expiring.Before(due) {
+			due = expiring
+		}
+	}
+	return due
+}
+
+// keyRenewalScheduleOf returns the key renewal schedule set by the flags, if any.
+func keyRenewalScheduleOf(f *Flags) (keyRenewalSchedule, error) {
+	if f.KeyRenewSchedule != "" {
+		return parseCronSchedule(f.KeyRenewSchedule)
+	}
+	if f.KeyRenewPeriod == 0 {
+		return nil, nil
+	}
+	return periodSchedule(f.KeyRenewPeriod), nil
 }
 
 func Main(f *Flags, version string) error {
@@ -200,11 +406,68 @@ func Main(f *Flags, version string) error {
 		return err
 	}
 
-	keyRegistry, err := initKeyRegistry(ctx, clientset, rand.Reader, myNs, prefix, SealedSecretsKeyLabel, f.KeySize, f.KeyOrderPriority)
+	keyType, err := validateKeyType(f.KeyType)
 	if err != nil {
 		return err
 	}
 
+	keySetNames, err := parseKeySets(f.KeySets)
+	if err != nil {
+		return err
+	}
+	if len(keySetNames) > 0 && f.KMSPluginEndpoint != "" {
+		return fmt.Errorf("--key-sets cannot be used with a key management plugin")
+	}
+
+	keyRegistry, err := initKeyRegistry(ctx, clientset, myNs, prefix, SealedSecretsKeyLabel, keyType, f.KeySize, f.KeyOrderPriority)
+	if err != nil {
+		return err
+	}
+	keySets := newKeySetRegistries(keyRegistry)
+	for _, name := range keySetNames {
+		setPrefix, err := validateKeyPrefix(fmt.Sprintf("%s-%s-", prefix, name))
+		if err != nil {
+			return fmt.Errorf("key set %s: %w", name, err)
+		}
+		kr, err := initKeySetRegistry(ctx, clientset, myNs, setPrefix, SealedSecretsKeyLabel, name, keyType, f.KeySize, f.KeyOrderPriority)
+		if err != nil {
+			return fmt.Errorf("key set %s: %w", name, err)
+		}
+		keySets.named[name] = kr
+	}
+
+	if f.CSRSignerName != "" && f.KMSPluginEndpoint != "" {
+		return fmt.Errorf("--csr-signer-name cannot be used with a key management plugin")
+	}
+	expiryWindow := f.KeyRenewBeforeExpiry
+	if expiryWindow == 0 {
+		expiryWindow = f.ValidFor / 10
+	}
+	if expiryWindow > 0 && expiryWindow+f.KeyActivationGracePeriod >= f.ValidFor {
+		return fmt.Errorf("--key-renew-before-expiry plus --key-activation-grace-period must be shorter than --key-ttl")
+	}
+	for _, kr := range keySets.all() {
+		if f.CSRSignerName != "" {
+			kr.csrSignerName = f.CSRSignerName
+			kr.csrTimeout = f.CSRTimeout
+		}
+		kr.activationGracePeriod = f.KeyActivationGracePeriod
+		if expiryWindow > 0 {
+			kr.renewBeforeExpiry = expiryWindow
+		}
+	}
+
+	var escrow *keyEscrow
+	if len(f.EscrowCerts) > 0 || f.EscrowConfigMap != "" || f.EscrowPath != "" {
+		escrow, err = newKeyEscrow(clientset, myNs, f.EscrowCerts, f.EscrowConfigMap, f.EscrowPath)
+		if err != nil {
+			return err
+		}
+		for _, kr := range keySets.all() {
+			kr.escrow = escrow
+		}
+	}
+
 	var ct time.Time
 	if f.KeyCutoffTime != "" {
 		var err error
@@ -214,17 +477,53 @@ func Main(f *Flags, version string) error {
 		}
 	}
 
-	trigger, err := initKeyRenewal(ctx, keyRegistry, f.KeyRenewPeriod, f.ValidFor, ct, f.MyCN, f.PrivateKeyAnnotations, f.PrivateKeyLabels)
-	if err != nil {
-		return err
+	var schedule keyRenewalSchedule
+	if f.KMSPluginEndpoint != "" {
+		// Every replica reads the keys of the plugin, which aren't in Secrets.
+		kmsClient, err := kms.NewClient(f.KMSPluginEndpoint, f.KMSPluginTimeout)
+		if err != nil {
+			return err
+		}
+		defer kmsClient.Close()
+		trigger, err := initKMSKeys(ctx, keyRegistry, kmsClient, f.KeyRenewPeriod)
+		if err != nil {
+			return err
+		}
+		initKeyGenSignalListener(trigger)
+	} else {
+		schedule, err = keyRenewalScheduleOf(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.LeaderElect && !f.WatchForSecrets {
+		// Followers learn about the keys generated by the leader from their Secrets.
+		slog.Info("Watching key secrets for leader election")
+		f.WatchForSecrets = true
+	}
+
+	// Namespaces are selected by label, or excluded, as they come and go.
+	selectNamespaces := f.NamespaceSelector != "" || f.ExcludeNamespaces != ""
+	if selectNamespaces {
+		if !f.NamespaceAll {
+			return fmt.Errorf("--namespace-selector and --exclude-namespaces require --all-namespaces")
+		}
+		if f.AdditionalNamespaces != "" {
+			return fmt.Errorf("--namespace-selector and --exclude-namespaces cannot be used with --additional-namespaces")
+		}
 	}
 
-	initKeyGenSignalListener(trigger)
+	stop := make(chan struct{})
+	defer close(stop)
 
-	namespace := v1.NamespaceAll
-	if !f.NamespaceAll || f.AdditionalNamespaces != "" {
-		namespace = myNamespace()
-		slog.Info("Starting informer", "namespace", namespace)
+	var namespaces cache.SharedIndexInformer
+	if len(keySets.named) > 0 || selectNamespaces {
+		nsinformer := informers.NewSharedInformerFactory(clientset, 0)
+		keySets.namespaces = nsinformer.Core().V1().Namespaces().Lister()
+		namespaces = nsinformer.Core().V1().Namespaces().Informer()
+		nsinformer.Start(stop)
+		nsinformer.WaitForCacheSync(stop)
 	}
 
 	var tweakopts func(*metav1.ListOptions) = nil
@@ -234,18 +533,15 @@ func Main(f *Flags, version string) error {
 		}
 	}
 
-	controller, err := prepareController(clientset, namespace, myNs, tweakopts, f, ssclientset, keyRegistry)
-	if err != nil {
-		return err
+	// When namespaces are selected by label, the namespace watcher tells
+	// the controller which ones to watch as they come and go.
+	var watched []string
+	if !selectNamespaces {
+		watched = []string{v1.NamespaceAll}
+		if !f.NamespaceAll || f.AdditionalNamespaces != "" {
+			watched = []string{myNs}
+		}
 	}
-	controller.oldGCBehavior = f.OldGCBehavior
-	controller.updateStatus = f.UpdateStatus
-
-	stop := make(chan struct{})
-	defer close(stop)
-
-	go controller.Run(stop)
-
 	if f.AdditionalNamespaces != "" {
 		addNS := removeDuplicates(strings.Split(f.AdditionalNamespaces, ","))
 
@@ -257,33 +553,161 @@ func Main(f *Flags, version string) error {
 				}
 				return err
 			}
-			if ns != namespace {
-				ctlr, err := prepareController(clientset, ns, myNs, tweakopts, f, ssclientset, keyRegistry)
+			if ns != myNs {
+				watched = append(watched, ns)
+			}
+		}
+	}
+	for _, ns := range watched {
+		if ns != v1.NamespaceAll {
+			slog.Info("Starting informer", "namespace", ns)
+		}
+	}
+
+	if f.Workers < 1 {
+		return fmt.Errorf("--workers must be positive")
+	}
+	if f.RetryBaseDelay <= 0 || f.RetryMaxDelay < f.RetryBaseDelay {
+		return fmt.Errorf("--unseal-retry-base-delay must be positive, and no longer than --unseal-retry-max-delay")
+	}
+	controller, err := prepareController(clientset, watched, myNs, tweakopts, f, ssclientset, keyRegistry)
+	if err != nil {
+		return err
+	}
+	controller.oldGCBehavior = f.OldGCBehavior
+	controller.updateStatus = f.UpdateStatus
+	controller.keySets = keySets
+
+	var watcher *namespaceWatcher
+	if selectNamespaces {
+		watcher, err = newNamespaceWatcher(f.NamespaceSelector, f.ExcludeNamespaces, controller.WatchNamespace, controller.UnwatchNamespace)
+		if err != nil {
+			return err
+		}
+	}
+
+	var reencrypt *reencryptor
+	if f.Reencrypt {
+		if f.ReencryptRate <= 0 {
+			return fmt.Errorf("--reencrypt-rate must be positive")
+		}
+		reencrypt = newReencryptor(f.ReencryptRate)
+		reencrypt.controller = controller
+		for _, kr := range keySets.all() {
+			kr.Lock()
+			kr.sealingKeyChanged = reencrypt.Trigger
+			kr.Unlock()
+		}
+	}
+
+	// lead generates keys and unseals SealedSecrets until ctx is done. Only
+	// the leader runs it when there are several replicas.
+	lead := func(ctx context.Context) error {
+		controller.RunKeyInformer(stop)
+		if controller.kInformer != nil && !cache.WaitForCacheSync(ctx.Done(), controller.kInformer.HasSynced) {
+			return ctx.Err()
+		}
+		if escrow != nil {
+			for _, kr := range keySets.all() {
+				escrow.escrowKeys(ctx, kr)
+			}
+		}
+
+		if f.KMSPluginEndpoint == "" {
+			var triggers []func()
+			for _, kr := range keySets.all() {
+				t, err := initKeyRenewal(ctx, kr, schedule, f.ValidFor, ct, f.MyCN, f.PrivateKeyAnnotations, f.PrivateKeyLabels)
 				if err != nil {
 					return err
 				}
-				ctlr.oldGCBehavior = f.OldGCBehavior
-				ctlr.updateStatus = f.UpdateStatus
-				slog.Info("Starting informer", "namespace", ns)
-				go ctlr.Run(stop)
+				triggers = append(triggers, t)
+			}
+			initKeyGenSignalListener(func() {
+				for _, t := range triggers {
+					t()
+				}
+			})
+		}
+
+		go controller.Run(ctx.Done())
+		if watcher != nil {
+			go func() {
+				if err := watcher.Run(namespaces, ctx.Done()); err != nil {
+					slog.Error("Failed to watch namespaces", "error", err)
+				}
+			}()
+		}
+
+		if expiryWindow > 0 {
+			for _, kr := range keySets.all() {
+				// Warn when the renewal had half of the window to kick in but didn't.
+				monitor := newKeyExpiryMonitor(kr, controller.recorder, expiryWindow/2)
+				go monitor.Run(ctx)
+			}
+		}
+
+		if f.PublishSealingKeys {
+			publisher := &sealingKeyPublisher{client: ssclientset.BitnamiV1alpha1(), keySets: keySets}
+			go publisher.Run(ctx)
+		}
+
+		if reencrypt != nil {
+			go reencrypt.Run(ctx)
+		}
+		return nil
+	}
+
+	// fatal receives the errors which stop the controller.
+	fatal := make(chan error, 1)
+	electionCtx, cancelElection := context.WithCancel(ctx)
+	defer cancelElection()
+	electionDone := make(chan struct{})
+	if f.LeaderElect {
+		controller.RunKeyInformer(stop)
+		go func() {
+			defer close(electionDone)
+			err := runLeaderElection(electionCtx, clientset, myNs, f.LeaderElectLeaseName, leaderElectionIdentity(),
+				f.LeaderElectLeaseDuration, f.LeaderElectRenewDeadline, f.LeaderElectRetryPeriod, lead)
+			select {
+			case fatal <- err:
+			default:
 			}
+		}()
+	} else {
+		close(electionDone)
+		if err := lead(wait.ContextForChannel(stop)); err != nil {
+			return err
 		}
 	}
 
-	cp := func() ([]*x509.Certificate, error) {
-		cert, err := keyRegistry.getCert()
+	certChain := func(namespace string) ([]*x509.Certificate, error) {
+		kr, err := keySets.forNamespace(namespace)
 		if err != nil {
 			return nil, err
 		}
-		return []*x509.Certificate{cert}, nil
+		return kr.getCertChain()
 	}
-
-	server := httpserver(cp, controller.AttemptUnseal, controller.Rotate, f.RateLimitBurst, f.RateLimitPerSecond)
+	upcomingCertChain := func(namespace string) ([]*x509.Certificate, error) {
+		kr, err := keySets.forNamespace(namespace)
+		if err != nil {
+			return nil, err
+		}
+		return kr.getUpcomingCertChain()
+	}
+	server := httpserver(certChain, upcomingCertChain, controller.AttemptUnseal, controller.Rotate, f.RateLimitBurst, f.RateLimitPerSecond)
 	serverMetrics := httpserverMetrics()
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGTERM)
-	<-sigterm
+	var runErr error
+	select {
+	case <-sigterm:
+	case runErr = <-fatal:
+	}
+
+	// Hand the leadership over right away.
+	cancelElection()
+	<-electionDone
 
 	if err := server.Shutdown(context.Background()); err != nil {
 		return err
@@ -293,12 +717,14 @@ func Main(f *Flags, version string) error {
 		return err
 	}
 
-	return nil
+	return runErr
 }
 
+// prepareController returns a controller watching the given namespaces, and
+// the keys in keyNamespace.
 func prepareController(
 	clientset kubernetes.Interface,
-	namespace string,
+	namespaces []string,
 	keyNamespace string,
 	tweakopts func(*metav1.ListOptions),
 	f *Flags,
@@ -308,10 +734,24 @@ func prepareController(
 	kinformer := initSecretInformerFactory(clientset, keyNamespace, func(options *metav1.ListOptions) {
 		options.LabelSelector = keySelector.String()
 	}, f.WatchForSecrets)
-	sinformer := initSecretInformerFactory(clientset, namespace, tweakopts, !f.SkipRecreate)
-	ssinformer := ssinformers.NewFilteredSharedInformerFactory(ssclientset, 0, namespace, tweakopts)
-	controller, err := NewController(clientset, ssclientset, ssinformer, sinformer, kinformer, keyRegistry, f.MaxRetries, f.KeyOrderPriority)
-	return controller, err
+	informerFactories := func(namespace string) (ssinformers.SharedInformerFactory, informers.SharedInformerFactory) {
+		sinformer := initSecretInformerFactory(clientset, namespace, tweakopts, !f.SkipRecreate)
+		ssinformer := ssinformers.NewFilteredSharedInformerFactory(ssclientset, 0, namespace, tweakopts)
+		return ssinformer, sinformer
+	}
+	controller, err := NewController(clientset, ssclientset, informerFactories, kinformer, keyRegistry, f.MaxRetries, f.RetryBaseDelay, f.RetryMaxDelay, f.KeyOrderPriority)
+	if err != nil {
+		return nil, err
+	}
+	if f.Workers > 0 {
+		controller.workers = f.Workers
+	}
+	for _, ns := range namespaces {
+		if err := controller.WatchNamespace(ns); err != nil {
+			return nil, err
+		}
+	}
+	return controller, nil
 }
 
 func initSecretInformerFactory(clientset kubernetes.Interface, ns string, tweakopts func(*metav1.ListOptions), enabled bool) informers.SharedInformerFactory {
@@ -6,9 +6,12 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -16,8 +19,10 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	certUtil "k8s.io/client-go/util/cert"
 	"k8s.io/client-go/util/keyutil"
+	"k8s.io/client-go/util/workqueue"
 )
 
 func findAction(fake *fake.Clientset, verb, resource string) ktesting.Action {
@@ -45,11 +50,10 @@ func generateNameReactor(action ktesting.Action) (handled bool, ret runtime.Obje
 
 func TestInitKeyRegistry(t *testing.T) {
 	ctx := context.Background()
-	rand := testRand()
 	client := fake.NewClientset()
 	client.PrependReactor("create", "secrets", generateNameReactor)
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", "label", 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", "label", KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
@@ -69,7 +73,7 @@ func TestInitKeyRegistry(t *testing.T) {
 
 	// Due to limitations of the fake client, we cannot test whether initKeyRegistry is able
 	// to pick up existing keys
-	_, err = initKeyRegistry(ctx, client, rand, "namespace", "prefix", "label", 1024, "CertNotBefore")
+	_, err = initKeyRegistry(ctx, client, "namespace", "prefix", "label", KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
@@ -80,18 +84,17 @@ func TestInitKeyRegistry(t *testing.T) {
 
 func TestInitKeyRotation(t *testing.T) {
 	ctx := context.Background()
-	rand := testRand()
 	client := fake.NewClientset()
 	client.PrependReactor("create", "secrets", generateNameReactor)
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", "label", 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", "label", KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
 
 	validFor := time.Hour
 	cn := "my-cn"
-	keyGenTrigger, err := initKeyRenewal(ctx, registry, 0, validFor, time.Time{}, cn, "", "")
+	keyGenTrigger, err := initKeyRenewal(ctx, registry, nil, validFor, time.Time{}, cn, "", "")
 	if err != nil {
 		t.Fatalf("initKeyRenewal() returned err: %v", err)
 	}
@@ -121,18 +124,17 @@ func TestInitKeyRotation(t *testing.T) {
 
 func TestInitKeyRotationTick(t *testing.T) {
 	ctx := context.Background()
-	rand := testRand()
 	client := fake.NewClientset()
 	client.PrependReactor("create", "secrets", generateNameReactor)
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", "label", 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", "label", KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
 
 	validFor := time.Hour
 	cn := "my-cn"
-	_, err = initKeyRenewal(ctx, registry, 100*time.Millisecond, validFor, time.Time{}, cn, "", "")
+	_, err = initKeyRenewal(ctx, registry, periodSchedule(100*time.Millisecond), validFor, time.Time{}, cn, "", "")
 	if err != nil {
 		t.Fatalf("initKeyRenewal() returned err: %v", err)
 	}
@@ -180,14 +182,14 @@ func TestReuseKey(t *testing.T) {
 
 	client.ClearActions()
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", SealedSecretsKeyLabel, 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
 
 	validFor := time.Hour
 	cn := "my-cn"
-	_, err = initKeyRenewal(ctx, registry, 0, validFor, time.Time{}, cn, "", "")
+	_, err = initKeyRenewal(ctx, registry, nil, validFor, time.Time{}, cn, "", "")
 	if err != nil {
 		t.Fatalf("initKeyRenewal() returned err: %v", err)
 	}
@@ -196,6 +198,44 @@ func TestReuseKey(t *testing.T) {
 	}
 }
 
+func TestRenewExpiringKeyWithoutSchedule(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	client.PrependReactor("create", "secrets", generateNameReactor)
+
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", "label", KeyTypeRSA, 1024, "CertNotBefore")
+	if err != nil {
+		t.Fatalf("initKeyRegistry() returned err: %v", err)
+	}
+	validFor := time.Hour
+	// Every key is about to expire as soon as it's created.
+	registry.renewBeforeExpiry = validFor
+
+	// --key-renew-period=0 and no --key-renew-cron.
+	if _, err := initKeyRenewal(ctx, registry, nil, validFor, time.Time{}, "my-cn", "", ""); err != nil {
+		t.Fatalf("initKeyRenewal() returned err: %v", err)
+	}
+	if !hasAction(client, "create", "secrets") {
+		t.Errorf("initKeyRenewal() failed to generate an initial key")
+	}
+
+	client.ClearActions()
+
+	maxWait := 10 * time.Second
+	endTime := time.Now().Add(maxWait)
+	successful := false
+	for time.Now().Before(endTime) {
+		time.Sleep(50 * time.Millisecond)
+		if hasAction(client, "create", "secrets") {
+			successful = true
+			break
+		}
+	}
+	if !successful {
+		t.Errorf("an expiring key wasn't renewed with periodic rotation deactivated")
+	}
+}
+
 func TestRenewStaleKey(t *testing.T) {
 	ctx := context.Background()
 	rand := testRand()
@@ -227,14 +267,14 @@ func TestRenewStaleKey(t *testing.T) {
 		t.Errorf("writeKey() failed with: %v", err)
 	}
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", SealedSecretsKeyLabel, 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
 
 	validFor := time.Hour
 	cn := "my-cn"
-	_, err = initKeyRenewal(ctx, registry, period, validFor, time.Time{}, cn, "", "")
+	_, err = initKeyRenewal(ctx, registry, periodSchedule(period), validFor, time.Time{}, cn, "", "")
 	if err != nil {
 		t.Fatalf("initKeyRenewal() returned err: %v", err)
 	}
@@ -284,7 +324,7 @@ func TestKeyCutoff(t *testing.T) {
 		t.Errorf("writeKey() failed with: %v", err)
 	}
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", SealedSecretsKeyLabel, 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
@@ -294,7 +334,7 @@ func TestKeyCutoff(t *testing.T) {
 	// by setting cutoff to "now" we effectively force the creation of a new key.
 	validFor := time.Hour
 	cn := "my-cn"
-	_, err = initKeyRenewal(ctx, registry, period, validFor, time.Now(), cn, "", "")
+	_, err = initKeyRenewal(ctx, registry, periodSchedule(period), validFor, time.Now(), cn, "", "")
 	if err != nil {
 		t.Fatalf("initKeyRenewal() returned err: %v", err)
 	}
@@ -351,14 +391,14 @@ func TestLegacySecret(t *testing.T) {
 
 	client.ClearActions()
 
-	registry, err := initKeyRegistry(ctx, client, rand, "namespace", "prefix", SealedSecretsKeyLabel, 1024, "CertNotBefore")
+	registry, err := initKeyRegistry(ctx, client, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024, "CertNotBefore")
 	if err != nil {
 		t.Fatalf("initKeyRegistry() returned err: %v", err)
 	}
 
 	validFor := time.Hour
 	cn := "my-cn"
-	_, err = initKeyRenewal(ctx, registry, 0, validFor, time.Time{}, cn, "", "")
+	_, err = initKeyRenewal(ctx, registry, nil, validFor, time.Time{}, cn, "", "")
 	if err != nil {
 		t.Fatalf("initKeyRenewal() returned err: %v", err)
 	}
@@ -366,3 +406,106 @@ func TestLegacySecret(t *testing.T) {
 		t.Errorf("initKeyRenewal() should not create a new secret when one already exist and rotation is deactivated")
 	}
 }
+
+func TestRegistryUpdateKeyWithSecret(t *testing.T) {
+	registry := NewKeyRegistry(nil, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 2048)
+	keySecret := func(annotations map[string]string) (*v1.Secret, string) {
+		t.Helper()
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, 2048, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := keySecretData(key, []*x509.Certificate{cert})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp, err := crypto.PublicKeyFingerprint(cert.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sealed-secrets-key1", Annotations: annotations},
+			Data:       data,
+			Type:       v1.SecretTypeTLS,
+		}, fp
+	}
+	update := func(secret *v1.Secret, want ...string) {
+		t.Helper()
+		changed, err := registryUpdateKeyWithSecret(secret, registry, "CertNotBefore")
+		if err != nil {
+			t.Fatalf("registryUpdateKeyWithSecret() returned error: %v", err)
+		}
+		if !slices.Equal(slices.Sorted(slices.Values(changed)), slices.Sorted(slices.Values(want))) {
+			t.Errorf("got changed keys %v, want %v", changed, want)
+		}
+	}
+
+	secret1, fp1 := keySecret(nil)
+	update(secret1, fp1)
+	update(secret1)
+	if registry.snapshot().sealingKey == nil || registry.snapshot().sealingKey.fingerprint != fp1 {
+		t.Fatalf("the added key isn't used for sealing")
+	}
+
+	secret1.Annotations = map[string]string{SealedSecretsKeyStateAnnotation: string(KeyStateDecryptOnly)}
+	update(secret1, fp1)
+	if registry.snapshot().sealingKey != nil {
+		t.Errorf("a decrypt-only key is used for sealing")
+	}
+
+	// The Secret is replaced with another key.
+	secret2, fp2 := keySecret(nil)
+	update(secret2, fp1, fp2)
+	if _, ok := registry.snapshot().keys[fp1]; ok || len(registry.snapshot().keys) != 1 {
+		t.Errorf("the replaced key is still registered")
+	}
+
+	// A Secret which doesn't hold a valid key any more unregisters its key.
+	broken := secret2.DeepCopy()
+	delete(broken.Data, v1.TLSCertKey)
+	changed, err := registryUpdateKeyWithSecret(broken, registry, "CertNotBefore")
+	if err == nil {
+		t.Errorf("registryUpdateKeyWithSecret() succeeded with an invalid key")
+	}
+	if !slices.Equal(changed, []string{fp2}) || len(registry.snapshot().keys) != 0 {
+		t.Errorf("got changed keys %v and %d keys, want the invalid key unregistered", changed, len(registry.snapshot().keys))
+	}
+
+	update(secret2, fp2)
+	if got := registryDeleteKeyWithSecret(secret2, registry); !slices.Equal(got, []string{fp2}) {
+		t.Errorf("got deleted keys %v, want %v", got, []string{fp2})
+	}
+	if len(registry.snapshot().keys) != 0 || registry.snapshot().sealingKey != nil || registry.snapshot().mostRecentKey != nil {
+		t.Errorf("the deleted key is still registered")
+	}
+}
+
+func TestRequeueDependents(t *testing.T) {
+	registry := NewKeyRegistry(nil, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 2048)
+	ssInformer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &ssv1alpha1.SealedSecret{}, 0, cache.Indexers{})
+	for _, name := range []string{"decrypted-by-1", "decrypted-by-2", "failed"} {
+		if err := ssInformer.GetStore().Add(&ssv1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry.recordDecryption("default/decrypted-by-1", []string{"fp1"})
+	registry.recordDecryption("default/decrypted-by-2", []string{"fp2"})
+	// SealedSecrets of other controllers aren't queued.
+	registry.recordDecryption("other/decrypted-by-1", []string{"fp1"})
+
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]())
+	defer queue.ShutDown()
+	registryFor := func(string) (*KeyRegistry, error) { return registry, nil }
+	requeueDependents(ssInformer.GetStore().List, queue, registryFor)(registry, []string{"fp1"})
+
+	var got []string
+	for queue.Len() > 0 {
+		key, _ := queue.Get()
+		got = append(got, key)
+		queue.Done(key)
+	}
+	slices.Sort(got)
+	if want := []string{"default/decrypted-by-1", "default/failed"}; !slices.Equal(got, want) {
+		t.Errorf("got queued %v, want %v", got, want)
+	}
+}
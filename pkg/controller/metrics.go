@@ -18,6 +18,7 @@ const (
 	labelName      = "name"
 	labelCondition = "condition"
 	labelInstance  = "ss_app_kubernetes_io_instance"
+	labelKeySet    = "key_set"
 )
 
 var conditionStatusToGaugeValue = map[v1.ConditionStatus]float64{
@@ -48,6 +49,33 @@ var (
 		[]string{"reason", "namespace"},
 	)
 
+	reencryptionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "reencryptions_total",
+			Help:      "Total number of SealedSecrets re-encrypted in the background by result",
+		},
+		[]string{"result"},
+	)
+
+	keyRenewalNextTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "key_renewal_next_timestamp_seconds",
+			Help:      "Time of the next scheduled key renewal in seconds since the epoch, 0 if there is none, by key set",
+		},
+		[]string{labelKeySet},
+	)
+
+	sealingCertExpiryTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "sealing_cert_expiry_timestamp_seconds",
+			Help:      "Expiry time of the certificate of the sealing key in seconds since the epoch, 0 if there is none, by key set",
+		},
+		[]string{labelKeySet},
+	)
+
 	conditionInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: metricNamespace,
@@ -91,6 +119,9 @@ func registerMetrics(version string) {
 	prometheus.MustRegister(collectors.NewBuildInfoCollector())
 	prometheus.MustRegister(unsealRequestsTotal)
 	prometheus.MustRegister(unsealErrorsTotal)
+	prometheus.MustRegister(reencryptionsTotal)
+	prometheus.MustRegister(keyRenewalNextTimestamp)
+	prometheus.MustRegister(sealingCertExpiryTimestamp)
 	prometheus.MustRegister(conditionInfo)
 	prometheus.MustRegister(httpRequestsTotal)
 	prometheus.MustRegister(httpRequestDurationSeconds)
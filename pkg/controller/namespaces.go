@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// A namespaceWatcher watches the namespaces which match a label selector and
+// aren't excluded, following namespaces as they are created, relabelled or
+// deleted.
+type namespaceWatcher struct {
+	selector labels.Selector
+	exclude  map[string]bool
+	// watch and unwatch start and stop watching a namespace.
+	watch   func(namespace string) error
+	unwatch func(namespace string)
+
+	mu      sync.Mutex
+	watched map[string]bool
+	stopped bool
+}
+
+// newNamespaceWatcher returns a namespaceWatcher for the namespaces matching
+// the label selector, all of them if it's empty, except the ones in the comma
+// separated exclude list.
+func newNamespaceWatcher(selector, exclude string, watch func(namespace string) error, unwatch func(namespace string)) (*namespaceWatcher, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector %q: %w", selector, err)
+	}
+	excluded := map[string]bool{}
+	if exclude != "" {
+		for _, ns := range strings.Split(exclude, ",") {
+			excluded[ns] = true
+		}
+	}
+	return &namespaceWatcher{
+		selector: sel,
+		exclude:  excluded,
+		watch:    watch,
+		unwatch:  unwatch,
+		watched:  map[string]bool{},
+	}, nil
+}
+
+// watches reports whether a namespace should be watched.
+func (w *namespaceWatcher) watches(ns *corev1.Namespace) bool {
+	return !w.exclude[ns.Name] && w.selector.Matches(labels.Set(ns.Labels))
+}
+
+// Run starts and stops watching the namespaces seen by informer until stopCh
+// is closed, and then stops watching them all.
+func (w *namespaceWatcher) Run(informer cache.SharedIndexInformer, stopCh <-chan struct{}) error {
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: w.sync,
+		UpdateFunc: func(_, obj interface{}) {
+			w.sync(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				w.stop(ns.Name)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	<-stopCh
+
+	if err := informer.RemoveEventHandler(registration); err != nil {
+		slog.Error("Failed to stop watching namespaces", "error", err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for ns := range w.watched {
+		w.stopLocked(ns)
+	}
+	return nil
+}
+
+// sync starts or stops watching a namespace which was added or updated,
+// depending on whether it's selected.
+func (w *namespaceWatcher) sync(obj interface{}) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	if w.watches(ns) {
+		w.start(ns.Name)
+	} else {
+		w.stop(ns.Name)
+	}
+}
+
+func (w *namespaceWatcher) start(namespace string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watched[namespace] || w.stopped {
+		return
+	}
+	if err := w.watch(namespace); err != nil {
+		slog.Error("Failed to watch namespace", "namespace", namespace, "error", err)
+		return
+	}
+	w.watched[namespace] = true
+	slog.Info("Started watching namespace", "namespace", namespace)
+}
+
+func (w *namespaceWatcher) stop(namespace string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked(namespace)
+}
+
+// stopLocked is stop with w.mu held.
+func (w *namespaceWatcher) stopLocked(namespace string) {
+	if !w.watched[namespace] {
+		return
+	}
+	w.unwatch(namespace)
+	delete(w.watched, namespace)
+	slog.Info("Stopped watching namespace", "namespace", namespace)
+}
+
+// namespaces returns the watched namespaces, sorted. This method can be
+// called by another goroutine.
+func (w *namespaceWatcher) namespaces() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	namespaces := make([]string, 0, len(w.watched))
+	for ns := range w.watched {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+)
+
+func TestNamespaceWatcher(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "selected", Labels: map[string]string{"sealed-secrets": "enabled"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "excluded", Labels: map[string]string{"sealed-secrets": "enabled"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+	ssc := ssfake.NewSimpleClientset()
+	keyRegistry := NewKeyRegistry(clientset, "namespace", "prefix", SealedSecretsKeyLabel, KeyTypeRSA, 1024)
+
+	c, err := prepareController(clientset, nil, "namespace", nil, &Flags{}, ssc, keyRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newNamespaceWatcher("sealed-secrets=enabled", "excluded", c.WatchNamespace, c.UnwatchNamespace)
+	if err != nil {
+		t.Fatalf("newNamespaceWatcher() returned error: %v", err)
+	}
+	expectNamespaces := func(want ...string) {
+		t.Helper()
+		var got []string
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+			got = w.namespaces()
+			return reflect.DeepEqual(got, append([]string{}, want...)), nil
+		})
+		if err != nil {
+			t.Fatalf("got watched namespaces %v, want %v", got, want)
+		}
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		if len(c.namespaces) != len(want) {
+			t.Errorf("the controller watches %d namespaces, want %d", len(c.namespaces), len(want))
+		}
+	}
+
+	stop := make(chan struct{})
+	nsinformer := informers.NewSharedInformerFactory(clientset, 0)
+	namespaces := nsinformer.Core().V1().Namespaces().Informer()
+	nsinformer.Start(stop)
+	go c.Run(stop)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(namespaces, stop)
+	}()
+	expectNamespaces("selected")
+
+	// Namespaces are followed as they're created, relabelled and deleted.
+	if _, err := clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "new", Labels: map[string]string{"sealed-secrets": "enabled"}}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectNamespaces("new", "selected")
+
+	if _, err := clientset.CoreV1().Namespaces().Update(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"sealed-secrets": "enabled"}}}, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientset.CoreV1().Namespaces().Update(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "selected"}}, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectNamespaces("new", "other")
+
+	if err := clientset.CoreV1().Namespaces().Delete(ctx, "new", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectNamespaces("other")
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("Run() returned error: %v", err)
+	}
+	expectNamespaces()
+}
+
+func TestNewNamespaceWatcherInvalidSelector(t *testing.T) {
+	if _, err := newNamespaceWatcher("a in (", "", nil, nil); err == nil {
+		t.Errorf("newNamespaceWatcher() accepted an invalid selector")
+	}
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+)
+
+const (
+	// SuccessReencrypted is used as part of the Event 'reason' and the
+	// Reencrypted condition 'reason' when a SealedSecret is re-encrypted
+	// for the sealing key in the background.
+	SuccessReencrypted = "Reencrypted"
+
+	// ErrReencryptFailed is used as part of the Event 'reason' and the
+	// Reencrypted condition 'reason' when a SealedSecret cannot be
+	// re-encrypted in the background.
+	ErrReencryptFailed = "ErrReencryptFailed"
+)
+
+// errNotUnsealable is returned when re-encrypting a SealedSecret which doesn't
+// unseal in the first place.
+var errNotUnsealable = errors.New("SealedSecret doesn't unseal")
+
+// A reencryptor re-encrypts the SealedSecrets watched by its controller for
+// the sealing key, so that old keys can eventually be retired.
+type reencryptor struct {
+	controller *Controller
+	limiter    *rate.Limiter
+	trigger    chan struct{}
+}
+
+// newReencryptor returns a reencryptor re-encrypting at most perSecond SealedSecrets per second.
+func newReencryptor(perSecond float64) *reencryptor {
+	return &reencryptor{
+		limiter: rate.NewLimiter(rate.Limit(perSecond), 1),
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Trigger schedules a pass over all SealedSecrets, e.g. after a key renewal.
+// It never blocks, and triggers coalesce while a pass is running.
+func (r *reencryptor) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run makes a first pass once the informers have synced, to catch up with
+// renewals which happened while the controller was down, and then one each
+// time it's triggered, until ctx is done.
+func (r *reencryptor) Run(ctx context.Context) {
+	if !cache.WaitForCacheSync(ctx.Done(), r.controller.HasSynced) {
+		return
+	}
+	r.Trigger()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.trigger:
+		}
+		if err := r.controller.reencryptAll(ctx, r.limiter); err != nil {
+			return
+		}
+	}
+}
+
+// reencryptAll re-encrypts the SealedSecrets which aren't sealed for the
+// sealing key of the key set of their namespace. It only returns an error if
+// ctx is done.
+func (c *Controller) reencryptAll(ctx context.Context, limiter *rate.Limiter) error {
+	var reencrypted, skipped, failed int
+	for _, obj := range c.sealedSecrets() {
+		ss, ok := obj.(*ssv1alpha1.SealedSecret)
+		if !ok {
+			continue
+		}
+		keyRegistry, err := c.registryFor(ss.Namespace)
+		if err != nil {
+			continue
+		}
+		sealingKey := keyRegistry.snapshot().sealingKey
+		if sealingKey == nil || !needsReencryption(ss, sealingKey.fingerprint) {
+			continue
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		if err := c.reencrypt(ctx, ss); errors.Is(err, errNotUnsealable) {
+			// The controller already reports it, and it's retried on the next pass.
+			skipped++
+			continue
+		} else if err != nil {
+			slog.Error("Failed to re-encrypt SealedSecret", "namespace", ss.Namespace, "name", ss.Name, "error", err)
+			failed++
+			continue
+		}
+		reencrypted++
+	}
+	if reencrypted > 0 || skipped > 0 || failed > 0 {
+		slog.Info("Re-encrypted SealedSecrets", "reencrypted", reencrypted, "skipped", skipped, "failed", failed)
+	}
+	return nil
+}
+
+// needsReencryption reports whether some value of a SealedSecret isn't sealed
+// for the key with the given fingerprint. SealedSecrets with values sealed for
+// several keys, e.g. of several clusters, are left alone since re-encrypting
+// them would drop the other recipients.
+func needsReencryption(ss *ssv1alpha1.SealedSecret, fingerprint string) bool {
+	needed := false
+	for _, ciphertext := range ciphertextsOf(ss) {
+		fingerprints, err := crypto.SealingKeyFingerprints(ciphertext)
+		if err != nil || len(fingerprints) > 1 {
+			return false
+		}
+		// Legacy ciphertexts don't tell which key they were sealed for.
+		if len(fingerprints) == 0 || fingerprints[0] != fingerprint {
+			needed = true
+		}
+	}
+	return needed
+}
+
+// reencrypt re-encrypts a SealedSecret in place with the logic of Rotate, and
+// records the outcome. SealedSecrets which don't unseal are left alone without
+// recording anything, since the controller already reports why once rather
+// than on every pass.
+func (c *Controller) reencrypt(ctx context.Context, ss *ssv1alpha1.SealedSecret) error {
+	err := c.updateReencrypted(ctx, ss)
+	if errors.Is(err, errNotUnsealable) {
+		return err
+	}
+	if err != nil {
+		c.recorder.Eventf(ss, corev1.EventTypeWarning, ErrReencryptFailed, "Failed to re-encrypt: %v", err)
+		reencryptionsTotal.WithLabelValues("failure").Inc()
+	} else {
+		c.recorder.Event(ss, corev1.EventTypeNormal, SuccessReencrypted, "SealedSecret re-encrypted for the current sealing key")
+		reencryptionsTotal.WithLabelValues("success").Inc()
+	}
+	if statusErr := c.updateReencryptedStatus(ctx, ss, err); statusErr != nil {
+		// Non-fatal.  Log and continue.
+		slog.Error("Error updating SealedSecret status", "namespace", ss.Namespace, "name", ss.Name, "error", statusErr)
+	}
+	return err
+}
+
+func (c *Controller) updateReencrypted(ctx context.Context, ss *ssv1alpha1.SealedSecret) error {
+	secret, err := c.attemptUnseal(ss)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNotUnsealable, err)
+	}
+	resealed, err := c.resealUnsealed(ss, secret)
+	if err != nil {
+		return err
+	}
+	updated := ss.DeepCopy()
+	if ss.Spec.Data != nil {
+		// The deprecated format seals the whole secret at once.
+		updated.Spec = resealed.Spec
+	} else {
+		updated.Spec.EncryptedData = resealed.Spec.EncryptedData
+	}
+	if v, ok := resealed.Annotations[ssv1alpha1.SealedSecretBindItemKeysAnnotation]; ok {
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[ssv1alpha1.SealedSecretBindItemKeysAnnotation] = v
+	}
+
+	// Never replace a SealedSecret with something that doesn't unseal to the same Secret.
+	if _, err := c.attemptUnseal(updated); err != nil {
+		return fmt.Errorf("re-encrypted SealedSecret doesn't unseal: %w", err)
+	}
+	_, err = c.ssclient.SealedSecrets(ss.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *Controller) updateReencryptedStatus(ctx context.Context, ss *ssv1alpha1.SealedSecret, reencryptErr error) error {
+	if !c.updateStatus {
+		return nil
+	}
+	ssclient := c.ssclient.SealedSecrets(ss.Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// The update, and the resulting unsealing, change the SealedSecret.
+		latest, err := ssclient.Get(ctx, ss.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if latest.Status == nil {
+			latest.Status = &ssv1alpha1.SealedSecretStatus{}
+		}
+		cond := statusCondition(latest.Status, ssv1alpha1.SealedSecretReencrypted)
+		status, reason, message := corev1.ConditionTrue, SuccessReencrypted, ""
+		if reencryptErr != nil {
+			status, reason, message = corev1.ConditionFalse, ErrReencryptFailed, reencryptErr.Error()
+		}
+		cond.LastUpdateTime = metav1.Now()
+		if cond.Status != status {
+			cond.LastTransitionTime = cond.LastUpdateTime
+			cond.Status = status
+		}
+		cond.Reason = reason
+		cond.Message = message
+		_, err = ssclient.UpdateStatus(ctx, latest, metav1.UpdateOptions{})
+		return err
+	})
+}
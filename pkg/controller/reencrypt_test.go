@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+)
+
+func TestReencrypt(t *testing.T) {
+	ctx := context.Background()
+	const keySize = 2048
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, keySize)
+	var certs []*x509.Certificate
+	for i := range 2 {
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kr.registerNewKey(fmt.Sprintf("k%d", i), key, []*x509.Certificate{cert}, time.Unix(int64(i), 0), KeyStateActive, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ss",
+			Namespace:   "default",
+			Annotations: map[string]string{ssv1alpha1.SealedSecretBindTemplateAnnotation: "true"},
+		},
+		Data: map[string][]byte{"password": []byte("temporal")},
+	}
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, certs[0].PublicKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !needsReencryption(ssecret, kr.snapshot().sealingKey.fingerprint) {
+		t.Fatalf("SealedSecret sealed for an old key doesn't need re-encryption")
+	}
+
+	ssc := ssfake.NewSimpleClientset(ssecret)
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		ssclient:     ssc.BitnamiV1alpha1(),
+		recorder:     recorder,
+		keySets:      newKeySetRegistries(kr),
+		updateStatus: true,
+	}
+	if err := c.reencrypt(ctx, ssecret); err != nil {
+		t.Fatalf("reencrypt() returned error: %v", err)
+	}
+	<-recorder.Events
+
+	got, err := ssc.BitnamiV1alpha1().SealedSecrets("default").Get(ctx, "ss", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsReencryption(got, kr.snapshot().sealingKey.fingerprint) {
+		t.Errorf("SealedSecret wasn't re-encrypted for the sealing key")
+	}
+	unsealed, err := attemptUnseal(got, kr)
+	if err != nil {
+		t.Fatalf("error unsealing the re-encrypted SealedSecret: %v", err)
+	}
+	if got, want := string(unsealed.Data["password"]), "temporal"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got.Status == nil || len(got.Status.Conditions) != 1 {
+		t.Fatalf("got status %v, want a Reencrypted condition", got.Status)
+	}
+	if cond := got.Status.Conditions[0]; cond.Type != ssv1alpha1.SealedSecretReencrypted || cond.Status != corev1.ConditionTrue {
+		t.Errorf("got condition %v, want Reencrypted True", cond)
+	}
+
+	// A SealedSecret which can't be unsealed is left alone, without recording
+	// anything on every pass.
+	broken := got.DeepCopy()
+	broken.Spec.EncryptedData["password"] = ssecret.Spec.EncryptedData["password"]
+	broken.Spec.Template.Labels = map[string]string{"tampered": "true"}
+	if _, err := ssc.BitnamiV1alpha1().SealedSecrets("default").Update(ctx, broken, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.reencrypt(ctx, broken); !errors.Is(err, errNotUnsealable) {
+		t.Fatalf("got error %v re-encrypting a tampered template, want %v", err, errNotUnsealable)
+	}
+	got, err = ssc.BitnamiV1alpha1().SealedSecrets("default").Get(ctx, "ss", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Spec.EncryptedData["password"] != ssecret.Spec.EncryptedData["password"] {
+		t.Errorf("a SealedSecret which cannot be unsealed was changed")
+	}
+	if cond := got.Status.Conditions[0]; cond.Status != corev1.ConditionTrue {
+		t.Errorf("got condition %v, want it unchanged", cond)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("got event %q for a SealedSecret which cannot be unsealed", event)
+	default:
+	}
+}
+
+func TestReencryptBoundTemplateData(t *testing.T) {
+	ctx := context.Background()
+	const keySize = 2048
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, keySize)
+	var certs []*x509.Certificate
+	for i := range 2 {
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kr.registerNewKey(fmt.Sprintf("k%d", i), key, []*x509.Certificate{cert}, time.Unix(int64(i), 0), KeyStateActive, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ss",
+			Namespace:   "default",
+			Annotations: map[string]string{ssv1alpha1.SealedSecretBindTemplateAnnotation: "true"},
+		},
+		Data: map[string][]byte{"password": []byte("temporal")},
+	}
+	templateData := map[string]string{"url": `postgres://user:{{ index . "password" }}@db`}
+	ssecret, err := ssv1alpha1.NewSealedSecretWithTemplateData(scheme.Codecs, certs[0].PublicKey, secret, templateData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ssc := ssfake.NewSimpleClientset(ssecret)
+	c := &Controller{
+		ssclient: ssc.BitnamiV1alpha1(),
+		recorder: record.NewFakeRecorder(10),
+		keySets:  newKeySetRegistries(kr),
+	}
+	if err := c.reencrypt(ctx, ssecret); err != nil {
+		t.Fatalf("reencrypt() returned error: %v", err)
+	}
+
+	got, err := ssc.BitnamiV1alpha1().SealedSecrets("default").Get(ctx, "ss", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsReencryption(got, kr.snapshot().sealingKey.fingerprint) {
+		t.Errorf("SealedSecret wasn't re-encrypted for the sealing key")
+	}
+	if !reflect.DeepEqual(got.Spec.Template.Data, templateData) {
+		t.Errorf("got template data %v, want %v", got.Spec.Template.Data, templateData)
+	}
+	if _, ok := got.Spec.EncryptedData["url"]; ok {
+		t.Errorf("the rendered template data was sealed")
+	}
+	unsealed, err := attemptUnseal(got, kr)
+	if err != nil {
+		t.Fatalf("error unsealing the re-encrypted SealedSecret: %v", err)
+	}
+	if got, want := string(unsealed.Data["url"]), "postgres://user:temporal@db"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNeedsReencryptionMultipleRecipients(t *testing.T) {
+	const keySize = 2048
+	var recipients crypto.Recipients
+	for range 2 {
+		key, _, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := crypto.PublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients = append(recipients, pub)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("temporal")},
+	}
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, recipients, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsReencryption(ssecret, "some-other-key") {
+		t.Errorf("SealedSecret sealed for several keys would be re-encrypted")
+	}
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A keyRenewalSchedule tells when the key renewal following a key created at
+// a given time is due.
+type keyRenewalSchedule interface {
+	// next returns the first due time after t, or the zero time if there is none.
+	next(t time.Time) time.Time
+}
+
+// periodSchedule renews keys every period.
+type periodSchedule time.Duration
+
+func (p periodSchedule) next(t time.Time) time.Time {
+	return t.Add(time.Duration(p))
+}
+
+// cronSchedule renews keys at the times matched by a cron expression.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// nthDow holds, for each weekday, the weeks of the month it matches in,
+	// as bits 1 to 5, for entries like MON#1.
+	nthDow           [7]uint64
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// parseCronSchedule parses a cron expression made of the minute, hour, day of
+// month, month and day of week fields, or one of the @yearly, @monthly,
+// @weekly, @daily and @hourly descriptors. Besides the usual lists, ranges
+// and steps, a day of week can be restricted to the nth of the month, e.g.
+// MON#1 for the first Monday. As in standard cron, a day matches if either
+// the day of month or the day of week does, when both are restricted, and a
+// field starting with * (e.g. */2) doesn't restrict the day. Times
+// are in UTC, unless the expression starts with CRON_TZ=<time zone>.
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	s := &cronSchedule{loc: time.UTC}
+	fields := strings.Fields(spec)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "CRON_TZ=") {
+		loc, err := time.LoadLocation(strings.TrimPrefix(fields[0], "CRON_TZ="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		s.loc = loc
+		fields = fields[1:]
+	}
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		expr, ok := cronDescriptors[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule %q: unknown descriptor %s", spec, fields[0])
+		}
+		fields = strings.Fields(expr)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", spec, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", spec, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", spec, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", spec, err)
+	}
+	if err := s.parseDow(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", spec, err)
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	s.dowStar = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+
+	if s.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid schedule %q: never due", spec)
+	}
+	return s, nil
+}
+
+// parseDow parses the day of week field, where both 0 and 7 are Sunday.
+func (s *cronSchedule) parseDow(field string) error {
+	var plain []string
+	for _, part := range strings.Split(field, ",") {
+		day, nth, ok := strings.Cut(part, "#")
+		if !ok {
+			plain = append(plain, part)
+			continue
+		}
+		d, err := parseCronValue(day, 0, 7, weekdayNames)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(nth)
+		if err != nil || n < 1 || n > 5 {
+			return fmt.Errorf("invalid week of the month %q", nth)
+		}
+		s.nthDow[d%7] |= 1 << uint(n)
+	}
+	if len(plain) > 0 {
+		bits, err := parseCronField(strings.Join(plain, ","), 0, 7, weekdayNames)
+		if err != nil {
+			return err
+		}
+		if bits&(1<<7) != 0 {
+			bits |= 1 << 0
+		}
+		s.dow = bits &^ (1 << 7)
+	}
+	return nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+// into a bit set.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		lo, hi := min, max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseCronValue(from, min, max, names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(to, min, max, names); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := parseCronValue(rng, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+		inc := 1
+		if hasStep {
+			var err error
+			if inc, err = strconv.Atoi(step); err != nil || inc < 1 {
+				return 0, fmt.Errorf("invalid step %q", step)
+			}
+		}
+		for v := lo; v <= hi; v += inc {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(value, name) {
+			return i + min, nil
+		}
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value %q, expected %d to %d", value, min, max)
+	}
+	return v, nil
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	// The schedule is checked not to be impossible, but some are very rare,
+	// e.g. February 29th falling on a Monday.
+	limit := t.AddDate(30, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			// Adding to the absolute time steps over daylight saving time changes.
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	week := (t.Day()-1)/7 + 1
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0 || s.nthDow[t.Weekday()]&(1<<uint(week)) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	testCases := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{spec: "0 2 * * MON#1", from: "2026-10-17T00:00:00Z", want: "2026-11-02T02:00:00Z"},
+		{spec: "0 2 * * 1#1", from: "2026-11-02T02:00:00Z", want: "2026-12-07T02:00:00Z"},
+		{spec: "@monthly", from: "2026-10-17T00:00:00Z", want: "2026-11-01T00:00:00Z"},
+		{spec: "*/15 * * * *", from: "2026-10-17T10:07:30Z", want: "2026-10-17T10:15:00Z"},
+		{spec: "30 4 1,15 feb-mar *", from: "2026-10-17T00:00:00Z", want: "2027-02-01T04:30:00Z"},
+		// The day of month or the day of week match when both are restricted.
+		{spec: "0 0 13 * FRI", from: "2026-10-17T00:00:00Z", want: "2026-10-23T00:00:00Z"},
+		{spec: "0 0 1-7 * MON", from: "2026-10-20T00:00:00Z", want: "2026-10-26T00:00:00Z"},
+		// Like in Vixie cron, a field starting with * doesn't restrict the day,
+		// so both must match: the next odd day which is a Monday.
+		{spec: "0 0 */2 * MON", from: "2026-10-20T00:00:00Z", want: "2026-11-09T00:00:00Z"},
+		{spec: "0 0 13 * */3", from: "2026-10-17T00:00:00Z", want: "2026-12-13T00:00:00Z"},
+		{spec: "0 0 * * 7", from: "2026-10-17T00:00:00Z", want: "2026-10-18T00:00:00Z"},
+		{spec: "0 0 29 2 *", from: "2026-10-17T00:00:00Z", want: "2028-02-29T00:00:00Z"},
+		{spec: "CRON_TZ=Europe/Berlin 0 2 * * *", from: "2026-10-17T00:00:00Z", want: "2026-10-18T00:00:00Z"},
+		// 02:30 doesn't exist when daylight saving time starts.
+		{spec: "CRON_TZ=Europe/Berlin 30 2 * * *", from: "2027-03-27T12:00:00Z", want: "2027-03-29T00:30:00Z"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := parseCronSchedule(tc.spec)
+			if err != nil {
+				t.Fatalf("parseCronSchedule() returned error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, tc.from)
+			if got := s.next(from).UTC().Format(time.RFC3339); got != tc.want {
+				t.Errorf("got next time %s after %s, want %s", got, tc.from, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 2 * *",
+		"60 * * * *",
+		"0 2 * * MON#6",
+		"0 2 10-1 * *",
+		"*/0 * * * *",
+		"0 0 30 2 *",
+		"@fortnightly",
+		"CRON_TZ=Nowhere/Special 0 2 * * *",
+	} {
+		if _, err := parseCronSchedule(spec); err == nil {
+			t.Errorf("parseCronSchedule(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestNextKeyRenewal(t *testing.T) {
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, 1024)
+	schedule := periodSchedule(24 * time.Hour)
+
+	before := time.Now()
+	if got := nextKeyRenewal(kr, schedule, time.Time{}); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("got next renewal at %s without keys, want now", got)
+	}
+
+	key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, 1024, time.Hour, "my-cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-2 * time.Hour)
+	if err := kr.registerNewKey("k", key, []*x509.Certificate{cert}, created, KeyStateActive, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	// The clock starts with the most recent key, not with the controller.
+	if got, want := nextKeyRenewal(kr, schedule, time.Time{}), created.Add(24*time.Hour); !got.Equal(want) {
+		t.Errorf("got next renewal at %s, want %s", got, want)
+	}
+
+	// A failed renewal is retried when the next one is due.
+	lastRun := time.Now().Add(24 * time.Hour)
+	if got, want := nextKeyRenewal(kr, schedule, lastRun), lastRun.Add(24*time.Hour); !got.Equal(want) {
+		t.Errorf("got next renewal at %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssv1alpha1client "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/typed/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+)
+
+// SealingKeyNamespaceLabel is set on SealingKeys to the namespace of the controller holding the key.
+const SealingKeyNamespaceLabel = "sealedsecrets.bitnami.com/controller-namespace"
+
+// sealingKeyPublishPeriod is how often the SealingKeys are brought up to date
+// with the key registry, mostly for the decryption counts.
+const sealingKeyPublishPeriod = time.Minute
+
+// A sealingKeyPublisher maintains a read-only SealingKey for each key of the
+// key sets, so that keys can be audited without access to their Secrets. The
+// SealingKeys of a named key set carry its label.
+type sealingKeyPublisher struct {
+	client  ssv1alpha1client.SealingKeysGetter
+	keySets *keySetRegistries
+}
+
+// Run publishes the SealingKeys every sealingKeyPublishPeriod until ctx is done.
+func (p *sealingKeyPublisher) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.publish(ctx); err != nil {
+			slog.Error("Failed to publish SealingKeys", "error", err)
+		}
+	}, sealingKeyPublishPeriod)
+}
+
+// publish creates or updates the SealingKey of every key of the registries,
+// and deletes the ones of keys which are gone. SealingKeys edited by anyone
+// else are overwritten.
+func (p *sealingKeyPublisher) publish(ctx context.Context) error {
+	statuses := map[string]*ssv1alpha1.SealingKeyStatus{}
+	keySetOf := map[string]string{}
+	for _, kr := range p.keySets.all() {
+		for name, status := range kr.sealingKeyStatuses() {
+			statuses[name] = status
+			keySetOf[name] = kr.keySetName
+		}
+	}
+	namespace := p.keySets.defaultSet.namespace
+
+	client := p.client.SealingKeys()
+	selector := labels.SelectorFromSet(labels.Set{SealingKeyNamespaceLabel: namespace})
+	list, err := client.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	existing := map[string]*ssv1alpha1.SealingKey{}
+	for i := range list.Items {
+		existing[list.Items[i].Name] = &list.Items[i]
+	}
+
+	var errs []error
+	for name, status := range statuses {
+		sk, ok := existing[name]
+		if !ok {
+			labels := map[string]string{SealingKeyNamespaceLabel: namespace}
+			if keySet := keySetOf[name]; keySet != "" {
+				labels[SealedSecretsKeySetLabel] = keySet
+			}
+			sk, err = client.Create(ctx, &ssv1alpha1.SealingKey{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: labels,
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if sk.Status != nil && apiequality.Semantic.DeepEqual(*sk.Status, *status) {
+			continue
+		}
+		sk.Status = status
+		if _, err := client.UpdateStatus(ctx, sk, metav1.UpdateOptions{}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for name := range existing {
+		if _, ok := statuses[name]; ok {
+			continue
+		}
+		if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sealingKeyStatuses returns the status of the SealingKey of each key, by
+// SealingKey name. This method can be called by another goroutine.
+func (kr *KeyRegistry) sealingKeyStatuses() map[string]*ssv1alpha1.SealingKeyStatus {
+	kr.Lock()
+	defer kr.Unlock()
+
+	decrypted := map[string]int{}
+	for _, fingerprints := range kr.decryptedBy {
+		for _, fp := range fingerprints {
+			decrypted[fp]++
+		}
+	}
+
+	keys := kr.snapshot()
+	statuses := make(map[string]*ssv1alpha1.SealingKeyStatus, len(keys.keys))
+	for _, k := range keys.keys {
+		statuses[sealingKeyName(kr.namespace, k)] = &ssv1alpha1.SealingKeyStatus{
+			KeyName:                k.name,
+			Fingerprint:            k.fingerprint,
+			NotBefore:              metav1.NewTime(k.cert.NotBefore),
+			NotAfter:               metav1.NewTime(k.cert.NotAfter),
+			OrderingTime:           metav1.NewTime(k.orderingTime),
+			State:                  string(k.state),
+			Sealing:                k == keys.sealingKey,
+			DecryptedSealedSecrets: decrypted[k.fingerprint],
+		}
+	}
+	return statuses
+}
+
+// sealingKeyName returns the name of the SealingKey of a key: the namespace
+// of the controller followed by the name of its Secret, or by a name derived
+// from its fingerprint if that doesn't make a valid name, e.g. for the IDs of
+// some key management plugins. SealingKeys are cluster-scoped, so the
+// namespace keeps controllers in different namespaces apart.
+func sealingKeyName(namespace string, k *Key) string {
+	if name := namespace + "." + k.name; len(validation.IsDNS1123Subdomain(name)) == 0 {
+		return name
+	}
+	sum := sha256.Sum256([]byte(k.fingerprint))
+	return namespace + ".key-" + hex.EncodeToString(sum[:8])
+}
+
+// decryptingKeys returns the fingerprints of the keys which decrypt the
+// values of a SealedSecret, as far as the ciphertexts tell.
+func decryptingKeys(ss *ssv1alpha1.SealedSecret, keyRegistry *KeyRegistry) []string {
+	keys := keyRegistry.snapshot()
+	var fingerprints []string
+	seen := map[string]bool{}
+	for _, ciphertext := range ciphertextsOf(ss) {
+		candidates, err := crypto.SealingKeyFingerprints(ciphertext)
+		if err != nil {
+			continue
+		}
+		for _, fp := range candidates {
+			if k, ok := keys.keys[fp]; !ok || k.state == KeyStateRetired {
+				continue
+			}
+			if !seen[fp] {
+				seen[fp] = true
+				fingerprints = append(fingerprints, fp)
+			}
+			break
+		}
+	}
+	return fingerprints
+}
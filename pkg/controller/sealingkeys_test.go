@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+)
+
+func TestPublishSealingKeys(t *testing.T) {
+	ctx := context.Background()
+	const keySize = 2048
+	kr := NewKeyRegistry(nil, "namespace", "prefix", "label", KeyTypeRSA, keySize)
+	var certs []*x509.Certificate
+	for i, name := range []string{"sealed-secrets-key1", "sealed-secrets-key2"} {
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, keySize, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kr.registerNewKey(name, key, []*x509.Certificate{cert}, time.Unix(int64(i), 0), KeyStateActive, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("temporal")},
+	}
+	ssecret, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, certs[0].PublicKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kr.recordDecryption("default/ss", decryptingKeys(ssecret, kr))
+
+	ssc := ssfake.NewSimpleClientset()
+	p := &sealingKeyPublisher{client: ssc.BitnamiV1alpha1(), keySets: newKeySetRegistries(kr)}
+	if err := p.publish(ctx); err != nil {
+		t.Fatalf("publish() returned error: %v", err)
+	}
+
+	sk, err := ssc.BitnamiV1alpha1().SealingKeys().Get(ctx, "namespace.sealed-secrets-key1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sk.Labels[SealingKeyNamespaceLabel], "namespace"; got != want {
+		t.Errorf("got namespace label %q, want %q", got, want)
+	}
+	if sk.Status == nil {
+		t.Fatalf("SealingKey has no status")
+	}
+	if got, want := sk.Status.DecryptedSealedSecrets, 1; got != want {
+		t.Errorf("got %d decrypted SealedSecrets, want %d", got, want)
+	}
+	if sk.Status.Sealing || sk.Status.State != string(KeyStateActive) || !sk.Status.NotAfter.Time.Equal(certs[0].NotAfter) {
+		t.Errorf("unexpected status %+v", sk.Status)
+	}
+
+	// Edits are overwritten, and SealingKeys of keys which are gone deleted.
+	sk.Status.State = string(KeyStateRetired)
+	if _, err := ssc.BitnamiV1alpha1().SealingKeys().UpdateStatus(ctx, sk, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	kr.unregisterKey(kr.snapshot().sealingKey.fingerprint)
+	kr.recordDecryption("default/ss", nil)
+	if err := p.publish(ctx); err != nil {
+		t.Fatalf("publish() returned error: %v", err)
+	}
+
+	list, err := ssc.BitnamiV1alpha1().SealingKeys().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(list.Items), 1; got != want {
+		t.Fatalf("got %d SealingKeys, want %d", got, want)
+	}
+	sk = &list.Items[0]
+	if sk.Name != "namespace.sealed-secrets-key1" || sk.Status.State != string(KeyStateActive) || sk.Status.DecryptedSealedSecrets != 0 {
+		t.Errorf("unexpected SealingKey %s: %+v", sk.Name, sk.Status)
+	}
+}
+
+func TestSealingKeyName(t *testing.T) {
+	if got, want := sealingKeyName("kube-system", &Key{name: "sealed-secrets-keyabcde"}), "kube-system.sealed-secrets-keyabcde"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got := sealingKeyName("kube-system", &Key{name: "arn:aws:kms:eu-west-1:123456789012:key/abcd", fingerprint: "SHA256:abc"})
+	if len(got) != len("kube-system.key-")+16 {
+		t.Errorf("got %q, want a name derived from the fingerprint", got)
+	}
+}
+
+func TestPublishSealingKeysSeveralControllers(t *testing.T) {
+	ctx := context.Background()
+	ssc := ssfake.NewSimpleClientset()
+	var publishers []*sealingKeyPublisher
+	for _, ns := range []string{"team-a", "team-b"} {
+		kr := NewKeyRegistry(nil, ns, "prefix", "label", KeyTypeRSA, 2048)
+		key, cert, err := generatePrivateKeyAndCert(KeyTypeRSA, 2048, time.Hour, "my-cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Both controllers use the default key prefix.
+		if err := kr.registerNewKey("sealed-secrets-keyabcde", key, []*x509.Certificate{cert}, time.Unix(0, 0), KeyStateActive, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		publishers = append(publishers, &sealingKeyPublisher{client: ssc.BitnamiV1alpha1(), keySets: newKeySetRegistries(kr)})
+	}
+	for range 2 {
+		for _, p := range publishers {
+			if err := p.publish(ctx); err != nil {
+				t.Fatalf("publish() returned error: %v", err)
+			}
+		}
+	}
+
+	list, err := ssc.BitnamiV1alpha1().SealingKeys().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(list.Items), 2; got != want {
+		t.Fatalf("got %d SealingKeys, want %d", got, want)
+	}
+	for _, sk := range list.Items {
+		if got, want := sk.Name, sk.Labels[SealingKeyNamespaceLabel]+".sealed-secrets-keyabcde"; got != want {
+			t.Errorf("got SealingKey %q, want %q", got, want)
+		}
+	}
+}
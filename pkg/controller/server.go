@@ -3,6 +3,7 @@ package controller
 import (
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"io"
 	"log"
 	"log/slog"
@@ -14,6 +15,7 @@ import (
 	flag "github.com/spf13/pflag"
 	"github.com/throttled/throttled"
 	"github.com/throttled/throttled/store/memstore"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	certUtil "k8s.io/client-go/util/cert"
 )
 
@@ -24,8 +26,10 @@ var (
 	writeTimeout      = flag.Duration("write-timeout", 2*time.Minute, "HTTP response timeout.")
 )
 
-// Called on every request to /cert.  Errors will be logged and return a 500.
-type certProvider func() ([]*x509.Certificate, error)
+// Called on every request to /cert, with the namespace query parameter if
+// any, which selects the key set.  Errors will be logged and return a 500.
+// No certificates result in a 404.
+type certProvider func(namespace string) ([]*x509.Certificate, error)
 type secretChecker func([]byte) (bool, error)
 type secretRotator func([]byte) ([]byte, error)
 
@@ -33,7 +37,8 @@ type secretRotator func([]byte) ([]byte, error)
 // or secret rotation and validation. This endpoint is designed to be accessible by
 // all users of a given cluster. It must not leak any secret material.
 // The server is started in the background and a handle to it returned so it can be shut down.
-func httpserver(cp certProvider, sc secretChecker, sr secretRotator, burst int, rate int) *http.Server {
+// The upcoming certificate, if any, is served at /v1/cert-upcoming.pem.
+func httpserver(cp, ucp certProvider, sc secretChecker, sr secretRotator, burst int, rate int) *http.Server {
 	httpRateLimiter := rateLimiter(burst, rate)
 
 	mux := http.NewServeMux()
@@ -89,19 +94,8 @@ func httpserver(cp certProvider, sc secretChecker, sr secretRotator, burst int,
 		_, _ = w.Write(newSecret)
 	})))
 
-	mux.Handle("/v1/cert.pem", Instrument("/v1/cert.pem", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		certs, err := cp()
-		if err != nil {
-			slog.Error("cannot get certificates", "error", err)
-			http.Error(w, "cannot get certificate", http.StatusInternalServerError)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/x-pem-file")
-		for _, cert := range certs {
-			_, _ = w.Write(pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw}))
-		}
-	})))
+	mux.Handle("/v1/cert.pem", Instrument("/v1/cert.pem", certHandler(cp)))
+	mux.Handle("/v1/cert-upcoming.pem", Instrument("/v1/cert-upcoming.pem", certHandler(ucp)))
 
 	server := http.Server{
 		Addr:              *listenAddr,
@@ -119,6 +113,35 @@ func httpserver(cp certProvider, sc secretChecker, sr secretRotator, burst int,
 	return &server
 }
 
+func certHandler(cp certProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		certs, err := cp(r.URL.Query().Get("namespace"))
+		if errors.Is(err, ErrCertExpired) {
+			slog.Error("cannot serve certificate", "error", err)
+			http.Error(w, "the certificate has expired", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, ErrUnknownKeySet) || k8serrors.IsNotFound(err) {
+			http.Error(w, "no certificate for this namespace", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			slog.Error("cannot get certificates", "error", err)
+			http.Error(w, "cannot get certificate", http.StatusInternalServerError)
+			return
+		}
+		if len(certs) == 0 {
+			http.Error(w, "no certificate", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		for _, cert := range certs {
+			_, _ = w.Write(pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw}))
+		}
+	})
+}
+
 func httpserverMetrics() *http.Server {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
@@ -16,15 +16,34 @@ import (
 
 type testCertStore struct {
 	sync.Mutex
-	cert *x509.Certificate
+	cert     *x509.Certificate
+	upcoming *x509.Certificate
+	// byNamespace holds the certificates of the namespaces with their own key set.
+	byNamespace map[string]*x509.Certificate
 }
 
-func (c *testCertStore) getCert() ([]*x509.Certificate, error) {
+func (c *testCertStore) getCert(namespace string) ([]*x509.Certificate, error) {
 	c.Lock()
 	defer c.Unlock()
+	if namespace != "" {
+		cert, ok := c.byNamespace[namespace]
+		if !ok {
+			return nil, ErrUnknownKeySet
+		}
+		return []*x509.Certificate{cert}, nil
+	}
 	return []*x509.Certificate{c.cert}, nil
 }
 
+func (c *testCertStore) getUpcomingCert(string) ([]*x509.Certificate, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.upcoming == nil {
+		return nil, nil
+	}
+	return []*x509.Certificate{c.upcoming}, nil
+}
+
 func (c *testCertStore) setCert(cert *x509.Certificate) {
 	c.Lock()
 	defer c.Unlock()
@@ -41,18 +60,18 @@ func shutdownServer(server *http.Server, t *testing.T) {
 func TestHttpCert(t *testing.T) {
 	validFor := time.Hour
 	cn := "my-cn"
-	_, certBefore, err := generatePrivateKeyAndCert(2048, validFor, cn)
+	_, certBefore, err := generatePrivateKeyAndCert(KeyTypeRSA, 2048, validFor, cn)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, certAfter, err := generatePrivateKeyAndCert(2048, validFor, cn)
+	_, certAfter, err := generatePrivateKeyAndCert(KeyTypeRSA, 2048, validFor, cn)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	cs := &testCertStore{}
-	server := httpserver(cs.getCert, nil, nil, 2, 2)
+	server := httpserver(cs.getCert, cs.getUpcomingCert, nil, nil, 2, 2)
 	defer shutdownServer(server, t)
 	hp := *listenAddr
 	if strings.HasPrefix(hp, ":") {
@@ -61,36 +80,62 @@ func TestHttpCert(t *testing.T) {
 
 	time.Sleep(1 * time.Second) // TODO(mkm) find a better way, e.g. retries
 
-	check := func(cert *x509.Certificate) {
-		resp, err := http.Get(fmt.Sprintf("http://%s/v1/cert.pem", hp))
-		if err != nil {
-			t.Fatal(err)
-		}
+	cs.setCert(certBefore)
+	checkCert(t, hp, "/v1/cert.pem", certBefore)
 
-		if got, want := resp.StatusCode, http.StatusOK; got != want {
-			t.Fatalf("got: %v, want: %v", got, want)
-		}
-		defer resp.Body.Close()
+	cs.setCert(certAfter)
+	checkCert(t, hp, "/v1/cert.pem", certAfter)
 
-		b, err := io.ReadAll(resp.Body)
-		if err != nil {
-			t.Fatal(err)
-		}
-		certs, err := certUtil.ParseCertsPEM(b)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if got, want := len(certs), 1; got != want {
-			t.Fatalf("got: %v, want: %v", got, want)
-		}
-		if got, want := certs[0], cert; !got.Equal(want) {
-			t.Fatalf("got: %v, want: %v", got, want)
-		}
+	resp, err := http.Get(fmt.Sprintf("http://%s/v1/cert-upcoming.pem", hp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("got status %d without an upcoming certificate, want %d", got, want)
 	}
+	cs.Lock()
+	cs.upcoming = certBefore
+	cs.byNamespace = map[string]*x509.Certificate{"team-a": certBefore}
+	cs.Unlock()
+	checkCert(t, hp, "/v1/cert-upcoming.pem", certBefore)
 
-	cs.setCert(certBefore)
-	check(certBefore)
+	// The namespace selects the certificate of its key set.
+	checkCert(t, hp, "/v1/cert.pem?namespace=team-a", certBefore)
+	resp, err = http.Get(fmt.Sprintf("http://%s/v1/cert.pem?namespace=team-b", hp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("got status %d for a namespace of an unknown key set, want %d", got, want)
+	}
+}
 
-	cs.setCert(certAfter)
-	check(certAfter)
+func checkCert(t *testing.T, hp, path string, cert *x509.Certificate) {
+	t.Helper()
+	resp, err := http.Get(fmt.Sprintf("http://%s%s", hp, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := certUtil.ParseCertsPEM(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(certs), 1; got != want {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	if got, want := certs[0], cert; !got.Equal(want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
 }
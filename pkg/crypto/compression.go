@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is an algorithm to compress plaintexts with before encrypting them.
+type Compression byte
+
+const (
+	// CompressionNone leaves plaintexts as they are.
+	CompressionNone Compression = iota
+	// CompressionGzip compresses plaintexts with gzip.
+	CompressionGzip
+	// CompressionZstd compresses plaintexts with Zstandard.
+	CompressionZstd
+)
+
+// MaxDecompressedBytes bounds the size of a decompressed plaintext, which guards
+// against decompression bombs. Nothing larger would fit in a Kubernetes Secret.
+const MaxDecompressedBytes = 1 << 20
+
+var (
+	// ErrUnsupportedCompression indicates a compression algorithm this binary doesn't know about.
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+
+	// ErrDecompressedTooLarge indicates a compressed plaintext exceeds MaxDecompressedBytes.
+	ErrDecompressedTooLarge = fmt.Errorf("decompressed data exceeds %d bytes", MaxDecompressedBytes)
+)
+
+var compressionNames = map[Compression]string{
+	CompressionNone: "none",
+	CompressionGzip: "gzip",
+	CompressionZstd: "zstd",
+}
+
+// ParseCompression parses the name of a compression algorithm, as returned by String.
+func ParseCompression(s string) (Compression, error) {
+	for c, name := range compressionNames {
+		if name == s {
+			return c, nil
+		}
+	}
+	return CompressionNone, fmt.Errorf("%w: %q, must be one of none, gzip, zstd", ErrUnsupportedCompression, s)
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Compression(%d)", byte(c))
+}
+
+// Set implements pflag.Value.
+func (c *Compression) Set(s string) error {
+	parsed, err := ParseCompression(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Type implements pflag.Value.
+func (c *Compression) Type() string { return "string" }
+
+// compress compresses plaintext with c. If that doesn't make it any smaller,
+// the plaintext is returned as is along with CompressionNone.
+func compress(c Compression, plaintext []byte) (Compression, []byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c {
+	case CompressionNone:
+		return CompressionNone, plaintext, nil
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(&buf, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(MaxDecompressedBytes))
+		if err != nil {
+			return CompressionNone, nil, err
+		}
+		w = zw
+	default:
+		return CompressionNone, nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, c)
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return CompressionNone, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return CompressionNone, nil, err
+	}
+	if buf.Len() >= len(plaintext) {
+		return CompressionNone, plaintext, nil
+	}
+	return c, buf.Bytes(), nil
+}
+
+// decompress reverses compress, refusing to produce more than MaxDecompressedBytes.
+func decompress(c Compression, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch c {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case CompressionZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(MaxDecompressedBytes))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, c)
+	}
+
+	plaintext, err := io.ReadAll(io.LimitReader(r, MaxDecompressedBytes+1))
+	if errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, ErrDecompressedTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) > MaxDecompressedBytes {
+		return nil, ErrDecompressedTooLarge
+	}
+	return plaintext, nil
+}
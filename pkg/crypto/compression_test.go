@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 2)
+	label := []byte("myns/myname")
+
+	var recipients Recipients
+	for _, key := range keys {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients = append(recipients, pubKey)
+	}
+
+	compressible := bytes.Repeat([]byte("-----BEGIN CERTIFICATE-----\n"), 100)
+	incompressible := make([]byte, 256)
+	if _, err := rand.Read(incompressible); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd} {
+		for _, pubKey := range []crypto.PublicKey{recipients[0], recipients} {
+			ciphertext, err := HybridEncryptWithOptions(rand, pubKey, compressible, label, EncryptOptions{Compression: compression})
+			if err != nil {
+				t.Fatalf("HybridEncryptWithOptions() returned error: %v", err)
+			}
+			if len(ciphertext) >= len(compressible) {
+				t.Errorf("%s: ciphertext of %d bytes isn't smaller than the plaintext", compression, len(ciphertext))
+			}
+			env, err := parseEnvelope(ciphertext)
+			if err != nil {
+				t.Fatalf("parseEnvelope() returned error: %v", err)
+			}
+			if got, want := env.compression, compression; got != want {
+				t.Errorf("got compression %s, want %s", got, want)
+			}
+
+			got, err := HybridDecrypt(rand, keys, ciphertext, label)
+			if err != nil {
+				t.Fatalf("HybridDecrypt() returned error: %v", err)
+			}
+			if !bytes.Equal(got, compressible) {
+				t.Errorf("%s: decrypted plaintext doesn't match", compression)
+			}
+
+			// The compression algorithm is authenticated.
+			tampered := bytes.Clone(ciphertext)
+			tampered[2] = byte(CompressionGzip + CompressionZstd - compression)
+			if _, err := HybridDecrypt(rand, keys, tampered, label); err == nil {
+				t.Errorf("%s: HybridDecrypt() succeeded with a different compression", compression)
+			}
+		}
+
+		// Compression is skipped if it doesn't help.
+		ciphertext, err := HybridEncryptWithOptions(rand, recipients[0], incompressible, label, EncryptOptions{Compression: compression})
+		if err != nil {
+			t.Fatalf("HybridEncryptWithOptions() returned error: %v", err)
+		}
+		if ciphertext[1]&envelopeCompressed != 0 {
+			t.Errorf("%s: incompressible plaintext was compressed", compression)
+		}
+	}
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 1)
+	var pubKey crypto.PublicKey
+	for _, key := range keys {
+		pubKey, _ = PublicKey(key)
+	}
+
+	bomb := make([]byte, 2*MaxDecompressedBytes)
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd} {
+		ciphertext, err := HybridEncryptWithOptions(rand, pubKey, bomb, nil, EncryptOptions{Compression: compression})
+		if err != nil {
+			t.Fatalf("HybridEncryptWithOptions() returned error: %v", err)
+		}
+		if _, err := HybridDecrypt(rand, keys, ciphertext, nil); !errors.Is(err, ErrDecompressedTooLarge) || !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: got error %v, want %v", compression, err, ErrDecompressedTooLarge)
+		}
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		got, err := ParseCompression(c.String())
+		if err != nil {
+			t.Fatalf("ParseCompression(%q) returned error: %v", c, err)
+		}
+		if got != c {
+			t.Errorf("got %s, want %s", got, c)
+		}
+	}
+	if _, err := ParseCompression("lz4"); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedCompression)
+	}
+}
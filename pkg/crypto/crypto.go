@@ -1,107 +1,504 @@
 package crypto
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 
 	"golang.org/x/crypto/ssh"
 )
 
 const (
 	sessionKeyBytes = 32
+
+	// envelopeMagic is the first byte of a versioned ciphertext envelope.
+	// Legacy ciphertexts start with the big-endian length of the RSA ciphertext,
+	// whose high byte can never reach this value for any usable RSA key size.
+	envelopeMagic byte = 0xa5
+
+	// envelopeV1 is the first envelope version, which names the recipient key
+	// by its fingerprint. How the session key is wrapped depends on the type of
+	// that key.
+	envelopeV1 byte = 1
+
+	// envelopeV2 wraps the session key for a post-quantum hybrid key: both the
+	// classical key and ML-KEM-768 contribute to it, see pqWrap.
+	envelopeV2 byte = 2
+
+	// envelopeMulti wraps the session key for several recipients, see Recipients.
+	envelopeMulti byte = 3
+
+	// envelopeCompressed is set on the version of envelopes whose plaintext was
+	// compressed. The Compression algorithm follows the version byte.
+	envelopeCompressed byte = 0x80
 )
 
-// ErrTooShort indicates the provided data is too short to be valid.
-var ErrTooShort = errors.New("SealedSecret data is too short")
+var (
+	// ErrTooShort indicates the provided data is too short to be valid.
+	ErrTooShort = errors.New("SealedSecret data is too short")
 
-// PublicKeyFingerprint returns a fingerprint for a public key.
-func PublicKeyFingerprint(rp *rsa.PublicKey) (string, error) {
-	sp, err := ssh.NewPublicKey(rp)
+	// ErrUnsupportedEnvelope indicates the ciphertext uses an envelope version this binary doesn't know about.
+	ErrUnsupportedEnvelope = errors.New("unsupported SealedSecret envelope version")
+
+	// ErrLegacyAdditionalData indicates additional data was expected for a legacy ciphertext, which cannot carry any.
+	ErrLegacyAdditionalData = errors.New("legacy SealedSecret data cannot be bound to additional data")
+
+	// ErrUnsupportedKey indicates a key that is neither an RSA nor an EC key.
+	ErrUnsupportedKey = errors.New("unsupported key type")
+
+	// ErrAuthentication indicates SealedSecret data which failed to authenticate
+	// with the key it was opened with: it was sealed for another key, label or
+	// additional data, or was tampered with.
+	ErrAuthentication = errors.New("SealedSecret data failed to authenticate")
+
+	// ErrMalformed indicates SealedSecret data which no key can decrypt, e.g.
+	// because it is truncated or its plaintext doesn't decompress.
+	ErrMalformed = errors.New("malformed SealedSecret data")
+)
+
+// malformed marks an error as being caused by ErrMalformed data.
+func malformed(err error) error {
+	return fmt.Errorf("%w: %w", ErrMalformed, err)
+}
+
+// UnknownKeyError is returned when a ciphertext names sealing keys none of
+// which is among the available private keys. Legacy ciphertexts don't name
+// them, and yield no Fingerprints when there's no RSA key to try.
+type UnknownKeyError struct {
+	Fingerprints []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	switch len(e.Fingerprints) {
+	case 0:
+		return "none of the available private keys is an RSA key"
+	case 1:
+		return fmt.Sprintf("sealed for key %s, which is not among the available private keys", e.Fingerprints[0])
+	}
+	return fmt.Sprintf("sealed for keys %s, none of which is among the available private keys", strings.Join(e.Fingerprints, ", "))
+}
+
+// PublicKeyFingerprint returns a fingerprint for an RSA or EC public key.
+// Post-quantum hybrid keys share the fingerprint of their classical key.
+func PublicKeyFingerprint(pub crypto.PublicKey) (string, error) {
+	if pq, ok := pub.(*PQPublicKey); ok {
+		pub = pq.Classical
+	}
+	sp, err := ssh.NewPublicKey(pub)
 	if err != nil {
 		return "", err
 	}
 	return ssh.FingerprintSHA256(sp), nil
 }
 
-// HybridEncrypt performs a regular AES-GCM + RSA-OAEP encryption.
+// PublicKey returns the public key matching an RSA, EC or post-quantum hybrid private key.
+// RSA keys may also be held elsewhere (e.g. in a KMS) behind a crypto.Decrypter.
+func PublicKey(priv crypto.PrivateKey) (crypto.PublicKey, error) {
+	switch k := priv.(type) {
+	case *PQPrivateKey:
+		classical, err := PublicKey(k.Classical)
+		if err != nil {
+			return nil, err
+		}
+		return &PQPublicKey{Classical: classical, MLKEM: k.MLKEM.EncapsulationKey()}, nil
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	case crypto.Decrypter:
+		if pub, ok := k.Public().(*rsa.PublicKey); ok {
+			return pub, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, priv)
+}
+
+// HybridEncrypt performs an AES-GCM encryption with a single-use session key,
+// which is wrapped for pubKey using RSA-OAEP for RSA keys, ECIES for EC keys, or
+// additionally ML-KEM-768 for post-quantum hybrid keys.
 // The output byte string is:
 //
-//	RSA ciphertext length || RSA ciphertext || AES ciphertext
-func HybridEncrypt(rnd io.Reader, pubKey *rsa.PublicKey, plaintext, label []byte) ([]byte, error) {
-	// Generate a random symmetric key
-	sessionKey := make([]byte, sessionKeyBytes)
-	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+//	magic || version || fingerprint length || fingerprint || wrapped key length || wrapped key || AES ciphertext
+//
+// where the fingerprint is the PublicKeyFingerprint of pubKey. Everything preceding
+// the AES ciphertext is authenticated as AES-GCM additional data.
+//
+// If pubKey is a Recipients list, the session key is wrapped for each of them instead.
+func HybridEncrypt(rnd io.Reader, pubKey crypto.PublicKey, plaintext, label []byte) ([]byte, error) {
+	return HybridEncryptWithOptions(rnd, pubKey, plaintext, label, EncryptOptions{})
+}
+
+// EncryptOptions tunes HybridEncryptWithOptions.
+type EncryptOptions struct {
+	// AdditionalData is authenticated along with the ciphertext but not part
+	// of it. The same additional data must be passed to HybridDecryptWithAD.
+	AdditionalData []byte
+
+	// Compression compresses the plaintext before encrypting it, unless that
+	// doesn't make it smaller. HybridDecrypt decompresses it transparently.
+	Compression Compression
+}
+
+// HybridEncryptWithOptions is like HybridEncrypt, tuned by opts.
+func HybridEncryptWithOptions(rnd io.Reader, pubKey crypto.PublicKey, plaintext, label []byte, opts EncryptOptions) ([]byte, error) {
+	compression, plaintext, err := compress(opts.Compression, plaintext)
+	if err != nil {
 		return nil, err
 	}
 
-	block, err := aes.NewCipher(sessionKey)
+	if recipients, ok := pubKey.(Recipients); ok {
+		if len(recipients) != 1 {
+			return multiEncrypt(rnd, recipients, plaintext, label, compression, opts.AdditionalData)
+		}
+		pubKey = recipients[0]
+	}
+
+	version, fingerprint, sessionKey, wrappedKey, err := wrap(rnd, pubKey, label)
 	if err != nil {
 		return nil, err
 	}
 
-	aed, err := cipher.NewGCM(block)
+	ciphertext := appendSlot(envelopeHeader(version, compression), fingerprint, wrappedKey)
+
+	aesCiphertext, err := aesSeal(sessionKey, plaintext, slices.Concat(ciphertext, opts.AdditionalData))
+	if err != nil {
+		return nil, err
+	}
+
+	// Append symmetrically encrypted Secret
+	return append(ciphertext, aesCiphertext...), nil
+}
+
+// HybridDecrypt reverses HybridEncrypt.
+// The private keys map has a fingerprint of each public key as the map key.
+func HybridDecrypt(rnd io.Reader, privKeys map[string]crypto.PrivateKey, ciphertext, label []byte) ([]byte, error) {
+	return HybridDecryptWithAD(rnd, privKeys, ciphertext, label, nil)
+}
+
+// HybridDecryptWithAD reverses HybridEncryptWithOptions. Legacy ciphertexts cannot
+// authenticate additional data, so they are rejected unless additionalData is empty.
+func HybridDecryptWithAD(rnd io.Reader, privKeys map[string]crypto.PrivateKey, ciphertext, label, additionalData []byte) ([]byte, error) {
+	if isEnvelope(ciphertext) {
+		env, err := parseEnvelope(ciphertext)
+		if err != nil {
+			return nil, malformed(err)
+		}
+		var unknown []string
+		var openErr error
+		for _, s := range env.slots {
+			privKey, ok := privKeys[s.fingerprint]
+			if !ok {
+				unknown = append(unknown, s.fingerprint)
+				continue
+			}
+			secret, err := env.open(rnd, s, privKey, label, additionalData)
+			if err != nil {
+				// Another key held for the secret may still open it. Failing
+				// to authenticate is the least telling error.
+				if openErr == nil || errors.Is(openErr, ErrAuthentication) {
+					openErr = fmt.Errorf("no key could decrypt secret (sealed for key %s): %w", s.fingerprint, err)
+				}
+				continue
+			}
+			plaintext, err := decompress(env.compression, secret)
+			if err != nil {
+				return nil, malformed(err)
+			}
+			return plaintext, nil
+		}
+		if openErr != nil {
+			return nil, openErr
+		}
+		return nil, &UnknownKeyError{Fingerprints: unknown}
+	}
+
+	if len(additionalData) > 0 {
+		return nil, ErrLegacyAdditionalData
+	}
+
+	// Legacy ciphertexts don't tell which key sealed them, so try all of them.
+	// They always used RSA-OAEP.
+	var decryptErr error
+	for _, privKey := range privKeys {
+		rsaKey, ok := rsaDecrypter(classicalKey(privKey))
+		if !ok {
+			continue
+		}
+		secret, err := singleDecrypt(rnd, rsaKey, ciphertext, label)
+		if err == nil {
+			return secret, nil
+		}
+		if decryptErr == nil || errors.Is(decryptErr, ErrAuthentication) {
+			decryptErr = err
+		}
+	}
+	if decryptErr != nil {
+		return nil, fmt.Errorf("no key could decrypt secret: %w", decryptErr)
+	}
+	return nil, fmt.Errorf("no key could decrypt secret: %w", &UnknownKeyError{})
+}
+
+// SealingKeyFingerprints returns the fingerprints of the keys a ciphertext was
+// sealed for. Legacy ciphertexts don't record them, and yield none.
+func SealingKeyFingerprints(ciphertext []byte) ([]string, error) {
+	if !isEnvelope(ciphertext) {
+		return nil, nil
+	}
+	env, err := parseEnvelope(ciphertext)
 	if err != nil {
 		return nil, err
 	}
+	fingerprints := make([]string, 0, len(env.slots))
+	for _, s := range env.slots {
+		fingerprints = append(fingerprints, s.fingerprint)
+	}
+	return fingerprints, nil
+}
+
+// CiphertextCompression returns the compression algorithm the plaintext of a
+// ciphertext was compressed with. Legacy ciphertexts are never compressed.
+func CiphertextCompression(ciphertext []byte) (Compression, error) {
+	if !isEnvelope(ciphertext) {
+		return CompressionNone, nil
+	}
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return CompressionNone, err
+	}
+	return env.compression, nil
+}
+
+// wrap generates a session key and wraps it for pubKey. It returns the matching
+// envelope version and the fingerprint of pubKey along with it.
+func wrap(rnd io.Reader, pubKey crypto.PublicKey, label []byte) (version byte, fingerprint string, sessionKey, wrappedKey []byte, err error) {
+	fingerprint, err = PublicKeyFingerprint(pubKey)
+	if err != nil {
+		return 0, "", nil, nil, err
+	}
 
-	// Encrypt symmetric key
-	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rnd, pubKey, sessionKey, label)
+	version = envelopeV1
+	switch k := pubKey.(type) {
+	case *PQPublicKey:
+		version = envelopeV2
+		sessionKey, wrappedKey, err = pqWrap(rnd, k, label)
+	case *rsa.PublicKey:
+		sessionKey, wrappedKey, err = rsaWrap(rnd, k, label)
+	case *ecdsa.PublicKey:
+		sessionKey, wrappedKey, err = ecWrap(rnd, k, label)
+	default:
+		err = fmt.Errorf("%w: %T", ErrUnsupportedKey, pubKey)
+	}
+	if err != nil {
+		return 0, "", nil, nil, err
+	}
+	return version, fingerprint, sessionKey, wrappedKey, nil
+}
+
+// isEnvelope reports whether the ciphertext uses the versioned envelope format
+// rather than the legacy length-prefixed one.
+func isEnvelope(ciphertext []byte) bool {
+	return len(ciphertext) > 0 && ciphertext[0] == envelopeMagic
+}
+
+// envelopeHeader returns magic || version, with the compression algorithm if there is one.
+func envelopeHeader(version byte, compression Compression) []byte {
+	if compression == CompressionNone {
+		return []byte{envelopeMagic, version}
+	}
+	return []byte{envelopeMagic, version | envelopeCompressed, byte(compression)}
+}
+
+// envelope is a parsed versioned ciphertext.
+type envelope struct {
+	version       byte
+	compression   Compression
+	slots         []slot
+	aesCiphertext []byte
+	header        []byte
+}
+
+// slot is the session key wrapped for one recipient key.
+type slot struct {
+	version     byte
+	fingerprint string
+	wrappedKey  []byte
+}
+
+func parseEnvelope(ciphertext []byte) (*envelope, error) {
+	if len(ciphertext) < 2 {
+		return nil, ErrTooShort
+	}
+	env := &envelope{version: ciphertext[1]}
+	rest := ciphertext[2:]
+
+	compressed := env.version&envelopeCompressed != 0
+	if compressed {
+		if len(rest) < 1 {
+			return nil, ErrTooShort
+		}
+		env.version &^= envelopeCompressed
+		env.compression = Compression(rest[0])
+		rest = rest[1:]
+	}
+
+	var err error
+	switch env.version {
+	case envelopeV1, envelopeV2:
+		var s slot
+		s, rest, err = parseSlot(env.version, rest)
+		env.slots = []slot{s}
+	case envelopeMulti:
+		env.slots, rest, err = parseMultiSlots(rest)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedEnvelope, env.version)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := compressionNames[env.compression]; !ok || (compressed && env.compression == CompressionNone) {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedCompression, env.compression)
+	}
+
+	headerLen := len(ciphertext) - len(rest)
+	env.header = ciphertext[:headerLen]
+	env.aesCiphertext = rest
+	return env, nil
+}
 
-	// First 2 bytes are RSA ciphertext length, so we can separate
-	// all the pieces later.
-	ciphertext := make([]byte, 2)
+// appendSlot appends fingerprint length || fingerprint || wrapped key length || wrapped key.
+func appendSlot(b []byte, fingerprint string, wrappedKey []byte) []byte {
+	// #nosec G115 -- fingerprints are short fixed-size strings
+	b = append(b, byte(len(fingerprint)))
+	b = append(b, fingerprint...)
 	// #nosec G115
-	binary.BigEndian.PutUint16(ciphertext, uint16(len(rsaCiphertext)))
-	ciphertext = append(ciphertext, rsaCiphertext...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(wrappedKey)))
+	return append(b, wrappedKey...)
+}
 
-	// SessionKey is only used once, so zero nonce is ok
-	zeroNonce := make([]byte, aed.NonceSize())
+// parseSlot reverses appendSlot.
+func parseSlot(version byte, b []byte) (slot, []byte, error) {
+	if len(b) < 1 {
+		return slot{}, nil, ErrTooShort
+	}
+	fpLen := int(b[0])
+	b = b[1:]
+	if len(b) < fpLen+2 {
+		return slot{}, nil, ErrTooShort
+	}
+	fingerprint := string(b[:fpLen])
+	b = b[fpLen:]
 
-	// Append symmetrically encrypted Secret
-	ciphertext = aed.Seal(ciphertext, zeroNonce, plaintext, nil)
+	wrappedLen := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < wrappedLen {
+		return slot{}, nil, ErrTooShort
+	}
+	return slot{version: version, fingerprint: fingerprint, wrappedKey: b[:wrappedLen]}, b[wrappedLen:], nil
+}
 
-	return ciphertext, nil
+func (e *envelope) open(rnd io.Reader, s slot, privKey crypto.PrivateKey, label, additionalData []byte) ([]byte, error) {
+	var sessionKey []byte
+	var err error
+	if e.version == envelopeMulti {
+		sessionKey, err = openMultiSlot(rnd, s, privKey, label)
+	} else {
+		sessionKey, err = unwrapSession(rnd, s.version, privKey, s.wrappedKey, label)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return aesOpen(sessionKey, e.aesCiphertext, slices.Concat(e.header, additionalData))
 }
 
-// HybridDecrypt performs a regular AES-GCM + RSA-OAEP decryption.
-// The private keys map has a fingerprint of each public key as the map key.
-func HybridDecrypt(rnd io.Reader, privKeys map[string]*rsa.PrivateKey, ciphertext, label []byte) ([]byte, error) {
-	// TODO(mkm): use the key fingerprint encoded in ciphertext (if present) instead of trying all the possible keys
-	for _, privKey := range privKeys {
-		if secret, err := singleDecrypt(rnd, privKey, ciphertext, label); err == nil {
-			return secret, nil
+// unwrapSession recovers a session key wrapped by wrap.
+func unwrapSession(rnd io.Reader, version byte, privKey crypto.PrivateKey, wrappedKey, label []byte) ([]byte, error) {
+	if version == envelopeV2 {
+		pq, ok := privKey.(*PQPrivateKey)
+		if !ok {
+			return nil, ErrNoMLKEMKey
 		}
+		return pqUnwrap(rnd, pq, wrappedKey, label)
+	}
+	return unwrap(rnd, classicalKey(privKey), wrappedKey, label)
+}
+
+// unwrap recovers a session key wrapped for a classical RSA or EC key.
+func unwrap(rnd io.Reader, privKey crypto.PrivateKey, wrappedKey, label []byte) ([]byte, error) {
+	if k, ok := privKey.(*ecdsa.PrivateKey); ok {
+		return ecUnwrap(k, wrappedKey, label)
+	}
+	if k, ok := rsaDecrypter(privKey); ok {
+		return rsaUnwrap(rnd, k, wrappedKey, label)
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, privKey)
+}
+
+// rsaDecrypter returns the private key as a crypto.Decrypter if it is an RSA key,
+// which covers both *rsa.PrivateKey and keys held by an external KMS.
+func rsaDecrypter(privKey crypto.PrivateKey) (crypto.Decrypter, bool) {
+	k, ok := privKey.(crypto.Decrypter)
+	if !ok {
+		return nil, false
+	}
+	_, ok = k.Public().(*rsa.PublicKey)
+	return k, ok
+}
+
+// rsaUnwrap decrypts a session key wrapped with RSA-OAEP.
+func rsaUnwrap(rnd io.Reader, privKey crypto.Decrypter, wrappedKey, label []byte) ([]byte, error) {
+	sessionKey, err := privKey.Decrypt(rnd, wrappedKey, &rsa.OAEPOptions{Hash: crypto.SHA256, Label: label})
+	if errors.Is(err, rsa.ErrDecryption) {
+		return nil, ErrAuthentication
+	}
+	return sessionKey, err
+}
+
+// rsaWrap generates a random session key and encrypts it with RSA-OAEP.
+func rsaWrap(rnd io.Reader, pubKey *rsa.PublicKey, label []byte) (sessionKey, wrappedKey []byte, err error) {
+	sessionKey = make([]byte, sessionKeyBytes)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		return nil, nil, err
+	}
+
+	wrappedKey, err = rsa.EncryptOAEP(sha256.New(), rnd, pubKey, sessionKey, label)
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil, fmt.Errorf("no key could decrypt secret")
+	return sessionKey, wrappedKey, nil
 }
 
-// singleDecrypt performs a regular AES-GCM + RSA-OAEP decryption.
-func singleDecrypt(rnd io.Reader, privKey *rsa.PrivateKey, ciphertext, label []byte) ([]byte, error) {
+// singleDecrypt performs a regular AES-GCM + RSA-OAEP decryption of a legacy ciphertext.
+func singleDecrypt(rnd io.Reader, privKey crypto.Decrypter, ciphertext, label []byte) ([]byte, error) {
 	if len(ciphertext) < 2 {
-		return nil, ErrTooShort
+		return nil, malformed(ErrTooShort)
 	}
 	rsaLen := int(binary.BigEndian.Uint16(ciphertext))
 	if len(ciphertext) < rsaLen+2 {
-		return nil, ErrTooShort
+		return nil, malformed(ErrTooShort)
 	}
 
 	rsaCiphertext := ciphertext[2 : rsaLen+2]
 	aesCiphertext := ciphertext[rsaLen+2:]
 
-	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rnd, privKey, rsaCiphertext, label)
+	sessionKey, err := rsaUnwrap(rnd, privKey, rsaCiphertext, label)
 	if err != nil {
 		return nil, err
 	}
 
+	return aesOpen(sessionKey, aesCiphertext, nil)
+}
+
+// aesSeal encrypts plaintext with AES-GCM under a single-use session key.
+func aesSeal(sessionKey, plaintext, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher(sessionKey)
 	if err != nil {
 		return nil, err
@@ -112,13 +509,30 @@ func singleDecrypt(rnd io.Reader, privKey *rsa.PrivateKey, ciphertext, label []b
 		return nil, err
 	}
 
-	// Key is only used once, so zero nonce is ok
+	// SessionKey is only used once, so zero nonce is ok
 	zeroNonce := make([]byte, aed.NonceSize())
 
-	plaintext, err := aed.Open(nil, zeroNonce, aesCiphertext, nil)
+	return aed.Seal(nil, zeroNonce, plaintext, additionalData), nil
+}
+
+// aesOpen decrypts an AES-GCM ciphertext produced by aesSeal.
+func aesOpen(sessionKey, ciphertext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(sessionKey)
 	if err != nil {
 		return nil, err
 	}
 
+	aed, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Key is only used once, so zero nonce is ok
+	zeroNonce := make([]byte, aed.NonceSize())
+
+	plaintext, err := aed.Open(nil, zeroNonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
 	return plaintext, nil
 }
@@ -0,0 +1,251 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func generateTestKeys(t *testing.T, rand io.Reader, n int) map[string]crypto.PrivateKey {
+	t.Helper()
+	keys := map[string]crypto.PrivateKey{}
+	for i := 0; i < n; i++ {
+		key, err := rsa.GenerateKey(rand, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate test key: %v", err)
+		}
+		fp, err := PublicKeyFingerprint(&key.PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to generate fingerprint: %v", err)
+		}
+		keys[fp] = key
+	}
+	return keys
+}
+
+func generateTestECKey(t *testing.T, rand io.Reader) (string, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand)
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+	fp, err := PublicKeyFingerprint(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to generate fingerprint: %v", err)
+	}
+	return fp, key
+}
+
+// legacyEncrypt produces the pre-envelope length-prefixed ciphertext format.
+func legacyEncrypt(t *testing.T, rnd io.Reader, pubKey *rsa.PublicKey, plaintext, label []byte) []byte {
+	t.Helper()
+	sessionKey := make([]byte, sessionKeyBytes)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		t.Fatal(err)
+	}
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rnd, pubKey, sessionKey, label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// #nosec G115
+	ciphertext := binary.BigEndian.AppendUint16(nil, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+	aesCiphertext, err := aesSeal(sessionKey, plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(ciphertext, aesCiphertext...)
+}
+
+func TestHybridRoundTrip(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 3)
+	plaintext := []byte("s3cr3t")
+	label := []byte("myns/myname")
+
+	ecFP, ecKey := generateTestECKey(t, rand)
+	keys[ecFP] = ecKey
+
+	for fp, key := range keys {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatalf("PublicKey() returned error: %v", err)
+		}
+		ciphertext, err := HybridEncrypt(rand, pubKey, plaintext, label)
+		if err != nil {
+			t.Fatalf("HybridEncrypt() returned error: %v", err)
+		}
+		if !isEnvelope(ciphertext) {
+			t.Fatalf("HybridEncrypt() didn't produce a versioned envelope")
+		}
+		env, err := parseEnvelope(ciphertext)
+		if err != nil {
+			t.Fatalf("parseEnvelope() returned error: %v", err)
+		}
+		if got, want := env.slots[0].fingerprint, fp; got != want {
+			t.Errorf("got fingerprint %q, want %q", got, want)
+		}
+
+		got, err := HybridDecrypt(rand, keys, ciphertext, label)
+		if err != nil {
+			t.Fatalf("HybridDecrypt() returned error: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+
+		if _, err := HybridDecrypt(rand, keys, ciphertext, []byte("otherns/myname")); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("got error %v with the wrong label, want %v", err, ErrAuthentication)
+		}
+	}
+}
+
+func TestHybridDecryptLegacy(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 2)
+	plaintext := []byte("s3cr3t")
+	label := []byte("myns/myname")
+
+	for _, key := range keys {
+		ciphertext := legacyEncrypt(t, rand, &key.(*rsa.PrivateKey).PublicKey, plaintext, label)
+		if isEnvelope(ciphertext) {
+			t.Fatalf("legacy ciphertext mistaken for a versioned envelope")
+		}
+
+		got, err := HybridDecrypt(rand, keys, ciphertext, label)
+		if err != nil {
+			t.Fatalf("HybridDecrypt() returned error: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+
+		if _, err := HybridDecrypt(rand, keys, ciphertext[:1], label); !errors.Is(err, ErrMalformed) {
+			t.Errorf("got error %v for truncated data, want %v", err, ErrMalformed)
+		}
+
+		// Without any RSA key, nothing can decrypt it.
+		fp, ecKey := generateTestECKey(t, rand)
+		var unknownKey *UnknownKeyError
+		if _, err := HybridDecrypt(rand, map[string]crypto.PrivateKey{fp: ecKey}, ciphertext, label); !errors.As(err, &unknownKey) {
+			t.Errorf("got error %v with EC keys only, want an UnknownKeyError", err)
+		}
+	}
+}
+
+func TestHybridAdditionalData(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 2)
+	plaintext := []byte("s3cr3t")
+	label := []byte("myns/myname")
+	ad := []byte("template digest")
+
+	var recipients Recipients
+	for _, key := range keys {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients = append(recipients, pubKey)
+	}
+
+	for _, pubKey := range []crypto.PublicKey{recipients[0], recipients} {
+		ciphertext, err := HybridEncryptWithOptions(rand, pubKey, plaintext, label, EncryptOptions{AdditionalData: ad})
+		if err != nil {
+			t.Fatalf("HybridEncryptWithOptions() returned error: %v", err)
+		}
+		got, err := HybridDecryptWithAD(rand, keys, ciphertext, label, ad)
+		if err != nil {
+			t.Fatalf("HybridDecryptWithAD() returned error: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+
+		if _, err := HybridDecryptWithAD(rand, keys, ciphertext, label, []byte("other digest")); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("got error %v with the wrong additional data, want %v", err, ErrAuthentication)
+		}
+		if _, err := HybridDecrypt(rand, keys, ciphertext, label); err == nil {
+			t.Errorf("HybridDecrypt() succeeded without the additional data")
+		}
+	}
+
+	legacy := legacyEncrypt(t, rand, recipients[0].(*rsa.PublicKey), plaintext, label)
+	if _, err := HybridDecryptWithAD(rand, keys, legacy, label, ad); !errors.Is(err, ErrLegacyAdditionalData) {
+		t.Errorf("got error %v, want %v", err, ErrLegacyAdditionalData)
+	}
+}
+
+func TestHybridDecryptUnknownKey(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 1)
+
+	key, err := rsa.GenerateKey(rand, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+	fp, err := PublicKeyFingerprint(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to generate fingerprint: %v", err)
+	}
+
+	ciphertext, err := HybridEncrypt(rand, &key.PublicKey, []byte("s3cr3t"), nil)
+	if err != nil {
+		t.Fatalf("HybridEncrypt() returned error: %v", err)
+	}
+
+	_, err = HybridDecrypt(rand, keys, ciphertext, nil)
+	var unknown *UnknownKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("got error %v, want an UnknownKeyError", err)
+	}
+	if len(unknown.Fingerprints) != 1 || unknown.Fingerprints[0] != fp {
+		t.Errorf("got fingerprints %q, want [%q]", unknown.Fingerprints, fp)
+	}
+}
+
+func TestHybridDecryptMalformedEnvelope(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 1)
+
+	for _, key := range keys {
+		ciphertext, err := HybridEncrypt(rand, &key.(*rsa.PrivateKey).PublicKey, []byte("s3cr3t"), nil)
+		if err != nil {
+			t.Fatalf("HybridEncrypt() returned error: %v", err)
+		}
+
+		unsupported := bytes.Clone(ciphertext)
+		unsupported[1] = 0xff
+		if _, err := HybridDecrypt(rand, keys, unsupported, nil); !errors.Is(err, ErrUnsupportedEnvelope) || !errors.Is(err, ErrMalformed) {
+			t.Errorf("got error %v, want %v", err, ErrUnsupportedEnvelope)
+		}
+
+		if _, err := HybridDecrypt(rand, keys, ciphertext[:10], nil); !errors.Is(err, ErrTooShort) || !errors.Is(err, ErrMalformed) {
+			t.Errorf("got error %v, want %v", err, ErrTooShort)
+		}
+	}
+}
+
+func TestHybridECWrongKey(t *testing.T) {
+	rand := testRand()
+	fp, key := generateTestECKey(t, rand)
+	_, other := generateTestECKey(t, rand)
+
+	ciphertext, err := HybridEncrypt(rand, &key.PublicKey, []byte("s3cr3t"), nil)
+	if err != nil {
+		t.Fatalf("HybridEncrypt() returned error: %v", err)
+	}
+
+	// Same fingerprint, different key: the ECDH shared secret won't match.
+	keys := map[string]crypto.PrivateKey{fp: other}
+	if _, err := HybridDecrypt(rand, keys, ciphertext, nil); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("got error %v with the wrong EC key, want %v", err, ErrAuthentication)
+	}
+}
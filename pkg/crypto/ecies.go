@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"io"
+)
+
+// eciesInfo is the HKDF context string used to derive session keys for EC recipients.
+const eciesInfo = "sealed-secrets ECIES AES-256-GCM"
+
+// ecWrap derives a single-use session key for an EC recipient from an ephemeral
+// ECDH key pair (ECIES). The wrapped key is the ephemeral public key, which lets the
+// recipient derive the same session key with its private key.
+func ecWrap(rnd io.Reader, pubKey *ecdsa.PublicKey, label []byte) (sessionKey, wrappedKey []byte, err error) {
+	recipient, err := pubKey.ECDH()
+	if err != nil {
+		return nil, nil, err
+	}
+	ephemeral, err := recipient.Curve().GenerateKey(rnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrappedKey = ephemeral.PublicKey().Bytes()
+	sessionKey, err = eciesKDF(shared, wrappedKey, recipient.Bytes(), label)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sessionKey, wrappedKey, nil
+}
+
+// ecUnwrap derives the session key produced by ecWrap.
+func ecUnwrap(privKey *ecdsa.PrivateKey, wrappedKey, label []byte) ([]byte, error) {
+	priv, err := privKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := priv.Curve().NewPublicKey(wrappedKey)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	shared, err := priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	return eciesKDF(shared, wrappedKey, priv.PublicKey().Bytes(), label)
+}
+
+// eciesKDF binds the session key to both public keys and to the label, which
+// plays the same role as the RSA-OAEP label: unsealing yields a different key,
+// and thus fails, unless the same label is used.
+func eciesKDF(shared, ephemeral, recipient, label []byte) ([]byte, error) {
+	salt := append(bytes.Clone(ephemeral), recipient...)
+	return hkdf.Key(sha256.New, shared, salt, eciesInfo+"\x00"+string(label), sessionKeyBytes)
+}
@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"crypto"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EscrowPEMType is the PEM block type of an escrowed sealing key.
+const EscrowPEMType = "SEALED SECRETS ESCROWED KEY"
+
+// escrowKeyHeader names the escrowed key in the PEM headers.
+const escrowKeyHeader = "Key"
+
+// SealEscrow encrypts backup, the backup of the sealing key called name, for
+// escrowKey, which can be a Recipients list. It returns a PEM block.
+func SealEscrow(r io.Reader, escrowKey crypto.PublicKey, name string, backup []byte) ([]byte, error) {
+	ciphertext, err := HybridEncrypt(r, escrowKey, backup, escrowLabel(name))
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:    EscrowPEMType,
+		Headers: map[string]string{escrowKeyHeader: name},
+		Bytes:   ciphertext,
+	}), nil
+}
+
+// OpenEscrow decrypts an escrowed key block with one of the offline privKeys,
+// and returns the name and backup of the key.
+func OpenEscrow(r io.Reader, privKeys map[string]crypto.PrivateKey, block *pem.Block) (string, []byte, error) {
+	if block.Type != EscrowPEMType {
+		return "", nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+	}
+	name := block.Headers[escrowKeyHeader]
+	if name == "" {
+		return "", nil, errors.New("escrowed key has no name")
+	}
+	backup, err := HybridDecrypt(r, privKeys, block.Bytes, escrowLabel(name))
+	if err != nil {
+		return "", nil, fmt.Errorf("escrowed key %s: %w", name, err)
+	}
+	return name, backup, nil
+}
+
+// escrowLabel binds an escrowed key to its name, so that entries cannot be swapped.
+func escrowLabel(name string) []byte {
+	return []byte("sealed-secrets escrow\x00" + name)
+}
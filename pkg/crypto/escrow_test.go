@@ -0,0 +1,45 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/pem"
+	"testing"
+)
+
+func TestEscrowRoundTrip(t *testing.T) {
+	rand := testRand()
+	offline := generateTestKeys(t, rand, 1)
+	var escrowKey crypto.PublicKey
+	for _, key := range offline {
+		escrowKey, _ = PublicKey(key)
+	}
+	backup := []byte(`{"kind":"Secret"}`)
+
+	data, err := SealEscrow(rand, escrowKey, "sealed-secrets-keyabcde", backup)
+	if err != nil {
+		t.Fatalf("SealEscrow() returned error: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("SealEscrow() didn't return a PEM block")
+	}
+
+	name, got, err := OpenEscrow(rand, offline, block)
+	if err != nil {
+		t.Fatalf("OpenEscrow() returned error: %v", err)
+	}
+	if name != "sealed-secrets-keyabcde" || !bytes.Equal(got, backup) {
+		t.Errorf("got %s: %q, want sealed-secrets-keyabcde: %q", name, got, backup)
+	}
+
+	// The ciphertext is bound to the key name.
+	block.Headers[escrowKeyHeader] = "sealed-secrets-keyfghij"
+	if _, _, err := OpenEscrow(rand, offline, block); err == nil {
+		t.Errorf("OpenEscrow() succeeded with a different key name")
+	}
+
+	if _, _, err := OpenEscrow(rand, generateTestKeys(t, rand, 1), block); err == nil {
+		t.Errorf("OpenEscrow() succeeded with another private key")
+	}
+}
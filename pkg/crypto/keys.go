@@ -1,10 +1,14 @@
 package crypto
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"fmt"
 	"io"
 	"math/big"
 	"time"
@@ -24,27 +28,73 @@ func GeneratePrivateKeyAndCert(keySize int, validFor time.Duration, cn string) (
 	return privKey, cert, nil
 }
 
-// SignKey returns a signed certificate.
-func SignKey(r io.Reader, key *rsa.PrivateKey, validFor time.Duration, cn string) (*x509.Certificate, error) {
-	// TODO: use certificates API to get this signed by the cluster root CA
-	// See https://kubernetes.io/docs/tasks/tls/managing-tls-in-a-cluster/
+// GenerateECPrivateKeyAndCert generates an EC P-256 keypair and signed certificate.
+func GenerateECPrivateKeyAndCert(validFor time.Duration, cn string) (*ecdsa.PrivateKey, *x509.Certificate, error) {
+	r := rand.Reader
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), r)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := SignKey(r, privKey, validFor, cn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privKey, cert, nil
+}
 
+// SignKey returns a self-signed certificate. See CreateCertificateRequest to
+// have the key certified by a CA instead.
+func SignKey(r io.Reader, key crypto.Signer, validFor time.Duration, cn string) (*x509.Certificate, error) {
 	return SignKeyWithNotBefore(r, key, time.Now(), validFor, cn)
 }
 
 // SignKeyWithNotBefore returns a signed certificate with custom notBefore.
-func SignKeyWithNotBefore(r io.Reader, key *rsa.PrivateKey, notBefore time.Time, validFor time.Duration, cn string) (*x509.Certificate, error) {
-	// TODO: use certificates API to get this signed by the cluster root CA
-	// See https://kubernetes.io/docs/tasks/tls/managing-tls-in-a-cluster/
+func SignKeyWithNotBefore(r io.Reader, key crypto.Signer, notBefore time.Time, validFor time.Duration, cn string) (*x509.Certificate, error) {
+	return signKey(r, key, notBefore, validFor, cn, nil)
+}
 
+// CreateCertificateRequest returns a DER encoded certificate signing request for
+// key, suitable for the Kubernetes certificates API. The ML-KEM-768 key of a
+// PQPrivateKey is requested as an extension, which the signer may or may not honour.
+func CreateCertificateRequest(r io.Reader, key crypto.PrivateKey, cn string) ([]byte, error) {
+	var extensions []pkix.Extension
+	if pq, ok := key.(*PQPrivateKey); ok {
+		ext, err := mlkemExtension(pq)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, ext)
+		key = pq.Classical
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+
+	template := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName: cn,
+		},
+		ExtraExtensions: extensions,
+	}
+	return x509.CreateCertificateRequest(r, &template, signer)
+}
+
+func signKey(r io.Reader, key crypto.Signer, notBefore time.Time, validFor time.Duration, cn string, extensions []pkix.Extension) (*x509.Certificate, error) {
 	serialNo, err := rand.Int(r, new(big.Int).Lsh(big.NewInt(1), 128))
 	if err != nil {
 		return nil, err
 	}
 
+	keyUsage := x509.KeyUsageEncipherOnly
+	if _, ok := key.Public().(*ecdsa.PublicKey); ok {
+		// EC keys only take part in ECDH key agreement.
+		keyUsage = x509.KeyUsageKeyAgreement
+	}
+
 	cert := x509.Certificate{
 		SerialNumber: serialNo,
-		KeyUsage:     x509.KeyUsageEncipherOnly,
+		KeyUsage:     keyUsage,
 		NotBefore:    notBefore.UTC(),
 		NotAfter:     notBefore.Add(validFor).UTC(),
 		Issuer: pkix.Name{
@@ -55,9 +105,10 @@ func SignKeyWithNotBefore(r io.Reader, key *rsa.PrivateKey, notBefore time.Time,
 		},
 		BasicConstraintsValid: true,
 		IsCA:                  true,
+		ExtraExtensions:       extensions,
 	}
 
-	data, err := x509.CreateCertificate(r, &cert, &cert, &key.PublicKey, key)
+	data, err := x509.CreateCertificate(r, &cert, &cert, key.Public(), key)
 	if err != nil {
 		return nil, err
 	}
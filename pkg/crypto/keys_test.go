@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"crypto/rsa"
+	"crypto/x509"
 	"io"
 	mathrand "math/rand"
 	"reflect"
@@ -31,3 +32,41 @@ func TestSignKey(t *testing.T) {
 		t.Errorf("cert pubkey != original pubkey")
 	}
 }
+
+func TestCreateCertificateRequest(t *testing.T) {
+	rand := testRand()
+
+	classical, err := rsa.GenerateKey(rand, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+	key, _, err := GeneratePQPrivateKeyAndCert(classical, time.Hour, "mycn")
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+
+	der, err := CreateCertificateRequest(rand, key, "mycn")
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest() returned error: %v", err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Errorf("invalid CSR signature: %v", err)
+	}
+	if got, want := csr.Subject.CommonName, "mycn"; got != want {
+		t.Errorf("got CN %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(csr.PublicKey, &classical.PublicKey) {
+		t.Errorf("CSR pubkey != original pubkey")
+	}
+	requested := false
+	for _, ext := range csr.Extensions {
+		requested = requested || ext.Id.Equal(oidSubjectAltPublicKeyInfo)
+	}
+	if !requested {
+		t.Errorf("CSR doesn't request the ML-KEM key")
+	}
+}
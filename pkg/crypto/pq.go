@@ -0,0 +1,233 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// MLKEMPrivateKeyBlockType is the PEM block type of an ML-KEM-768 private key,
+	// stored as its 64 byte seed.
+	MLKEMPrivateKeyBlockType = "ML-KEM-768 PRIVATE KEY"
+
+	// MLKEMSecretKey is the data key holding the ML-KEM-768 private key in a
+	// sealing key Secret, next to tls.key and tls.crt.
+	MLKEMSecretKey = "mlkem768.key"
+
+	// pqInfo is the HKDF context string used to combine the classical and ML-KEM shares.
+	pqInfo = "sealed-secrets hybrid ML-KEM-768 AES-256-GCM"
+)
+
+var (
+	// oidSubjectAltPublicKeyInfo is the X.509 extension carrying an alternative
+	// public key of the subject (ITU-T X.509 (10/2019) section 9.8).
+	oidSubjectAltPublicKeyInfo = asn1.ObjectIdentifier{2, 5, 29, 72}
+	// oidMLKEM768 is id-alg-ml-kem-768 from the NIST algorithm registry.
+	oidMLKEM768 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+
+	// ErrNoMLKEMKey indicates a post-quantum hybrid ciphertext for a key that has no ML-KEM key material.
+	ErrNoMLKEMKey = errors.New("sealed with ML-KEM-768, but the private key has no ML-KEM key")
+)
+
+// PQPublicKey is a classical (RSA or EC) public key paired with an ML-KEM-768
+// encapsulation key. Session keys sealed for it are only recoverable by breaking
+// both.
+type PQPublicKey struct {
+	Classical crypto.PublicKey
+	MLKEM     *mlkem.EncapsulationKey768
+}
+
+// PQPrivateKey is the private counterpart of PQPublicKey. It also decrypts
+// secrets sealed for the classical key alone.
+type PQPrivateKey struct {
+	Classical crypto.PrivateKey
+	MLKEM     *mlkem.DecapsulationKey768
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// GeneratePQPrivateKeyAndCert adds a new ML-KEM-768 key to a classical key pair
+// and returns a certificate advertising both.
+func GeneratePQPrivateKeyAndCert(classical crypto.Signer, validFor time.Duration, cn string) (*PQPrivateKey, *x509.Certificate, error) {
+	dk, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, nil, err
+	}
+	key := &PQPrivateKey{Classical: classical, MLKEM: dk}
+	cert, err := SignPQKeyWithNotBefore(rand.Reader, key, time.Now(), validFor, cn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
+// SignPQKeyWithNotBefore returns a certificate signed by the classical key, which
+// carries the ML-KEM-768 encapsulation key in a SubjectAltPublicKeyInfo extension.
+func SignPQKeyWithNotBefore(r io.Reader, key *PQPrivateKey, notBefore time.Time, validFor time.Duration, cn string) (*x509.Certificate, error) {
+	signer, ok := key.Classical.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key.Classical)
+	}
+	ext, err := mlkemExtension(key)
+	if err != nil {
+		return nil, err
+	}
+	return signKey(r, signer, notBefore, validFor, cn, []pkix.Extension{ext})
+}
+
+// mlkemExtension returns the SubjectAltPublicKeyInfo extension advertising the
+// ML-KEM-768 encapsulation key of key.
+func mlkemExtension(key *PQPrivateKey) (pkix.Extension, error) {
+	spki, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidMLKEM768},
+		PublicKey: asn1.BitString{Bytes: key.MLKEM.EncapsulationKey().Bytes(), BitLength: 8 * mlkem.EncapsulationKeySize768},
+	})
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	return pkix.Extension{Id: oidSubjectAltPublicKeyInfo, Value: spki}, nil
+}
+
+// CertPublicKey returns the public key a certificate advertises for sealing: a
+// PQPublicKey if it carries an ML-KEM-768 key, or else its plain public key.
+func CertPublicKey(cert *x509.Certificate) (crypto.PublicKey, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSubjectAltPublicKeyInfo) {
+			continue
+		}
+		var spki subjectPublicKeyInfo
+		if rest, err := asn1.Unmarshal(ext.Value, &spki); err != nil {
+			return nil, fmt.Errorf("invalid alternative public key: %w", err)
+		} else if len(rest) != 0 {
+			return nil, errors.New("invalid alternative public key: trailing data")
+		}
+		if !spki.Algorithm.Algorithm.Equal(oidMLKEM768) {
+			// Not ours to interpret; seal for the classical key.
+			break
+		}
+		ek, err := mlkem.NewEncapsulationKey768(spki.PublicKey.RightAlign())
+		if err != nil {
+			return nil, fmt.Errorf("invalid ML-KEM-768 public key: %w", err)
+		}
+		return &PQPublicKey{Classical: cert.PublicKey, MLKEM: ek}, nil
+	}
+	return cert.PublicKey, nil
+}
+
+// MarshalMLKEMPrivateKeyPEM encodes the seed of an ML-KEM-768 private key as PEM.
+func MarshalMLKEMPrivateKeyPEM(dk *mlkem.DecapsulationKey768) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: MLKEMPrivateKeyBlockType, Bytes: dk.Bytes()})
+}
+
+// WithMLKEMPrivateKeyPEM pairs a classical private key with the ML-KEM-768
+// private key found among the PEM blocks of data, if any. Otherwise the
+// classical key is returned as is.
+func WithMLKEMPrivateKeyPEM(classical crypto.PrivateKey, data []byte) (crypto.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return classical, nil
+		}
+		if block.Type != MLKEMPrivateKeyBlockType {
+			continue
+		}
+		dk, err := mlkem.NewDecapsulationKey768(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ML-KEM-768 private key: %w", err)
+		}
+		return &PQPrivateKey{Classical: classical, MLKEM: dk}, nil
+	}
+}
+
+// classicalKey strips the ML-KEM part of a post-quantum hybrid private key.
+func classicalKey(priv crypto.PrivateKey) crypto.PrivateKey {
+	if pq, ok := priv.(*PQPrivateKey); ok {
+		return pq.Classical
+	}
+	return priv
+}
+
+// pqWrap wraps a session key for both the classical and the ML-KEM-768 key of
+// pubKey. The wrapped key is:
+//
+//	classical wrapped key length || classical wrapped key || ML-KEM ciphertext
+//
+// ML-KEM encapsulation always draws from crypto/rand.
+func pqWrap(rnd io.Reader, pubKey *PQPublicKey, label []byte) (sessionKey, wrappedKey []byte, err error) {
+	var classicalSecret, classicalWrapped []byte
+	switch k := pubKey.Classical.(type) {
+	case *rsa.PublicKey:
+		classicalSecret, classicalWrapped, err = rsaWrap(rnd, k, label)
+	case *ecdsa.PublicKey:
+		classicalSecret, classicalWrapped, err = ecWrap(rnd, k, label)
+	default:
+		err = fmt.Errorf("%w: %T", ErrUnsupportedKey, pubKey.Classical)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mlkemSecret, mlkemCiphertext := pubKey.MLKEM.Encapsulate()
+
+	// #nosec G115
+	wrappedKey = binary.BigEndian.AppendUint16(nil, uint16(len(classicalWrapped)))
+	wrappedKey = append(wrappedKey, classicalWrapped...)
+	wrappedKey = append(wrappedKey, mlkemCiphertext...)
+
+	sessionKey, err = pqKDF(classicalSecret, mlkemSecret, mlkemCiphertext, pubKey.MLKEM.Bytes(), label)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sessionKey, wrappedKey, nil
+}
+
+// pqUnwrap recovers the session key produced by pqWrap.
+func pqUnwrap(rnd io.Reader, privKey *PQPrivateKey, wrappedKey, label []byte) ([]byte, error) {
+	if len(wrappedKey) < 2 {
+		return nil, malformed(ErrTooShort)
+	}
+	classicalLen := int(binary.BigEndian.Uint16(wrappedKey))
+	if len(wrappedKey) != 2+classicalLen+mlkem.CiphertextSize768 {
+		return nil, malformed(ErrTooShort)
+	}
+	classicalWrapped := wrappedKey[2 : 2+classicalLen]
+	mlkemCiphertext := wrappedKey[2+classicalLen:]
+
+	classicalSecret, err := unwrap(rnd, privKey.Classical, classicalWrapped, label)
+	if err != nil {
+		return nil, err
+	}
+	mlkemSecret, err := privKey.MLKEM.Decapsulate(mlkemCiphertext)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	return pqKDF(classicalSecret, mlkemSecret, mlkemCiphertext, privKey.MLKEM.EncapsulationKey().Bytes(), label)
+}
+
+// pqKDF combines both shares so that the session key stays secret as long as
+// either of them does. The ML-KEM ciphertext and encapsulation key are bound in
+// the salt, the label in the context string.
+func pqKDF(classicalSecret, mlkemSecret, mlkemCiphertext, encapsulationKey, label []byte) ([]byte, error) {
+	secret := append(bytes.Clone(classicalSecret), mlkemSecret...)
+	salt := append(bytes.Clone(mlkemCiphertext), encapsulationKey...)
+	return hkdf.Key(sha256.New, secret, salt, pqInfo+"\x00"+string(label), sessionKeyBytes)
+}
@@ -0,0 +1,164 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+)
+
+func generateTestPQKeys(t *testing.T) []*PQPrivateKey {
+	t.Helper()
+	rand := testRand()
+	rsaKey, err := rsa.GenerateKey(rand, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand)
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+
+	var keys []*PQPrivateKey
+	for _, classical := range []crypto.Signer{rsaKey, ecKey} {
+		key, _, err := GeneratePQPrivateKeyAndCert(classical, time.Hour, "testcn")
+		if err != nil {
+			t.Fatalf("GeneratePQPrivateKeyAndCert() returned error: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestCertPublicKey(t *testing.T) {
+	rand := testRand()
+	key, err := rsa.GenerateKey(rand, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate test key: %v", err)
+	}
+
+	plain, err := SignKey(rand, key, time.Hour, "testcn")
+	if err != nil {
+		t.Fatalf("SignKey() returned error: %v", err)
+	}
+	if pub, err := CertPublicKey(plain); err != nil {
+		t.Fatalf("CertPublicKey() returned error: %v", err)
+	} else if _, ok := pub.(*rsa.PublicKey); !ok {
+		t.Errorf("got %T, want *rsa.PublicKey", pub)
+	}
+
+	pqKey, pqCert, err := GeneratePQPrivateKeyAndCert(key, time.Hour, "testcn")
+	if err != nil {
+		t.Fatalf("GeneratePQPrivateKeyAndCert() returned error: %v", err)
+	}
+	pub, err := CertPublicKey(pqCert)
+	if err != nil {
+		t.Fatalf("CertPublicKey() returned error: %v", err)
+	}
+	pqPub, ok := pub.(*PQPublicKey)
+	if !ok {
+		t.Fatalf("got %T, want *PQPublicKey", pub)
+	}
+	if !bytes.Equal(pqPub.MLKEM.Bytes(), pqKey.MLKEM.EncapsulationKey().Bytes()) {
+		t.Errorf("certificate advertises the wrong ML-KEM key")
+	}
+
+	got, err := PublicKeyFingerprint(pqPub)
+	if err != nil {
+		t.Fatalf("PublicKeyFingerprint() returned error: %v", err)
+	}
+	want, err := PublicKeyFingerprint(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("PublicKeyFingerprint() returned error: %v", err)
+	}
+	if got != want {
+		t.Errorf("got fingerprint %q, want the classical key's %q", got, want)
+	}
+}
+
+func TestPQHybridRoundTrip(t *testing.T) {
+	rand := testRand()
+	plaintext := []byte("s3cr3t")
+	label := []byte("myns/myname")
+
+	for _, key := range generateTestPQKeys(t) {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatalf("PublicKey() returned error: %v", err)
+		}
+		fp, err := PublicKeyFingerprint(pubKey)
+		if err != nil {
+			t.Fatalf("PublicKeyFingerprint() returned error: %v", err)
+		}
+		keys := map[string]crypto.PrivateKey{fp: key}
+
+		ciphertext, err := HybridEncrypt(rand, pubKey, plaintext, label)
+		if err != nil {
+			t.Fatalf("HybridEncrypt() returned error: %v", err)
+		}
+		if got, want := ciphertext[1], envelopeV2; got != want {
+			t.Errorf("got envelope version %d, want %d", got, want)
+		}
+
+		got, err := HybridDecrypt(rand, keys, ciphertext, label)
+		if err != nil {
+			t.Fatalf("HybridDecrypt() returned error: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+
+		if _, err := HybridDecrypt(rand, keys, ciphertext, []byte("otherns/myname")); err == nil {
+			t.Errorf("HybridDecrypt() succeeded with the wrong label")
+		}
+
+		classicalOnly := map[string]crypto.PrivateKey{fp: key.Classical}
+		if _, err := HybridDecrypt(rand, classicalOnly, ciphertext, label); !errors.Is(err, ErrNoMLKEMKey) {
+			t.Errorf("got error %v, want %v", err, ErrNoMLKEMKey)
+		}
+
+		// Secrets sealed for the classical key alone keep working.
+		classicalPub, err := PublicKey(key.Classical)
+		if err != nil {
+			t.Fatalf("PublicKey() returned error: %v", err)
+		}
+		ciphertext, err = HybridEncrypt(rand, classicalPub, plaintext, label)
+		if err != nil {
+			t.Fatalf("HybridEncrypt() returned error: %v", err)
+		}
+		if got, err := HybridDecrypt(rand, keys, ciphertext, label); err != nil {
+			t.Errorf("HybridDecrypt() returned error: %v", err)
+		} else if !bytes.Equal(got, plaintext) {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestMLKEMPrivateKeyPEM(t *testing.T) {
+	key := generateTestPQKeys(t)[0]
+	data := MarshalMLKEMPrivateKeyPEM(key.MLKEM)
+
+	got, err := WithMLKEMPrivateKeyPEM(key.Classical, data)
+	if err != nil {
+		t.Fatalf("WithMLKEMPrivateKeyPEM() returned error: %v", err)
+	}
+	pq, ok := got.(*PQPrivateKey)
+	if !ok {
+		t.Fatalf("got %T, want *PQPrivateKey", got)
+	}
+	if !bytes.Equal(pq.MLKEM.Bytes(), key.MLKEM.Bytes()) {
+		t.Errorf("ML-KEM key didn't survive the round trip")
+	}
+
+	got, err = WithMLKEMPrivateKeyPEM(key.Classical, nil)
+	if err != nil {
+		t.Fatalf("WithMLKEMPrivateKeyPEM() returned error: %v", err)
+	}
+	if got != key.Classical {
+		t.Errorf("got %T, want the classical key", got)
+	}
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// wrappedDataKeyBytes is the size of the AES-GCM encrypted data key in each
+// slot of a multi-recipient envelope.
+const wrappedDataKeyBytes = sessionKeyBytes + 16
+
+// ErrDuplicateRecipient is returned when the same key is among the Recipients twice.
+var ErrDuplicateRecipient = errors.New("duplicate recipient key")
+
+// Recipients is a list of public keys to seal for at once. Each of the matching
+// private keys can decrypt the result on its own, and the label applies to each
+// of them just like for a single key.
+type Recipients []crypto.PublicKey
+
+// multiEncrypt encrypts plaintext under a random data key, which is wrapped for
+// each recipient. The output byte string is:
+//
+//	magic || version || recipient count || slot... || AES ciphertext
+//
+// where each slot is:
+//
+//	slot version || fingerprint length || fingerprint || wrapped key length || wrapped key || encrypted data key
+//
+// The slot version and wrapped key are those a single recipient envelope would
+// use; the session key they carry encrypts the data key with AES-GCM instead of
+// the plaintext. Everything preceding the AES ciphertext is authenticated as
+// AES-GCM additional data, followed by the caller's additionalData.
+func multiEncrypt(rnd io.Reader, recipients Recipients, plaintext, label []byte, compression Compression, additionalData []byte) ([]byte, error) {
+	if len(recipients) == 0 || len(recipients) > math.MaxUint8 {
+		return nil, fmt.Errorf("cannot seal for %d recipients, must be between 1 and %d", len(recipients), math.MaxUint8)
+	}
+
+	dataKey := make([]byte, sessionKeyBytes)
+	if _, err := io.ReadFull(rnd, dataKey); err != nil {
+		return nil, err
+	}
+
+	ciphertext := append(envelopeHeader(envelopeMulti, compression), byte(len(recipients)))
+	seen := map[string]bool{}
+	for _, pubKey := range recipients {
+		version, fingerprint, sessionKey, wrappedKey, err := wrap(rnd, pubKey, label)
+		if err != nil {
+			return nil, err
+		}
+		if seen[fingerprint] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateRecipient, fingerprint)
+		}
+		seen[fingerprint] = true
+
+		wrappedDataKey, err := aesSeal(sessionKey, dataKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext = append(ciphertext, version)
+		ciphertext = appendSlot(ciphertext, fingerprint, append(wrappedKey, wrappedDataKey...))
+	}
+
+	aesCiphertext, err := aesSeal(dataKey, plaintext, slices.Concat(ciphertext, additionalData))
+	if err != nil {
+		return nil, err
+	}
+	return append(ciphertext, aesCiphertext...), nil
+}
+
+// parseMultiSlots parses the recipient count and slots of a multi-recipient envelope.
+func parseMultiSlots(b []byte) ([]slot, []byte, error) {
+	if len(b) < 1 {
+		return nil, nil, ErrTooShort
+	}
+	n := int(b[0])
+	b = b[1:]
+
+	slots := make([]slot, 0, n)
+	for range n {
+		if len(b) < 1 {
+			return nil, nil, ErrTooShort
+		}
+		version := b[0]
+		if version != envelopeV1 && version != envelopeV2 {
+			return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedEnvelope, version)
+		}
+		s, rest, err := parseSlot(version, b[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(s.wrappedKey) < wrappedDataKeyBytes {
+			return nil, nil, ErrTooShort
+		}
+		slots = append(slots, s)
+		b = rest
+	}
+	return slots, b, nil
+}
+
+// openMultiSlot recovers the data key of a multi-recipient envelope from the
+// slot of privKey.
+func openMultiSlot(rnd io.Reader, s slot, privKey crypto.PrivateKey, label []byte) ([]byte, error) {
+	split := len(s.wrappedKey) - wrappedDataKeyBytes
+	sessionKey, err := unwrapSession(rnd, s.version, privKey, s.wrappedKey[:split], label)
+	if err != nil {
+		return nil, err
+	}
+	return aesOpen(sessionKey, s.wrappedKey[split:], nil)
+}
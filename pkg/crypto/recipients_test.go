@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"testing"
+)
+
+func TestMultiRecipientRoundTrip(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 2)
+	ecFP, ecKey := generateTestECKey(t, rand)
+	keys[ecFP] = ecKey
+	pqKey := generateTestPQKeys(t)[1]
+	pqPub, err := PublicKey(pqKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pqFP, err := PublicKeyFingerprint(pqPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys[pqFP] = pqKey
+
+	var recipients Recipients
+	for _, key := range keys {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients = append(recipients, pubKey)
+	}
+
+	plaintext := []byte("s3cr3t")
+	label := []byte("myns/myname")
+	ciphertext, err := HybridEncrypt(rand, recipients, plaintext, label)
+	if err != nil {
+		t.Fatalf("HybridEncrypt() returned error: %v", err)
+	}
+	if got, want := ciphertext[1], envelopeMulti; got != want {
+		t.Errorf("got envelope version %d, want %d", got, want)
+	}
+
+	// Each recipient finds its own slot.
+	for fp, key := range keys {
+		own := map[string]crypto.PrivateKey{fp: key}
+		got, err := HybridDecrypt(rand, own, ciphertext, label)
+		if err != nil {
+			t.Fatalf("HybridDecrypt() with key %s returned error: %v", fp, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+
+		if _, err := HybridDecrypt(rand, own, ciphertext, []byte("otherns/myname")); err == nil {
+			t.Errorf("HybridDecrypt() with key %s succeeded with the wrong label", fp)
+		}
+	}
+
+	others := generateTestKeys(t, rand, 1)
+	_, err = HybridDecrypt(rand, others, ciphertext, label)
+	var unknown *UnknownKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("got error %v, want an UnknownKeyError", err)
+	}
+	if got, want := len(unknown.Fingerprints), len(keys); got != want {
+		t.Errorf("got %d fingerprints, want %d", got, want)
+	}
+
+	// The recipient list is authenticated.
+	tampered := bytes.Clone(ciphertext)
+	tampered[2]--
+	if _, err := HybridDecrypt(rand, keys, tampered, label); err == nil {
+		t.Errorf("HybridDecrypt() succeeded with a truncated recipient list")
+	}
+}
+
+func TestMultiRecipientInvalid(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 1)
+
+	var recipients Recipients
+	for _, key := range keys {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients = append(recipients, pubKey)
+	}
+
+	// A single recipient gets a regular envelope.
+	ciphertext, err := HybridEncrypt(rand, recipients, []byte("s3cr3t"), nil)
+	if err != nil {
+		t.Fatalf("HybridEncrypt() returned error: %v", err)
+	}
+	if got, want := ciphertext[1], envelopeV1; got != want {
+		t.Errorf("got envelope version %d, want %d", got, want)
+	}
+
+	if _, err := HybridEncrypt(rand, append(recipients, recipients[0]), nil, nil); !errors.Is(err, ErrDuplicateRecipient) {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateRecipient)
+	}
+
+	if _, err := HybridEncrypt(rand, Recipients{}, nil, nil); err == nil {
+		t.Errorf("HybridEncrypt() succeeded without recipients")
+	}
+}
+
+func TestMultiRecipientFailingSlot(t *testing.T) {
+	rand := testRand()
+	keys := generateTestKeys(t, rand, 2)
+	var fingerprints []string
+	var recipients Recipients
+	for fp, key := range keys {
+		pubKey, err := PublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprints = append(fingerprints, fp)
+		recipients = append(recipients, pubKey)
+	}
+	plaintext := []byte("s3cr3t")
+	ciphertext, err := HybridEncrypt(rand, recipients, plaintext, nil)
+	if err != nil {
+		t.Fatalf("HybridEncrypt() returned error: %v", err)
+	}
+
+	// The first slot fails to open, e.g. because its key is unavailable, but
+	// the second one still decrypts the secret.
+	broken := map[string]crypto.PrivateKey{
+		fingerprints[0]: keys[fingerprints[1]],
+		fingerprints[1]: keys[fingerprints[1]],
+	}
+	got, err := HybridDecrypt(rand, broken, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("HybridDecrypt() returned error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("got %q, want %q", got, plaintext)
+	}
+
+	// If no slot opens, the open error is returned rather than an UnknownKeyError.
+	broken[fingerprints[1]] = keys[fingerprints[0]]
+	_, err = HybridDecrypt(rand, broken, ciphertext, nil)
+	var unknown *UnknownKeyError
+	if err == nil || errors.As(err, &unknown) {
+		t.Errorf("got error %v, want an open error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Shamir's secret sharing over GF(2^8), one polynomial per byte of the secret.
+// A share is the evaluation of all the polynomials at some non-zero x, followed
+// by x itself.
+
+var (
+	// ErrInvalidShares is returned when shares cannot be combined into a secret.
+	ErrInvalidShares = errors.New("invalid secret shares")
+)
+
+// SplitSecret splits secret into n shares, any threshold of which recover it
+// with CombineShares, while fewer reveal nothing about it.
+func SplitSecret(r io.Reader, secret []byte, n, threshold int) ([][]byte, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, fmt.Errorf("cannot split a secret into %d shares with a threshold of %d: need 2 <= threshold <= shares <= 255", n, threshold)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("cannot split an empty secret")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	for i, b := range secret {
+		coefficients[0] = b
+		if _, err := io.ReadFull(r, coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[i] = gfEval(coefficients, share[len(secret)])
+		}
+	}
+	clear(coefficients)
+	return shares, nil
+}
+
+// CombineShares recovers a secret from a quorum of the shares returned by
+// SplitSecret. Combining fewer shares than the threshold silently returns garbage.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 shares are needed", ErrInvalidShares)
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, fmt.Errorf("%w: share too short", ErrInvalidShares)
+	}
+
+	xs := make([]byte, len(shares))
+	seen := map[byte]bool{}
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, fmt.Errorf("%w: shares have different lengths", ErrInvalidShares)
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("%w: duplicate or zero share index %d", ErrInvalidShares, x)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	// Lagrange interpolation at x = 0.
+	secret := make([]byte, size-1)
+	for j, share := range shares {
+		basis := byte(1)
+		for m, x := range xs {
+			if m != j {
+				basis = gfMul(basis, gfMul(x, gfInv(x^xs[j])))
+			}
+		}
+		for i := range secret {
+			secret[i] ^= gfMul(share[i], basis)
+		}
+	}
+	return secret, nil
+}
+
+// gfEval evaluates the polynomial with the given coefficients, constant term first, at x.
+func gfEval(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+// gfMul multiplies in GF(2^8) modulo the AES polynomial, in constant time.
+func gfMul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= -(b & 1) & a
+		a = a<<1 ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a non-zero a, which is a^254.
+func gfInv(a byte) byte {
+	b := gfMul(a, a) // a^2
+	c := gfMul(a, b) // a^3
+	b = gfMul(c, c)  // a^6
+	b = gfMul(b, b)  // a^12
+	c = gfMul(b, c)  // a^15
+	b = gfMul(b, b)  // a^24
+	b = gfMul(b, b)  // a^48
+	b = gfMul(b, c)  // a^63
+	b = gfMul(b, b)  // a^126
+	b = gfMul(a, b)  // a^127
+	return gfMul(b, b)
+}
@@ -0,0 +1,159 @@
+// Package kms lets the controller keep its private keys in an external key
+// management plugin. The plugin speaks the gRPC API in pkg/kms/api/v1 over a
+// Unix socket and unwraps session keys on the controller's behalf, so the
+// private keys never leave it.
+package kms
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	kmsapi "github.com/bitnami-labs/sealed-secrets/pkg/kms/api/v1"
+)
+
+const (
+	// APIVersion is the version of the plugin API implemented by this package.
+	APIVersion = "v1"
+
+	// healthzOK is the healthz value of a healthy plugin.
+	healthzOK = "ok"
+)
+
+// ErrUnsupportedDecrypterOpts is returned for anything but RSA-OAEP with SHA-256.
+var ErrUnsupportedDecrypterOpts = errors.New("KMS keys only support RSA-OAEP with SHA-256")
+
+// Client is a connection to a key management plugin.
+type Client struct {
+	conn    *grpc.ClientConn
+	api     kmsapi.KeyManagementServiceClient
+	timeout time.Duration
+}
+
+// NewClient connects to the plugin listening on endpoint, which must be of the
+// form unix:///path/to/socket. Every call to the plugin is bounded by timeout.
+func NewClient(endpoint string, timeout time.Duration) (*Client, error) {
+	if _, err := socketPath(endpoint); err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn:    conn,
+		api:     kmsapi.NewKeyManagementServiceClient(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// Close closes the connection to the plugin.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Keys returns the keys held by the plugin. It fails unless the plugin is healthy.
+func (c *Client) Keys(ctx context.Context) ([]*Key, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.api.Status(ctx, &kmsapi.StatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Version != APIVersion {
+		return nil, fmt.Errorf("unsupported KMS plugin API version %q, want %q", resp.Version, APIVersion)
+	}
+	if resp.Healthz != healthzOK {
+		return nil, fmt.Errorf("KMS plugin is unhealthy: %s", resp.Healthz)
+	}
+
+	var keys []*Key
+	current := false
+	for _, k := range resp.Keys {
+		cert, err := x509.ParseCertificate(k.Certificate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate for KMS key %q: %w", k.KeyId, err)
+		}
+		if _, ok := cert.PublicKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("KMS key %q is not an RSA key", k.KeyId)
+		}
+		current = current || k.KeyId == resp.KeyId
+		keys = append(keys, &Key{
+			ID:          k.KeyId,
+			Certificate: cert,
+			Current:     k.KeyId == resp.KeyId,
+			client:      c,
+		})
+	}
+	if !current {
+		return nil, fmt.Errorf("KMS plugin didn't return its current key %q", resp.KeyId)
+	}
+	return keys, nil
+}
+
+// Key is an RSA private key held by a key management plugin. It implements
+// crypto.Decrypter, so it can stand in for an *rsa.PrivateKey when unsealing.
+type Key struct {
+	// ID identifies the key towards the plugin.
+	ID string
+	// Certificate is the certificate of the key.
+	Certificate *x509.Certificate
+	// Current is set for the key the plugin wants new secrets sealed for.
+	Current bool
+
+	client *Client
+}
+
+// Public returns the public key of the certificate.
+func (k *Key) Public() gocrypto.PublicKey {
+	return k.Certificate.PublicKey
+}
+
+// Decrypt has the plugin decrypt an RSA-OAEP ciphertext. opts must be an
+// *rsa.OAEPOptions using SHA-256. A ciphertext the plugin rejects as invalid
+// yields crypto.ErrAuthentication, like it would with an *rsa.PrivateKey.
+func (k *Key) Decrypt(_ io.Reader, ciphertext []byte, opts gocrypto.DecrypterOpts) ([]byte, error) {
+	oaep, ok := opts.(*rsa.OAEPOptions)
+	if !ok || oaep.Hash != gocrypto.SHA256 || (oaep.MGFHash != 0 && oaep.MGFHash != gocrypto.SHA256) {
+		return nil, ErrUnsupportedDecrypterOpts
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), k.client.timeout)
+	defer cancel()
+
+	resp, err := k.client.api.Decrypt(ctx, &kmsapi.DecryptRequest{
+		Ciphertext: ciphertext,
+		Uid:        string(uuid.NewUUID()),
+		KeyId:      k.ID,
+		Label:      oaep.Label,
+	})
+	if status.Code(err) == codes.InvalidArgument {
+		return nil, fmt.Errorf("%w: %w", crypto.ErrAuthentication, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+func socketPath(endpoint string) (string, error) {
+	path, ok := strings.CutPrefix(endpoint, "unix://")
+	if !ok || path == "" {
+		return "", fmt.Errorf("invalid KMS plugin endpoint %q, must be of the form unix:///path/to/socket", endpoint)
+	}
+	return path, nil
+}
@@ -0,0 +1,135 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	gocrypto "crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+)
+
+// startPlugin serves plugin on a fresh Unix socket and returns a client for it.
+func startPlugin(t *testing.T, plugin *SoftPlugin) *Client {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir() can exceed.
+	dir, err := os.MkdirTemp("", "kms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "kms.sock")
+	endpoint := "unix://" + path
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		if err := Serve(ctx, endpoint, plugin); err != nil {
+			t.Errorf("Serve() returned error: %v", err)
+		}
+	}()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("plugin didn't start listening on %s", path)
+		}
+	}
+
+	client, err := NewClient(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewClient() returned error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestSoftPluginUnseal(t *testing.T) {
+	plugin := NewSoftPlugin()
+	id, err := plugin.GenerateKey(2048, time.Hour, "testcn")
+	if err != nil {
+		t.Fatalf("GenerateKey() returned error: %v", err)
+	}
+	client := startPlugin(t, plugin)
+
+	keys, err := client.Keys(context.Background())
+	if err != nil {
+		t.Fatalf("Keys() returned error: %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != id || !keys[0].Current {
+		t.Fatalf("got keys %v, want the current key %q", keys, id)
+	}
+	key := keys[0]
+
+	fp, err := crypto.PublicKeyFingerprint(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	privKeys := map[string]gocrypto.PrivateKey{fp: key}
+	plaintext := []byte("s3cr3t")
+	label := []byte("myns/myname")
+
+	ciphertext, err := crypto.HybridEncrypt(rand.Reader, key.Public(), plaintext, label)
+	if err != nil {
+		t.Fatalf("HybridEncrypt() returned error: %v", err)
+	}
+	got, err := crypto.HybridDecrypt(rand.Reader, privKeys, ciphertext, label)
+	if err != nil {
+		t.Fatalf("HybridDecrypt() returned error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("got %q, want %q", got, plaintext)
+	}
+
+	if _, err := crypto.HybridDecrypt(rand.Reader, privKeys, ciphertext, []byte("otherns/myname")); !errors.Is(err, crypto.ErrAuthentication) {
+		t.Errorf("got error %v with the wrong label, want %v", err, crypto.ErrAuthentication)
+	}
+
+	if _, err := key.Decrypt(rand.Reader, ciphertext, &rsa.PKCS1v15DecryptOptions{}); !errors.Is(err, ErrUnsupportedDecrypterOpts) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedDecrypterOpts)
+	}
+}
+
+func TestSoftPluginCurrentKey(t *testing.T) {
+	plugin := NewSoftPlugin()
+	client := startPlugin(t, plugin)
+
+	if _, err := client.Keys(context.Background()); err == nil {
+		t.Errorf("Keys() succeeded against a plugin without keys")
+	}
+
+	older, err := plugin.GenerateKey(2048, time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second) // certificate times have a resolution of one second
+	newer, err := plugin.GenerateKey(2048, time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := client.Keys(context.Background())
+	if err != nil {
+		t.Fatalf("Keys() returned error: %v", err)
+	}
+	for _, k := range keys {
+		if got, want := k.Current, k.ID == newer; got != want {
+			t.Errorf("key %q (older: %v): got current %v, want %v", k.ID, k.ID == older, got, want)
+		}
+	}
+}
+
+func TestNewClientEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "/tmp/kms.sock", "tcp://localhost:1234", "unix://"} {
+		if _, err := NewClient(endpoint, time.Second); err == nil {
+			t.Errorf("NewClient(%q) succeeded", endpoint)
+		}
+	}
+}
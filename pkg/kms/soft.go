@@ -0,0 +1,124 @@
+package kms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	kmsapi "github.com/bitnami-labs/sealed-secrets/pkg/kms/api/v1"
+)
+
+// SoftPlugin is a reference key management plugin which keeps its RSA keys in
+// memory. It is meant for tests and as an example for real plugins; it offers
+// none of the protection of an actual KMS or HSM.
+type SoftPlugin struct {
+	kmsapi.UnimplementedKeyManagementServiceServer
+
+	mu      sync.Mutex
+	keys    map[string]*softKey
+	current string
+}
+
+type softKey struct {
+	private *rsa.PrivateKey
+	cert    *x509.Certificate
+}
+
+// NewSoftPlugin returns a SoftPlugin without any keys.
+func NewSoftPlugin() *SoftPlugin {
+	return &SoftPlugin{keys: map[string]*softKey{}}
+}
+
+// AddKey adds a key to the plugin, identified by its public key fingerprint.
+// The key with the most recent certificate becomes the current one.
+func (p *SoftPlugin) AddKey(private *rsa.PrivateKey, cert *x509.Certificate) (string, error) {
+	id, err := crypto.PublicKeyFingerprint(&private.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys[id] = &softKey{private: private, cert: cert}
+	if cur, ok := p.keys[p.current]; !ok || cur.cert.NotBefore.Before(cert.NotBefore) {
+		p.current = id
+	}
+	return id, nil
+}
+
+// GenerateKey generates a new key and adds it to the plugin.
+func (p *SoftPlugin) GenerateKey(keySize int, validFor time.Duration, cn string) (string, error) {
+	private, cert, err := crypto.GeneratePrivateKeyAndCert(keySize, validFor, cn)
+	if err != nil {
+		return "", err
+	}
+	return p.AddKey(private, cert)
+}
+
+// Status implements kmsapi.KeyManagementServiceServer.
+func (p *SoftPlugin) Status(context.Context, *kmsapi.StatusRequest) (*kmsapi.StatusResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return &kmsapi.StatusResponse{Version: APIVersion, Healthz: "no keys"}, nil
+	}
+	resp := &kmsapi.StatusResponse{Version: APIVersion, Healthz: healthzOK, KeyId: p.current}
+	for id, k := range p.keys {
+		resp.Keys = append(resp.Keys, &kmsapi.PublicKey{KeyId: id, Certificate: k.cert.Raw})
+	}
+	return resp, nil
+}
+
+// Decrypt implements kmsapi.KeyManagementServiceServer.
+func (p *SoftPlugin) Decrypt(_ context.Context, req *kmsapi.DecryptRequest) (*kmsapi.DecryptResponse, error) {
+	p.mu.Lock()
+	k, ok := p.keys[req.KeyId]
+	p.mu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown key %q", req.KeyId)
+	}
+
+	plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, k.private, req.Ciphertext, req.Label)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &kmsapi.DecryptResponse{Plaintext: plaintext}, nil
+}
+
+// Serve serves a plugin on the Unix socket endpoint (unix:///path/to/socket)
+// until ctx is done. A stale socket file is removed first.
+func Serve(ctx context.Context, endpoint string, plugin kmsapi.KeyManagementServiceServer) error {
+	path, err := socketPath(endpoint)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	kmsapi.RegisterKeyManagementServiceServer(server, plugin)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	return server.Serve(lis)
+}
@@ -0,0 +1,72 @@
+package kubeseal
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"crypto/rand"
+	"encoding/pem"
+	"sort"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// parseEscrowBundle returns the escrowed keys of a PEM file, or of a
+// json/yaml ConfigMap published by the controller with --escrow-configmap.
+func parseEscrowBundle(b []byte) []*pem.Block {
+	if blocks := escrowBlocks(b); len(blocks) > 0 {
+		return blocks
+	}
+
+	var cm v1.ConfigMap
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096).Decode(&cm); err != nil || cm.Kind != "ConfigMap" {
+		return nil
+	}
+	names := make([]string, 0, len(cm.Data))
+	for name := range cm.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var blocks []*pem.Block
+	for _, name := range names {
+		blocks = append(blocks, escrowBlocks([]byte(cm.Data[name]))...)
+	}
+	return blocks
+}
+
+func escrowBlocks(b []byte) []*pem.Block {
+	var blocks []*pem.Block
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return blocks
+		}
+		if block.Type == crypto.EscrowPEMType {
+			blocks = append(blocks, block)
+		}
+	}
+}
+
+// openEscrowedKeys decrypts escrowed keys with the offline privKeys.
+func openEscrowedKeys(blocks []*pem.Block, privKeys map[string]gocrypto.PrivateKey) ([]gocrypto.PrivateKey, error) {
+	var keys []gocrypto.PrivateKey
+	for _, block := range blocks {
+		_, backup, err := crypto.OpenEscrow(rand.Reader, privKeys, block)
+		if err != nil {
+			return nil, err
+		}
+		secrets, err := parseKeySecrets(backup)
+		if err != nil {
+			return nil, err
+		}
+		pks, err := privKeysOfSecrets(secrets)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pks...)
+	}
+	return keys, nil
+}
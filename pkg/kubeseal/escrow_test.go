@@ -0,0 +1,72 @@
+package kubeseal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func TestReadPrivKeysEscrowBundle(t *testing.T) {
+	offlinePub, offlineKey := newTestKeyPairSingle(t)
+	_, escrowedKey := newTestKeyPairSingle(t)
+
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(escrowedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup, err := json.Marshal(&v1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "sealed-secrets-keyabcde"},
+		Data:       map[string][]byte{v1.TLSPrivateKeyKey: keyPEM},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := crypto.SealEscrow(rand.Reader, offlinePub, "sealed-secrets-keyabcde", backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := &v1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "sealed-secrets-escrow"},
+		Data:       map[string]string{"sealed-secrets-keyabcde": string(block)},
+	}
+	var cmYAML bytes.Buffer
+	if err := resourceOutput(&cmYAML, "yaml", scheme.Codecs, v1.SchemeGroupVersion, cm); err != nil {
+		t.Fatal(err)
+	}
+
+	offlinePEM, err := keyutil.MarshalPrivateKeyToPEM(offlineKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offlineFile := tmpfile(t, offlinePEM)
+	want, err := crypto.PublicKeyFingerprint(&escrowedKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, bundle := range map[string][]byte{"configmap": cmYAML.Bytes(), "file": block} {
+		t.Run(name, func(t *testing.T) {
+			bundleFile := tmpfile(t, bundle)
+			keys, err := readPrivKeys([]string{bundleFile, offlineFile})
+			if err != nil {
+				t.Fatalf("readPrivKeys() returned error: %v", err)
+			}
+			if _, ok := keys[want]; !ok {
+				t.Errorf("escrowed key wasn't recovered")
+			}
+
+			if _, err := readPrivKeys([]string{bundleFile}); err == nil {
+				t.Errorf("readPrivKeys() succeeded without the offline key")
+			}
+		})
+	}
+}
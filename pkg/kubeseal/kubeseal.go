@@ -3,17 +3,22 @@ package kubeseal
 import (
 	"bytes"
 	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,7 +44,13 @@ type ClientConfig interface {
 	Namespace() (string, bool, error)
 }
 
-func ParseKey(r io.Reader) (*rsa.PublicKey, error) {
+func ParseKey(r io.Reader) (gocrypto.PublicKey, error) {
+	return ParseVerifiedKey(r, nil)
+}
+
+// ParseVerifiedKey is like ParseKey, but refuses a certificate chain which
+// doesn't verify against trust.
+func ParseVerifiedKey(r io.Reader, trust *TrustAnchors) (gocrypto.PublicKey, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
@@ -55,16 +66,22 @@ func ParseKey(r io.Reader) (*rsa.PublicKey, error) {
 		return nil, errors.New("failed to read any certificates")
 	}
 
-	cert, ok := certs[0].PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("expected RSA public key but found %v", certs[0].PublicKey)
+	if err := trust.Verify(certs); err != nil {
+		return nil, err
+	}
+
+	switch certs[0].PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("expected RSA or EC public key but found %v", certs[0].PublicKey)
 	}
 
 	if time.Now().After(certs[0].NotAfter) {
 		return nil, fmt.Errorf("failed to encrypt using an expired certificate on %v", certs[0].NotAfter.Format("January 2, 2006"))
 	}
 
-	return cert, nil
+	// Seal in post-quantum hybrid mode if the certificate advertises an ML-KEM key.
+	return crypto.CertPublicKey(certs[0])
 }
 
 func prettyEncoder(codecs runtimeserializer.CodecFactory, mediaType string, gv runtime.GroupVersioner) (runtime.Encoder, error) {
@@ -137,20 +154,28 @@ func openCertURI(uri string) (io.ReadCloser, error) {
 }
 
 // openCertCluster fetches a certificate by performing an HTTP request to the controller
-// through the k8s API proxy.
-func openCertCluster(ctx context.Context, c corev1.CoreV1Interface, namespace, name string) (io.ReadCloser, error) {
+// through the k8s API proxy. The target namespace, if any, selects the certificate of
+// its key set.
+func openCertCluster(ctx context.Context, c corev1.CoreV1Interface, namespace, name, targetNamespace string) (io.ReadCloser, error) {
 	portName, err := getServicePortName(ctx, c, namespace, name)
 	if err != nil {
 		return nil, err
 	}
-	cert, err := c.Services(namespace).ProxyGet("http", name, portName, "/v1/cert.pem", nil).Stream(ctx)
+	var params map[string]string
+	if targetNamespace != "" {
+		params = map[string]string{"namespace": targetNamespace}
+	}
+	cert, err := c.Services(namespace).ProxyGet("http", name, portName, "/v1/cert.pem", params).Stream(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("cannot fetch certificate: %v", err)
 	}
 	return cert, nil
 }
 
-func OpenCert(ctx context.Context, clientConfig ClientConfig, controllerNs, controllerName string, certURL string) (io.ReadCloser, error) {
+// OpenCert opens the certificate at certURL, or fetches the one of the
+// controller for the key set of the target namespace, the default one if
+// it's empty.
+func OpenCert(ctx context.Context, clientConfig ClientConfig, controllerNs, controllerName, targetNamespace string, certURL string) (io.ReadCloser, error) {
 	if certURL != "" {
 		return openCertLocal(certURL)
 	}
@@ -164,7 +189,39 @@ func OpenCert(ctx context.Context, clientConfig ClientConfig, controllerNs, cont
 	if err != nil {
 		return nil, err
 	}
-	return openCertCluster(ctx, restClient, controllerNs, controllerName)
+	return openCertCluster(ctx, restClient, controllerNs, controllerName, targetNamespace)
+}
+
+// OpenKeys opens and parses the certificate of each of certURLs, or the one of
+// the controller for the target namespace if there are none. With several
+// certificates, the returned key seals for all of them at once. Each
+// certificate chain has to verify against trust, if set.
+func OpenKeys(ctx context.Context, clientConfig ClientConfig, controllerNs, controllerName, targetNamespace string, certURLs []string, trust *TrustAnchors) (gocrypto.PublicKey, error) {
+	if len(certURLs) == 0 {
+		certURLs = []string{""}
+	}
+
+	var recipients crypto.Recipients
+	for _, certURL := range certURLs {
+		f, err := OpenCert(ctx, clientConfig, controllerNs, controllerName, targetNamespace, certURL)
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := ParseVerifiedKey(f, trust)
+		_ = f.Close()
+		if err != nil {
+			if len(certURLs) > 1 {
+				return nil, fmt.Errorf("%s: %w", certURL, err)
+			}
+			return nil, err
+		}
+		recipients = append(recipients, pubKey)
+	}
+
+	if len(recipients) == 1 {
+		return recipients[0], nil
+	}
+	return recipients, nil
 }
 
 func readSecrets(r io.Reader) ([]*v1.Secret, error) {
@@ -217,15 +274,51 @@ func readSealedSecrets(r io.Reader) ([]*ssv1alpha1.SealedSecret, error) {
 	return secrets, nil
 }
 
+// KeysFunc returns the public key to seal the secrets of a namespace for, or
+// cluster-wide secrets if the namespace is empty.
+type KeysFunc func(namespace string) (gocrypto.PublicKey, error)
+
+// StaticKey returns a KeysFunc sealing the secrets of any namespace for pubKey.
+func StaticKey(pubKey gocrypto.PublicKey) KeysFunc {
+	return func(string) (gocrypto.PublicKey, error) {
+		return pubKey, nil
+	}
+}
+
 // Seal reads a k8s Secret resource parsed from an input reader by a given codec, encrypts all its secrets
-// with a given public key, using the name and namespace found in the input secret, unless explicitly overridden
-// by the overrideName and overrideNamespace arguments.
-func Seal(clientConfig ClientConfig, outputFormat string, in io.Reader, out io.Writer, codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKey, scope ssv1alpha1.SealingScope, allowEmptyData bool, overrideName, overrideNamespace string) error {
+// with the public key keys returns for its namespace, using the name and namespace found in the input secret,
+// unless explicitly overridden by the overrideName and overrideNamespace arguments.
+func Seal(clientConfig ClientConfig, outputFormat string, in io.Reader, out io.Writer, codecs runtimeserializer.CodecFactory, keys KeysFunc, scope ssv1alpha1.SealingScope, compression crypto.Compression, allowEmptyData bool, overrideName, overrideNamespace string) error {
+	return seal(clientConfig, outputFormat, in, out, codecs, keys, scope, compression, allowEmptyData, overrideName, overrideNamespace, nil)
+}
+
+// seal implements Seal, calling prepare (if not nil) on each secret right before sealing it.
+func seal(clientConfig ClientConfig, outputFormat string, in io.Reader, out io.Writer, codecs runtimeserializer.CodecFactory, keys KeysFunc, scope ssv1alpha1.SealingScope, compression crypto.Compression, allowEmptyData bool, overrideName, overrideNamespace string, prepare func(*v1.Secret)) error {
 	secrets, err := readSecrets(in)
 	if err != nil {
 		return err
 	}
 
+	// The keys are only looked up once the namespace of the secrets is known,
+	// and once per namespace.
+	pubKeys := map[string]gocrypto.PublicKey{}
+	pubKeyFor := func(secret *v1.Secret) (gocrypto.PublicKey, error) {
+		// Cluster-wide secrets can only be sealed for the default keys.
+		var namespace string
+		if ssv1alpha1.SecretScope(secret) != ssv1alpha1.ClusterWideScope {
+			namespace = secret.GetNamespace()
+		}
+		if pubKey, ok := pubKeys[namespace]; ok {
+			return pubKey, nil
+		}
+		pubKey, err := keys(namespace)
+		if err != nil {
+			return nil, err
+		}
+		pubKeys[namespace] = pubKey
+		return pubKey, nil
+	}
+
 	if len(secrets) == 0 {
 		return fmt.Errorf("no secrets found. Ensure the input is valid and UTF-8 encoded")
 	}
@@ -251,6 +344,13 @@ func Seal(clientConfig ClientConfig, outputFormat string, in io.Reader, out io.W
 			secret.Annotations = ssv1alpha1.UpdateScopeAnnotations(secret.Annotations, scope)
 		}
 
+		if compression != crypto.CompressionNone {
+			if secret.Annotations == nil {
+				secret.Annotations = map[string]string{}
+			}
+			secret.Annotations[ssv1alpha1.SealedSecretCompressionAnnotation] = compression.String()
+		}
+
 		if ssv1alpha1.SecretScope(secret) != ssv1alpha1.ClusterWideScope {
 			ns, namespaceSet, _ := clientConfig.Namespace()
 			// Check for namespace mismatch when namespace is explicitly set via command line
@@ -272,6 +372,14 @@ func Seal(clientConfig ClientConfig, outputFormat string, in io.Reader, out io.W
 		secret.SetDeletionTimestamp(nil)
 		secret.DeletionGracePeriodSeconds = nil
 
+		if prepare != nil {
+			prepare(secret)
+		}
+
+		pubKey, err := pubKeyFor(secret)
+		if err != nil {
+			return err
+		}
 		ssecret, err := ssv1alpha1.NewSealedSecret(codecs, pubKey, secret)
 		if err != nil {
 			return err
@@ -427,7 +535,7 @@ func decodeSealedSecret(codecs runtimeserializer.CodecFactory, b []byte) (*ssv1a
 	return &ss, nil
 }
 
-func SealMergingInto(clientConfig ClientConfig, outputFormat string, in io.Reader, filename string, codecs runtimeserializer.CodecFactory, pubKey *rsa.PublicKey, scope ssv1alpha1.SealingScope, allowEmptyData bool) error {
+func SealMergingInto(clientConfig ClientConfig, outputFormat string, in io.Reader, filename string, codecs runtimeserializer.CodecFactory, keys KeysFunc, scope ssv1alpha1.SealingScope, compression crypto.Compression, allowEmptyData bool) error {
 	// #nosec G304 -- should open user provided file
 	f, err := os.OpenFile(filename, os.O_RDWR, 0)
 	if err != nil {
@@ -446,8 +554,16 @@ func SealMergingInto(clientConfig ClientConfig, outputFormat string, in io.Reade
 		return err
 	}
 
+	// New values must be bound to their item keys exactly when the existing ones are.
+	bindItemKeys := func(secret *v1.Secret) {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[ssv1alpha1.SealedSecretBindItemKeysAnnotation] = strconv.FormatBool(ssv1alpha1.ItemKeysBound(orig))
+	}
+
 	var buf bytes.Buffer
-	if err := Seal(clientConfig, outputFormat, in, &buf, codecs, pubKey, scope, allowEmptyData, orig.Name, orig.Namespace); err != nil {
+	if err := seal(clientConfig, outputFormat, in, &buf, codecs, keys, scope, compression, allowEmptyData, orig.Name, orig.Namespace, bindItemKeys); err != nil {
 		return err
 	}
 
@@ -456,6 +572,12 @@ func SealMergingInto(clientConfig ClientConfig, outputFormat string, in io.Reade
 		return err
 	}
 
+	// The values of a template-bound sealed secret only decrypt with the exact
+	// template they were sealed with, which merging would change.
+	if orig.TemplateBound() || update.TemplateBound() {
+		return fmt.Errorf("cannot merge into a template-bound sealed secret, seal the whole secret again instead")
+	}
+
 	// merge encrypted data and metadata
 	for k, v := range update.Spec.EncryptedData {
 		orig.Spec.EncryptedData[k] = v
@@ -492,11 +614,17 @@ func SealMergingInto(clientConfig ClientConfig, outputFormat string, in io.Reade
 	return nil
 }
 
-func EncryptSecretItem(w io.Writer, secretName, ns string, data []byte, scope ssv1alpha1.SealingScope, pubKey *rsa.PublicKey) error {
+// EncryptSecretItem encrypts a single value of a sealed secret. If itemKey is
+// not empty, the value is bound to that item key and only decrypts as part of a
+// sealed secret annotated to bind its item keys.
+func EncryptSecretItem(w io.Writer, secretName, ns, itemKey string, data []byte, scope ssv1alpha1.SealingScope, compression crypto.Compression, pubKey gocrypto.PublicKey) error {
 	// TODO(mkm): refactor cluster-wide/namespace-wide to an actual enum so we can have a simple flag
 	// to refer to the scope mode that is not a tuple of booleans.
 	label := ssv1alpha1.EncryptionLabel(ns, secretName, scope)
-	out, err := crypto.HybridEncrypt(rand.Reader, pubKey, data, label)
+	if itemKey != "" {
+		label = ssv1alpha1.ItemEncryptionLabel(ns, secretName, scope, itemKey)
+	}
+	out, err := crypto.HybridEncryptWithOptions(rand.Reader, pubKey, data, label, crypto.EncryptOptions{Compression: compression})
 	if err != nil {
 		return err
 	}
@@ -514,23 +642,37 @@ func ParseFromFile(s string) (string, string) {
 	return c[0], c[1]
 }
 
-func readPrivKeysFromFile(filename string) ([]*rsa.PrivateKey, error) {
+func readPrivKeysFromFile(filename string) ([]gocrypto.PrivateKey, error) {
 	// #nosec G304 -- should open user provided file
 	b, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	return parsePrivKeys(b)
+}
 
-	res, err := parsePrivKey(b)
+// parsePrivKeys parses either a PEM encoded private key or a backup of
+// controller key secrets.
+func parsePrivKeys(b []byte) ([]gocrypto.PrivateKey, error) {
+	res, err := parsePrivKey(b, b)
 	if err == nil {
-		return []*rsa.PrivateKey{res}, nil
+		return []gocrypto.PrivateKey{res}, nil
+	}
+
+	secrets, err := parseKeySecrets(b)
+	if err != nil {
+		return nil, err
 	}
+	return privKeysOfSecrets(secrets)
+}
 
+// parseKeySecrets parses a json/yaml backup of controller key secrets, or of a v1.List of them.
+func parseKeySecrets(b []byte) ([]*v1.Secret, error) {
 	var secrets []*v1.Secret
 
 	// try to parse it as json/yaml encoded v1.List of secrets
 	var lst v1.List
-	if err = runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), b, &lst); err == nil {
+	if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), b, &lst); err == nil {
 		for _, r := range lst.Items {
 			s, err := readSecrets(bytes.NewBuffer(r.Raw))
 			if err != nil {
@@ -546,14 +688,17 @@ func readPrivKeysFromFile(filename string) ([]*rsa.PrivateKey, error) {
 		}
 		secrets = append(secrets, s...)
 	}
+	return secrets, nil
+}
 
-	var keys []*rsa.PrivateKey
+func privKeysOfSecrets(secrets []*v1.Secret) ([]gocrypto.PrivateKey, error) {
+	var keys []gocrypto.PrivateKey
 	for _, s := range secrets {
 		tlsKey, ok := s.Data["tls.key"]
 		if !ok {
 			return nil, fmt.Errorf("secret must contain a 'tls.data' key")
 		}
-		pk, err := parsePrivKey(tlsKey)
+		pk, err := parsePrivKey(tlsKey, s.Data[crypto.MLKEMSecretKey])
 		if err != nil {
 			return nil, err
 		}
@@ -563,7 +708,7 @@ func readPrivKeysFromFile(filename string) ([]*rsa.PrivateKey, error) {
 	return keys, nil
 }
 
-func readPrivKey(filename string) (*rsa.PrivateKey, error) {
+func readPrivKey(filename string) (gocrypto.PrivateKey, error) {
 	pks, err := readPrivKeysFromFile(filename)
 	if err != nil {
 		return nil, err
@@ -571,34 +716,88 @@ func readPrivKey(filename string) (*rsa.PrivateKey, error) {
 	return pks[0], nil
 }
 
-func parsePrivKey(b []byte) (*rsa.PrivateKey, error) {
+// parsePrivKey parses a PEM encoded RSA or EC private key, paired with an
+// ML-KEM-768 private key if mlkemKey contains one.
+func parsePrivKey(b, mlkemKey []byte) (gocrypto.PrivateKey, error) {
 	key, err := keyutil.ParsePrivateKeyPEM(b)
 	if err != nil {
 		return nil, err
 	}
-	switch rsaKey := key.(type) {
-	case *rsa.PrivateKey:
-		return rsaKey, nil
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return crypto.WithMLKEMPrivateKeyPEM(key, mlkemKey)
 	default:
 		return nil, fmt.Errorf("unexpected private key type %T", key)
 	}
 }
 
-func readPrivKeys(filenames []string) (map[string]*rsa.PrivateKey, error) {
-	res := map[string]*rsa.PrivateKey{}
+// readPrivKeys reads private keys from PEM files, backups of controller key
+// secrets, quorums of key share files and escrow bundles, indexed by their
+// fingerprint. Escrowed keys are decrypted with the other keys.
+func readPrivKeys(filenames []string) (map[string]gocrypto.PrivateKey, error) {
+	var pks []gocrypto.PrivateKey
+	var escrowed []*pem.Block
+	shares := keyShareSets{}
 	for _, filename := range filenames {
-		pks, err := readPrivKeysFromFile(filename)
+		// #nosec G304 -- should open user provided file
+		b, err := os.ReadFile(filename)
 		if err != nil {
 			return nil, err
 		}
-		for _, pk := range pks {
-			fingerprint, err := crypto.PublicKeyFingerprint(&pk.PublicKey)
-			if err != nil {
+		if blocks := parseEscrowBundle(b); len(blocks) > 0 {
+			escrowed = append(escrowed, blocks...)
+			continue
+		}
+		if isKeyShare(b) {
+			if err := shares.add(filename, b); err != nil {
 				return nil, err
 			}
+			continue
+		}
+		keys, err := parsePrivKeys(b)
+		if err != nil {
+			return nil, err
+		}
+		pks = append(pks, keys...)
+	}
+	keys, err := shares.combine()
+	if err != nil {
+		return nil, err
+	}
+	pks = append(pks, keys...)
+
+	res, err := indexPrivKeys(pks)
+	if err != nil {
+		return nil, err
+	}
+	if len(escrowed) == 0 {
+		return res, nil
+	}
+	keys, err = openEscrowedKeys(escrowed, res)
+	if err != nil {
+		return nil, err
+	}
+	escrowedKeys, err := indexPrivKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(res, escrowedKeys)
+	return res, nil
+}
 
-			res[fingerprint] = pk
+func indexPrivKeys(pks []gocrypto.PrivateKey) (map[string]gocrypto.PrivateKey, error) {
+	res := map[string]gocrypto.PrivateKey{}
+	for _, pk := range pks {
+		pubKey, err := crypto.PublicKey(pk)
+		if err != nil {
+			return nil, err
 		}
+		fingerprint, err := crypto.PublicKeyFingerprint(pubKey)
+		if err != nil {
+			return nil, err
+		}
+
+		res[fingerprint] = pk
 	}
 	return res, nil
 }
@@ -3,9 +3,12 @@ package kubeseal
 import (
 	"bytes"
 	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"io"
@@ -14,6 +17,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	goruntime "runtime"
 	"strings"
 	"testing"
@@ -96,10 +100,14 @@ func tmpfile(t *testing.T, contents []byte) string {
 }
 
 func TestParseKey(t *testing.T) {
-	key, err := ParseKey(strings.NewReader(testCert))
+	pubKey, err := ParseKey(strings.NewReader(testCert))
 	if err != nil {
 		t.Fatalf("Failed to parse test key: %v", err)
 	}
+	key, ok := pubKey.(*rsa.PublicKey)
+	if !ok {
+		t.Fatalf("Expected an RSA key, got %T", pubKey)
+	}
 
 	if key.N.Cmp(testModulus) != 0 {
 		t.Errorf("Unexpected key modulus: %v", key.N)
@@ -110,6 +118,22 @@ func TestParseKey(t *testing.T) {
 	}
 }
 
+func TestParseKeyEC(t *testing.T) {
+	_, cert, err := crypto.GenerateECPrivateKeyAndCert(time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw})
+
+	key, err := ParseKey(bytes.NewReader(pemCert))
+	if err != nil {
+		t.Fatalf("Failed to parse test key: %v", err)
+	}
+	if _, ok := key.(*ecdsa.PublicKey); !ok {
+		t.Errorf("Expected an EC key, got %T", key)
+	}
+}
+
 /* repeated from main here... STARTs */
 
 func testClientConfig() clientcmd.ClientConfig {
@@ -139,7 +163,7 @@ func TestOpenCertFile(t *testing.T) {
 	}
 
 	for _, certURL := range testCases {
-		f, err := OpenCert(ctx, clientConfig, controllerNs, controllerName, certURL)
+		f, err := OpenCert(ctx, clientConfig, controllerNs, controllerName, "", certURL)
 		if err != nil {
 			t.Fatalf("Error reading test cert file: %v", err)
 		}
@@ -155,6 +179,34 @@ func TestOpenCertFile(t *testing.T) {
 	}
 }
 
+func TestOpenKeysMultiple(t *testing.T) {
+	ctx := context.Background()
+	certFile1, _, cleanup1 := testingKeypairFiles(t)
+	defer cleanup1()
+	certFile2, _, cleanup2 := testingKeypairFiles(t)
+	defer cleanup2()
+
+	key, err := OpenKeys(ctx, testClientConfig(), "default", "controller", "", []string{certFile1}, nil)
+	if err != nil {
+		t.Fatalf("OpenKeys() returned error: %v", err)
+	}
+	if _, ok := key.(*rsa.PublicKey); !ok {
+		t.Errorf("Expected an RSA key for a single cert, got %T", key)
+	}
+
+	key, err = OpenKeys(ctx, testClientConfig(), "default", "controller", "", []string{certFile1, certFile2}, nil)
+	if err != nil {
+		t.Fatalf("OpenKeys() returned error: %v", err)
+	}
+	if recipients, ok := key.(crypto.Recipients); !ok || len(recipients) != 2 {
+		t.Errorf("Expected two recipients, got %v", key)
+	}
+
+	if _, err := OpenKeys(ctx, testClientConfig(), "default", "controller", "", []string{certFile1, "/does/not/exist"}, nil); err == nil {
+		t.Errorf("OpenKeys() succeeded with a missing cert")
+	}
+}
+
 func TestSealWithMultiDocSecrets(t *testing.T) {
 	key, err := ParseKey(strings.NewReader(testCert))
 	if err != nil {
@@ -198,7 +250,7 @@ func TestSealWithMultiDocSecrets(t *testing.T) {
 			t.Logf("input is:\n%s", inbuf.String())
 
 			outbuf := bytes.Buffer{}
-			if err := Seal(clientConfig, outputFormat, &inbuf, &outbuf, scheme.Codecs, key, ssv1alpha1.NamespaceWideScope, false, "", ""); err != nil {
+			if err := Seal(clientConfig, outputFormat, &inbuf, &outbuf, scheme.Codecs, StaticKey(key), ssv1alpha1.NamespaceWideScope, crypto.CompressionNone, false, "", ""); err != nil {
 				t.Fatalf("seal() returned error: %v", err)
 			}
 
@@ -273,6 +325,9 @@ func TestSeal(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "mysecret",
 					Namespace: "myns",
+					Annotations: map[string]string{
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation: "true",
+					},
 				},
 			},
 		},
@@ -289,6 +344,9 @@ func TestSeal(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "mysecret",
 					Namespace: "default",
+					Annotations: map[string]string{
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation: "true",
+					},
 				},
 			},
 		},
@@ -311,6 +369,7 @@ func TestSeal(t *testing.T) {
 					Namespace: "default",
 					Annotations: map[string]string{
 						ssv1alpha1.SealedSecretNamespaceWideAnnotation: "true",
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation:  "true",
 					},
 				},
 			},
@@ -333,7 +392,8 @@ func TestSeal(t *testing.T) {
 					Name:      "mysecret",
 					Namespace: "", // <--- we shouldn't force the default namespace for cluster wide secrets ...
 					Annotations: map[string]string{
-						ssv1alpha1.SealedSecretClusterWideAnnotation: "true",
+						ssv1alpha1.SealedSecretClusterWideAnnotation:  "true",
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation: "true",
 					},
 				},
 			},
@@ -356,7 +416,8 @@ func TestSeal(t *testing.T) {
 					Name:      "mysecret",
 					Namespace: "myns", // <--- ... but we should preserve one if specified.
 					Annotations: map[string]string{
-						ssv1alpha1.SealedSecretClusterWideAnnotation: "true",
+						ssv1alpha1.SealedSecretClusterWideAnnotation:  "true",
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation: "true",
 					},
 				},
 			},
@@ -378,6 +439,7 @@ func TestSeal(t *testing.T) {
 					Namespace: "default",
 					Annotations: map[string]string{
 						ssv1alpha1.SealedSecretNamespaceWideAnnotation: "true",
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation:  "true",
 					},
 				},
 			},
@@ -398,9 +460,30 @@ func TestSeal(t *testing.T) {
 					Name:      "mysecret",
 					Namespace: "",
 					Annotations: map[string]string{
-						ssv1alpha1.SealedSecretClusterWideAnnotation: "true",
+						ssv1alpha1.SealedSecretClusterWideAnnotation:  "true",
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation: "true",
+					},
+				},
+			},
+		},
+		{
+			secret: v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysecret",
+					Namespace: "myns",
+					Annotations: map[string]string{
+						ssv1alpha1.SealedSecretBindItemKeysAnnotation: "false",
 					},
 				},
+				Data: map[string][]byte{
+					"foo": []byte("sekret"),
+				},
+			},
+			want: ssv1alpha1.SealedSecret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysecret",
+					Namespace: "myns",
+				},
 			},
 		},
 	}
@@ -426,7 +509,7 @@ func TestSeal(t *testing.T) {
 			t.Logf("input is: %s", inbuf.String())
 
 			outbuf := bytes.Buffer{}
-			if err := Seal(clientConfig, outputFormat, &inbuf, &outbuf, scheme.Codecs, key, tc.scope, false, "", ""); err != nil {
+			if err := Seal(clientConfig, outputFormat, &inbuf, &outbuf, scheme.Codecs, StaticKey(key), tc.scope, crypto.CompressionNone, false, "", ""); err != nil {
 				t.Fatalf("seal() returned error: %v", err)
 			}
 
@@ -464,11 +547,80 @@ func TestSeal(t *testing.T) {
 	}
 }
 
+func TestSealCompression(t *testing.T) {
+	clientConfig := &mockClientConfig{namespace: "testns", namespaceSet: false}
+	pubKey, privKeys := newTestKeyPair(t)
+	value := strings.Repeat("compressible ", 1000)
+
+	var outbuf bytes.Buffer
+	inbuf := bytes.NewBuffer(mkTestSecret(t, "foo", value))
+	if err := Seal(clientConfig, "json", inbuf, &outbuf, scheme.Codecs, StaticKey(pubKey), ssv1alpha1.DefaultScope, crypto.CompressionGzip, false, "", ""); err != nil {
+		t.Fatalf("Seal() returned error: %v", err)
+	}
+
+	ss, err := decodeSealedSecret(scheme.Codecs, outbuf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ss.Spec.Template.Annotations[ssv1alpha1.SealedSecretCompressionAnnotation]; ok {
+		t.Errorf("the template has the compression annotation")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ss.Spec.EncryptedData["foo"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := crypto.CiphertextCompression(ciphertext); err != nil || got != crypto.CompressionGzip {
+		t.Errorf("got compression %v (error %v), want %v", got, err, crypto.CompressionGzip)
+	}
+	if got := len(ss.Spec.EncryptedData["foo"]); got > len(value)/2 {
+		t.Errorf("sealed value is %d bytes, want compressed", got)
+	}
+
+	secret, err := ss.Unseal(scheme.Codecs, privKeys)
+	if err != nil {
+		t.Fatalf("Unseal() returned error: %v", err)
+	}
+	if got := string(secret.Data["foo"]); got != value {
+		t.Errorf("got %q, want %q", got, value)
+	}
+}
+
+func TestSealKeysForSecretNamespace(t *testing.T) {
+	// The kubeconfig namespace differs from the ones of the secrets.
+	clientConfig := &mockClientConfig{namespace: "kubeconfig-ns", namespaceSet: false}
+	pubKey, _ := newTestKeyPair(t)
+
+	var requested []string
+	keys := func(namespace string) (gocrypto.PublicKey, error) {
+		requested = append(requested, namespace)
+		return pubKey, nil
+	}
+
+	in := bytes.Join([][]byte{
+		mkTestSecret(t, "foo", "1", withSecretName("s1"), withSecretNamespace("tenant"), asYAML(true)),
+		mkTestSecret(t, "foo", "2", withSecretName("s2"), withSecretNamespace("tenant"), asYAML(true)),
+		mkTestSecret(t, "foo", "3", withSecretName("s3"), withSecretNamespace(""), asYAML(true)),
+		mkTestSecret(t, "foo", "4", withSecretName("s4"), withSecretNamespace("tenant"), asYAML(true), withAnnotation(ssv1alpha1.SealedSecretClusterWideAnnotation, "true")),
+	}, []byte("---\n"))
+
+	var outbuf bytes.Buffer
+	if err := Seal(clientConfig, "yaml", bytes.NewReader(in), &outbuf, scheme.Codecs, keys, ssv1alpha1.DefaultScope, crypto.CompressionNone, false, "", ""); err != nil {
+		t.Fatalf("Seal() returned error: %v", err)
+	}
+
+	// Keys are looked up once per namespace, and cluster-wide secrets are
+	// sealed for the default keys.
+	if want := []string{"tenant", "kubeconfig-ns", ""}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("got keys requested for namespaces %q, want %q", requested, want)
+	}
+}
+
 type mkTestSecretOpt func(*mkTestSecretOpts)
 type mkTestSecretOpts struct {
 	secretName      string
 	secretNamespace string
 	asYAML          bool
+	annotations     map[string]string
 }
 
 func withSecretName(n string) mkTestSecretOpt {
@@ -483,6 +635,15 @@ func withSecretNamespace(n string) mkTestSecretOpt {
 	}
 }
 
+func withAnnotation(k, v string) mkTestSecretOpt {
+	return func(o *mkTestSecretOpts) {
+		if o.annotations == nil {
+			o.annotations = map[string]string{}
+		}
+		o.annotations[k] = v
+	}
+}
+
 func asYAML(y bool) mkTestSecretOpt {
 	return func(o *mkTestSecretOpts) {
 		o.asYAML = y
@@ -513,6 +674,9 @@ func mkTestSecret(t *testing.T, key, value string, opts ...mkTestSecretOpt) []by
 			key: []byte(value),
 		},
 	}
+	for k, v := range o.annotations {
+		secret.Annotations[k] = v
+	}
 
 	contentType := runtime.ContentTypeJSON
 	if o.asYAML {
@@ -531,12 +695,12 @@ func mkTestSecret(t *testing.T, key, value string, opts ...mkTestSecretOpt) []by
 	return inbuf.Bytes()
 }
 
-func mkTestSealedSecret(t *testing.T, pubKey *rsa.PublicKey, key, value string, opts ...mkTestSecretOpt) []byte {
+func mkTestSealedSecret(t *testing.T, pubKey gocrypto.PublicKey, key, value string, opts ...mkTestSecretOpt) []byte {
 	clientConfig := &mockClientConfig{namespace: "testns", namespaceSet: false}
 	outputFormat := "json"
 	inbuf := bytes.NewBuffer(mkTestSecret(t, key, value, opts...))
 	var outbuf bytes.Buffer
-	if err := Seal(clientConfig, outputFormat, inbuf, &outbuf, scheme.Codecs, pubKey, ssv1alpha1.DefaultScope, false, "", ""); err != nil {
+	if err := Seal(clientConfig, outputFormat, inbuf, &outbuf, scheme.Codecs, StaticKey(pubKey), ssv1alpha1.DefaultScope, crypto.CompressionNone, false, "", ""); err != nil {
 		t.Fatalf("seal() returned error: %v", err)
 	}
 
@@ -544,7 +708,7 @@ func mkTestSealedSecret(t *testing.T, pubKey *rsa.PublicKey, key, value string,
 }
 
 // TODO(mkm): rename newTestKeyPair to newTestKeyPairs.
-func newTestKeyPair(t *testing.T) (*rsa.PublicKey, map[string]*rsa.PrivateKey) {
+func newTestKeyPair(t *testing.T) (*rsa.PublicKey, map[string]gocrypto.PrivateKey) {
 	privKey, _, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "testcn")
 	if err != nil {
 		t.Fatal(err)
@@ -555,7 +719,7 @@ func newTestKeyPair(t *testing.T) (*rsa.PublicKey, map[string]*rsa.PrivateKey) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	privKeys := map[string]*rsa.PrivateKey{fp: privKey}
+	privKeys := map[string]gocrypto.PrivateKey{fp: privKey}
 
 	return pubKey, privKeys
 }
@@ -572,7 +736,7 @@ func TestUnseal(t *testing.T) {
 		t.Fatal("assuming only one test key-pair")
 	}
 	for _, key := range privKeys {
-		err := pem.Encode(pkFile, &pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		err := pem.Encode(pkFile, &pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey))})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -604,6 +768,61 @@ func TestUnseal(t *testing.T) {
 	}
 }
 
+func TestUnsealPQ(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privKey, cert, err := crypto.GeneratePQPrivateKeyAndCert(rsaKey, time.Hour, "testcn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubKey, err := ParseKey(bytes.NewReader(pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw})))
+	if err != nil {
+		t.Fatalf("Failed to parse test key: %v", err)
+	}
+	if _, ok := pubKey.(*crypto.PQPublicKey); !ok {
+		t.Fatalf("Expected a post-quantum hybrid key, got %T", pubKey)
+	}
+
+	tlsKey, err := keyutil.MarshalPrivateKeyToPEM(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec := &v1.Secret{
+		Data: map[string][]byte{
+			"tls.key":             tlsKey,
+			crypto.MLKEMSecretKey: crypto.MarshalMLKEMPrivateKeyPEM(privKey.MLKEM),
+		},
+	}
+	pkFile, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(pkFile.Name())
+	if err := resourceOutput(pkFile, "json", scheme.Codecs, v1.SchemeGroupVersion, sec); err != nil {
+		t.Fatal(err)
+	}
+	pkFile.Close()
+
+	ss := mkTestSealedSecret(t, pubKey, "foo", "secret1")
+
+	var buf bytes.Buffer
+	if err := UnsealSealedSecret(&buf, bytes.NewBuffer(ss), []string{pkFile.Name()}, "json", scheme.Codecs); err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := readSecrets(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if got, want := string(secret.Data["foo"]), "secret1"; got != want {
+			t.Fatalf("got: %q, want: %q", got, want)
+		}
+	}
+}
+
 func TestUnsealList(t *testing.T) {
 	pubKey, privKeys := newTestKeyPair(t)
 	pkFile, err := os.CreateTemp("", "")
@@ -620,7 +839,7 @@ func TestUnsealList(t *testing.T) {
 
 	var secrets [][]byte
 	for _, key := range privKeys {
-		b := pem.EncodeToMemory(&pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		b := pem.EncodeToMemory(&pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey))})
 		buf, err := runtime.Encode(prettyEnc, &v1.Secret{Data: map[string][]byte{"tls.key": b}})
 		if err != nil {
 			t.Fatal(err)
@@ -681,7 +900,7 @@ func TestMergeInto(t *testing.T) {
 		f.Close()
 
 		buf := bytes.NewBuffer(newSecret)
-		if err := SealMergingInto(clientConfig, outputFormat, buf, f.Name(), scheme.Codecs, pubKey, ssv1alpha1.DefaultScope, false); err != nil {
+		if err := SealMergingInto(clientConfig, outputFormat, buf, f.Name(), scheme.Codecs, StaticKey(pubKey), ssv1alpha1.DefaultScope, crypto.CompressionNone, false); err != nil {
 			t.Fatal(err)
 		}
 
@@ -764,6 +983,42 @@ func TestMergeInto(t *testing.T) {
 			mkTestSealedSecret(t, pubKey, "bar", "secret2"),
 		)
 	})
+
+	t.Run("legacy item keys", func(t *testing.T) {
+		merged := merge(t,
+			mkTestSecret(t, "foo", "secret1"),
+			mkTestSealedSecret(t, pubKey, "bar", "secret2", withAnnotation(ssv1alpha1.SealedSecretBindItemKeysAnnotation, "false")),
+		)
+		if ssv1alpha1.ItemKeysBound(merged) {
+			t.Errorf("merging into a sealed secret made it bind its item keys")
+		}
+	})
+
+	t.Run("template-bound", func(t *testing.T) {
+		bound := withAnnotation(ssv1alpha1.SealedSecretBindTemplateAnnotation, "true")
+		for _, tc := range []struct {
+			name             string
+			secret, original []byte
+		}{
+			{"original", mkTestSecret(t, "foo", "secret1"), mkTestSealedSecret(t, pubKey, "bar", "secret2", bound)},
+			{"update", mkTestSecret(t, "foo", "secret1", bound), mkTestSealedSecret(t, pubKey, "bar", "secret2")},
+		} {
+			f, err := os.CreateTemp("", "*.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.Write(tc.original); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			err = SealMergingInto(clientConfig, outputFormat, bytes.NewBuffer(tc.secret), f.Name(), scheme.Codecs, StaticKey(pubKey), ssv1alpha1.DefaultScope, crypto.CompressionNone, false)
+			if err == nil {
+				t.Errorf("%s: SealMergingInto() succeeded with a template-bound sealed secret", tc.name)
+			}
+		}
+	})
 }
 
 // writeTempFile creates a temporary file, writes data into it and closes it.
@@ -812,14 +1067,14 @@ func testingKeypairFiles(t *testing.T) (string, string, func()) {
 	}
 }
 
-func sealTestItem(certFilename, secretNS, secretName, secretValue string, scope ssv1alpha1.SealingScope) (string, error) {
+func sealTestItem(certFilename, secretNS, secretName, itemKey, secretValue string, scope ssv1alpha1.SealingScope) (string, error) {
 	var buf bytes.Buffer
 
 	ctx := context.Background()
 	clientConfig := testClientConfig()
 	controllerNs := "default"
 	controllerName := "controller"
-	f, err := OpenCert(ctx, clientConfig, controllerNs, controllerName, certFilename)
+	f, err := OpenCert(ctx, clientConfig, controllerNs, controllerName, "", certFilename)
 	if err != nil {
 		return "", err
 	}
@@ -829,7 +1084,7 @@ func sealTestItem(certFilename, secretNS, secretName, secretValue string, scope
 		return "", err
 	}
 
-	if err := EncryptSecretItem(&buf, secretName, secretNS, []byte(secretValue), scope, pubKey); err != nil {
+	if err := EncryptSecretItem(&buf, secretName, secretNS, itemKey, []byte(secretValue), scope, crypto.CompressionNone, pubKey); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -850,6 +1105,7 @@ func TestRaw(t *testing.T) {
 		ns        string
 		name      string
 		scope     ssv1alpha1.SealingScope
+		itemKey   string
 		unsealErr string
 	}{
 		// strict scope
@@ -871,13 +1127,19 @@ func TestRaw(t *testing.T) {
 		{scope: ssv1alpha1.ClusterWideScope, ns: secretNS, name: "aBadName"},
 		{scope: ssv1alpha1.ClusterWideScope, ns: "", name: ""},
 		{scope: ssv1alpha1.ClusterWideScope, ns: "", name: "aBadName"},
+
+		// bound to the item key
+		{ns: secretNS, name: secretName, itemKey: secretItem},
+		{ns: secretNS, name: secretName, itemKey: "aBadItem", unsealErr: "no key could decrypt secret"},
+		{scope: ssv1alpha1.ClusterWideScope, ns: secretNS, name: secretName, itemKey: secretItem},
+		{scope: ssv1alpha1.ClusterWideScope, ns: secretNS, name: secretName, itemKey: "aBadItem", unsealErr: "no key could decrypt secret"},
 	}
 
 	for i, tc := range testCases {
 		// encrypt an item with data from the testCase and put it
 		// in a sealed secret with the metadata from the constants above
 		t.Run(fmt.Sprint(i), func(t *testing.T) {
-			enc, err := sealTestItem(certFilename, tc.ns, tc.name, secretValue, tc.scope)
+			enc, err := sealTestItem(certFilename, tc.ns, tc.name, tc.itemKey, secretValue, tc.scope)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -896,6 +1158,9 @@ func TestRaw(t *testing.T) {
 					},
 				},
 			}
+			if tc.itemKey != "" {
+				ss.Annotations[ssv1alpha1.SealedSecretBindItemKeysAnnotation] = "true"
+			}
 
 			privKeys, err := readPrivKeys([]string{privKeyFilename})
 			if err != nil {
@@ -958,7 +1223,7 @@ func TestReadPrivKeySecret(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if got, want := pkr.D.String(), pkw.D.String(); got != want {
+	if got, want := pkr.(*rsa.PrivateKey).D.String(), pkw.D.String(); got != want {
 		t.Errorf("got: %q, want: %q", got, want)
 	}
 }
@@ -986,7 +1251,7 @@ func TestReadPrivKeyPEM(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if got, want := pkr.D.String(), pkw.D.String(); got != want {
+	if got, want := pkr.(*rsa.PrivateKey).D.String(), pkw.D.String(); got != want {
 		t.Errorf("got: %q, want: %q", got, want)
 	}
 }
@@ -1086,7 +1351,7 @@ func TestNamespaceMismatchValidation(t *testing.T) {
 			}
 
 			outbuf := bytes.Buffer{}
-			err := Seal(mockClientConfig, outputFormat, &inbuf, &outbuf, scheme.Codecs, key, ssv1alpha1.DefaultScope, false, "", "")
+			err := Seal(mockClientConfig, outputFormat, &inbuf, &outbuf, scheme.Codecs, StaticKey(key), ssv1alpha1.DefaultScope, crypto.CompressionNone, false, "", "")
 
 			if tc.expectedError != "" {
 				if err == nil {
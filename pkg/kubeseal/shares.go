@@ -0,0 +1,247 @@
+package kubeseal
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/keyutil"
+)
+
+// keySharePEMType is the PEM block type of key share files.
+const keySharePEMType = "SEALED SECRETS KEY SHARE"
+
+// sealingKeySelector selects the key secrets of the controller, see controller.SealedSecretsKeyLabel.
+const sealingKeySelector = "sealedsecrets.bitnami.com/sealed-secrets-key"
+
+// ExportKeyShares splits the sealing keys read from keysFilenames, or those of
+// the controller if there are none, into n share files written to dir. Any
+// threshold of them recover the keys when passed to --recovery-private-key.
+// It returns the names of the files written.
+func ExportKeyShares(ctx context.Context, clientConfig ClientConfig, controllerNs string, keysFilenames []string, n, threshold int, dir string) ([]string, error) {
+	var secrets []*v1.Secret
+	var err error
+	if len(keysFilenames) > 0 {
+		secrets, err = readKeySecrets(keysFilenames)
+	} else {
+		secrets, err = fetchKeySecrets(ctx, clientConfig, controllerNs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return nil, errors.New("no sealing keys found")
+	}
+
+	backup, err := marshalKeyBackup(secrets)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := crypto.SplitSecret(rand.Reader, backup, n, threshold)
+	clear(backup)
+	if err != nil {
+		return nil, err
+	}
+
+	setID := make([]byte, 8)
+	if _, err := rand.Read(setID); err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	for i, share := range shares {
+		block := &pem.Block{
+			Type: keySharePEMType,
+			Headers: map[string]string{
+				"Set":       hex.EncodeToString(setID),
+				"Share":     fmt.Sprintf("%d/%d", i+1, n),
+				"Threshold": strconv.Itoa(threshold),
+			},
+			Bytes: share,
+		}
+		filename := filepath.Join(dir, fmt.Sprintf("sealed-secrets-key-share-%d-of-%d.pem", i+1, n))
+		if err := writeKeyShare(filename, block); err != nil {
+			return filenames, err
+		}
+		filenames = append(filenames, filename)
+	}
+	return filenames, nil
+}
+
+func writeKeyShare(filename string, block *pem.Block) error {
+	// #nosec G304 -- should open user provided file
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, block); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readKeySecrets reads key secrets from backups, turning PEM encoded private keys into secrets.
+func readKeySecrets(filenames []string) ([]*v1.Secret, error) {
+	var secrets []*v1.Secret
+	for _, filename := range filenames {
+		// #nosec G304 -- should open user provided file
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if pk, err := parsePrivKey(b, b); err == nil {
+			s, err := keySecret(pk)
+			if err != nil {
+				return nil, err
+			}
+			secrets = append(secrets, s)
+			continue
+		}
+		s, err := parseKeySecrets(b)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, s...)
+	}
+	return secrets, nil
+}
+
+func fetchKeySecrets(ctx context.Context, clientConfig ClientConfig, controllerNs string) ([]*v1.Secret, error) {
+	conf, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restClient, err := corev1.NewForConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	list, err := restClient.Secrets(controllerNs).List(ctx, metav1.ListOptions{LabelSelector: sealingKeySelector})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list sealing keys: %w", err)
+	}
+	var secrets []*v1.Secret
+	for i := range list.Items {
+		secrets = append(secrets, &list.Items[i])
+	}
+	return secrets, nil
+}
+
+// keySecret returns a key secret holding pk, as the controller would store it.
+func keySecret(pk gocrypto.PrivateKey) (*v1.Secret, error) {
+	data := map[string][]byte{}
+	if pq, ok := pk.(*crypto.PQPrivateKey); ok {
+		pk = pq.Classical
+		data[crypto.MLKEMSecretKey] = crypto.MarshalMLKEMPrivateKeyPEM(pq.MLKEM)
+	}
+	keybytes, err := keyutil.MarshalPrivateKeyToPEM(pk)
+	if err != nil {
+		return nil, err
+	}
+	data[v1.TLSPrivateKeyKey] = keybytes
+	return &v1.Secret{Data: data, Type: v1.SecretTypeTLS}, nil
+}
+
+// marshalKeyBackup encodes key secrets as a v1.List, the way
+// `kubectl get secret -o json` backs them up, leaving out server-side metadata.
+func marshalKeyBackup(secrets []*v1.Secret) ([]byte, error) {
+	lst := v1.List{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"}}
+	for _, s := range secrets {
+		raw, err := json.Marshal(&v1.Secret{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        s.Name,
+				Namespace:   s.Namespace,
+				Labels:      s.Labels,
+				Annotations: s.Annotations,
+			},
+			Data: s.Data,
+			Type: s.Type,
+		})
+		if err != nil {
+			return nil, err
+		}
+		lst.Items = append(lst.Items, runtime.RawExtension{Raw: raw})
+	}
+	return json.Marshal(&lst)
+}
+
+func isKeyShare(b []byte) bool {
+	block, _ := pem.Decode(b)
+	return block != nil && block.Type == keySharePEMType
+}
+
+// keyShareSets collects key share files by the export they come from.
+type keyShareSets map[string]*keyShareSet
+
+type keyShareSet struct {
+	threshold int
+	shares    [][]byte
+}
+
+func (s keyShareSets) add(filename string, b []byte) error {
+	block, _ := pem.Decode(b)
+	id := block.Headers["Set"]
+	threshold, err := strconv.Atoi(block.Headers["Threshold"])
+	if id == "" || err != nil {
+		return fmt.Errorf("%s: malformed key share", filename)
+	}
+	set, ok := s[id]
+	if !ok {
+		set = &keyShareSet{threshold: threshold}
+		s[id] = set
+	}
+	if set.threshold != threshold {
+		return fmt.Errorf("%s: key share has a threshold of %d, other shares of set %s have %d", filename, threshold, id, set.threshold)
+	}
+	set.shares = append(set.shares, block.Bytes)
+	return nil
+}
+
+// combine recovers the private keys of each set of key shares.
+func (s keyShareSets) combine() ([]gocrypto.PrivateKey, error) {
+	ids := make([]string, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var keys []gocrypto.PrivateKey
+	for _, id := range ids {
+		set := s[id]
+		if len(set.shares) < set.threshold {
+			return nil, fmt.Errorf("%d key shares of set %s are needed to recover the keys, got %d", set.threshold, id, len(set.shares))
+		}
+		backup, err := crypto.CombineShares(set.shares)
+		if err != nil {
+			return nil, fmt.Errorf("key shares of set %s: %w", id, err)
+		}
+		secrets, err := parseKeySecrets(backup)
+		if err == nil && len(secrets) == 0 {
+			err = errors.New("no keys")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot recover the keys from the shares of set %s, some of them may be corrupt: %w", id, err)
+		}
+		pks, err := privKeysOfSecrets(secrets)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pks...)
+	}
+	return keys, nil
+}
@@ -0,0 +1,80 @@
+package kubeseal
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/util/keyutil"
+)
+
+func TestExportKeyShares(t *testing.T) {
+	ctx := context.Background()
+	_, pk1 := newTestKeyPairSingle(t)
+	_, pk2 := newTestKeyPairSingle(t)
+
+	var keyFiles []string
+	for _, pk := range []any{pk1, pk2} {
+		b, err := keyutil.MarshalPrivateKeyToPEM(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyFiles = append(keyFiles, tmpfile(t, b))
+	}
+	want, err := readPrivKeys(keyFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	shares, err := ExportKeyShares(ctx, testClientConfig(), "kube-system", keyFiles, 5, 3, dir)
+	if err != nil {
+		t.Fatalf("ExportKeyShares() returned error: %v", err)
+	}
+	if got, want := len(shares), 5; got != want {
+		t.Fatalf("got %d share files, want %d", got, want)
+	}
+	fi, err := os.Stat(shares[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("got share file mode %s, want %s", got, want)
+	}
+
+	got, err := readPrivKeys([]string{shares[4], shares[0], shares[2]})
+	if err != nil {
+		t.Fatalf("readPrivKeys() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered keys don't match the exported ones")
+	}
+
+	// Shares and other backups can be mixed.
+	got, err = readPrivKeys([]string{shares[1], keyFiles[0], shares[3], shares[2]})
+	if err != nil {
+		t.Fatalf("readPrivKeys() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered keys don't match the exported ones")
+	}
+
+	if _, err := readPrivKeys(shares[:2]); err == nil {
+		t.Errorf("readPrivKeys() succeeded below the threshold")
+	}
+
+	// Shares of another export don't make up for missing ones.
+	other, err := ExportKeyShares(ctx, testClientConfig(), "kube-system", keyFiles[:1], 3, 2, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPrivKeys([]string{shares[0], shares[1], other[0]}); err == nil {
+		t.Errorf("readPrivKeys() combined shares of different exports")
+	}
+
+	// Existing share files aren't overwritten.
+	if _, err := ExportKeyShares(ctx, testClientConfig(), "kube-system", keyFiles, 5, 3, dir); !os.IsExist(err) {
+		t.Errorf("got error %v, want an already exists error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package kubeseal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/util/cert"
+)
+
+// ErrUntrustedCertificate is returned when a sealing certificate doesn't match the trust anchors.
+var ErrUntrustedCertificate = errors.New("untrusted sealing certificate")
+
+// TrustAnchors are what a sealing certificate chain has to verify against
+// before it gets used. A nil *TrustAnchors trusts any certificate.
+type TrustAnchors struct {
+	// Roots, if set, are the CAs the chain has to verify against.
+	Roots *x509.CertPool
+	// Fingerprints, if set, are SHA-256 certificate fingerprints, one of which
+	// has to match a certificate of the chain.
+	Fingerprints [][]byte
+}
+
+// NewTrustAnchors builds trust anchors from the cluster CA of the kubeconfig if
+// clusterCA is set, the PEM CA bundles in bundleFiles and the pinned
+// fingerprints. It returns nil if none of them is given.
+func NewTrustAnchors(clientConfig ClientConfig, clusterCA bool, bundleFiles, fingerprints []string) (*TrustAnchors, error) {
+	if !clusterCA && len(bundleFiles) == 0 && len(fingerprints) == 0 {
+		return nil, nil
+	}
+
+	t := &TrustAnchors{}
+	var bundles [][]byte
+	if clusterCA {
+		conf, err := clientConfig.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		data := conf.CAData
+		if len(data) == 0 && conf.CAFile != "" {
+			if data, err = os.ReadFile(conf.CAFile); err != nil {
+				return nil, err
+			}
+		}
+		if len(data) == 0 {
+			return nil, errors.New("the kubeconfig has no cluster CA to verify the sealing certificate against")
+		}
+		bundles = append(bundles, data)
+	}
+	for _, f := range bundleFiles {
+		// #nosec G304 -- should open user provided file
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, data)
+	}
+	if len(bundles) > 0 {
+		t.Roots = x509.NewCertPool()
+		for _, data := range bundles {
+			certs, err := cert.ParseCertsPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read trust bundle: %w", err)
+			}
+			for _, c := range certs {
+				t.Roots.AddCert(c)
+			}
+		}
+	}
+
+	for _, s := range fingerprints {
+		fp, err := ParseFingerprint(s)
+		if err != nil {
+			return nil, err
+		}
+		t.Fingerprints = append(t.Fingerprints, fp)
+	}
+	return t, nil
+}
+
+// ParseFingerprint parses a hex encoded SHA-256 certificate fingerprint, as
+// printed by `openssl x509 -noout -fingerprint -sha256`. Colons are optional.
+func ParseFingerprint(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "sha256:")
+	fp, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || len(fp) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", s)
+	}
+	return fp, nil
+}
+
+// Verify checks a certificate chain, leaf first, against the trust anchors.
+func (t *TrustAnchors) Verify(certs []*x509.Certificate) error {
+	if t == nil {
+		return nil
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("%w: no certificate", ErrUntrustedCertificate)
+	}
+
+	if t.Roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         t.Roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err != nil {
+			return fmt.Errorf("%w: %v", ErrUntrustedCertificate, err)
+		}
+	}
+
+	if len(t.Fingerprints) > 0 && !t.pinned(certs) {
+		sum := sha256.Sum256(certs[0].Raw)
+		return fmt.Errorf("%w: no certificate of the chain matches a pinned fingerprint (leaf is %s)", ErrUntrustedCertificate, hex.EncodeToString(sum[:]))
+	}
+	return nil
+}
+
+// VerifyPEM is like Verify, for a PEM encoded certificate chain.
+func (t *TrustAnchors) VerifyPEM(data []byte) error {
+	if t == nil {
+		return nil
+	}
+	certs, err := cert.ParseCertsPEM(data)
+	if err != nil {
+		return err
+	}
+	return t.Verify(certs)
+}
+
+func (t *TrustAnchors) pinned(certs []*x509.Certificate) bool {
+	for _, c := range certs {
+		sum := sha256.Sum256(c.Raw)
+		for _, fp := range t.Fingerprints {
+			if bytes.Equal(sum[:], fp) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package kubeseal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	certUtil "k8s.io/client-go/util/cert"
+)
+
+// issueTestChain returns a CA certificate and a sealing certificate issued by it.
+func issueTestChain(t *testing.T) (ca, leaf *x509.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "sealed-secrets"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+	if der, err = x509.CreateCertificate(rand.Reader, leafTmpl, ca, key.Public(), caKey); err != nil {
+		t.Fatal(err)
+	}
+	if leaf, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	return ca, leaf
+}
+
+func encodeCerts(certs ...*x509.Certificate) []byte {
+	var data []byte
+	for _, c := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: c.Raw})...)
+	}
+	return data
+}
+
+func fingerprint(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTrustAnchorsVerify(t *testing.T) {
+	ca, leaf := issueTestChain(t)
+	otherCA, _ := issueTestChain(t)
+	chain := []*x509.Certificate{leaf, ca}
+
+	testCases := []struct {
+		name         string
+		bundle       []*x509.Certificate
+		fingerprints []string
+		certs        []*x509.Certificate
+		trusted      bool
+	}{
+		{name: "issuing CA", bundle: []*x509.Certificate{ca}, certs: chain, trusted: true},
+		{name: "leaf only", bundle: []*x509.Certificate{ca}, certs: []*x509.Certificate{leaf}, trusted: true},
+		{name: "other CA", bundle: []*x509.Certificate{otherCA}, certs: chain, trusted: false},
+		{name: "pinned leaf", fingerprints: []string{fingerprint(leaf)}, certs: chain, trusted: true},
+		{name: "pinned CA", fingerprints: []string{fingerprint(otherCA), fingerprint(ca)}, certs: chain, trusted: true},
+		{name: "pinned other CA", fingerprints: []string{fingerprint(otherCA)}, certs: chain, trusted: false},
+		{name: "issuing CA but not pinned", bundle: []*x509.Certificate{ca}, fingerprints: []string{fingerprint(otherCA)}, certs: chain, trusted: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bundles []string
+			if len(tc.bundle) > 0 {
+				bundles = append(bundles, tmpfile(t, encodeCerts(tc.bundle...)))
+			}
+			trust, err := NewTrustAnchors(testClientConfig(), false, bundles, tc.fingerprints)
+			if err != nil {
+				t.Fatalf("NewTrustAnchors() returned error: %v", err)
+			}
+			err = trust.Verify(tc.certs)
+			if tc.trusted && err != nil {
+				t.Errorf("Verify() returned error: %v", err)
+			}
+			if !tc.trusted && !errors.Is(err, ErrUntrustedCertificate) {
+				t.Errorf("got error %v, want %v", err, ErrUntrustedCertificate)
+			}
+		})
+	}
+}
+
+func TestNewTrustAnchors(t *testing.T) {
+	trust, err := NewTrustAnchors(testClientConfig(), false, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trust != nil {
+		t.Errorf("got trust anchors without any being configured")
+	}
+	if err := trust.Verify(nil); err != nil {
+		t.Errorf("nil trust anchors refused a certificate: %v", err)
+	}
+
+	// The test kubeconfig has no cluster CA.
+	if _, err := NewTrustAnchors(testClientConfig(), true, nil, nil); err == nil {
+		t.Errorf("NewTrustAnchors() succeeded without a cluster CA")
+	}
+	if _, err := NewTrustAnchors(testClientConfig(), false, nil, []string{"abcd"}); err == nil {
+		t.Errorf("NewTrustAnchors() accepted a short fingerprint")
+	}
+}
+
+func TestParseFingerprint(t *testing.T) {
+	_, leaf := issueTestChain(t)
+	want := fingerprint(leaf)
+
+	var colons []string
+	for i := 0; i < len(want); i += 2 {
+		colons = append(colons, strings.ToUpper(want[i:i+2]))
+	}
+	for _, s := range []string{want, strings.Join(colons, ":"), "sha256:" + want} {
+		fp, err := ParseFingerprint(s)
+		if err != nil {
+			t.Fatalf("ParseFingerprint(%q) returned error: %v", s, err)
+		}
+		if got := hex.EncodeToString(fp); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestOpenKeysUntrusted(t *testing.T) {
+	ctx := context.Background()
+	ca, leaf := issueTestChain(t)
+	otherCA, _ := issueTestChain(t)
+	certFile := tmpfile(t, encodeCerts(leaf, ca))
+
+	trust := &TrustAnchors{Roots: x509.NewCertPool()}
+	trust.Roots.AddCert(ca)
+	if _, err := OpenKeys(ctx, testClientConfig(), "default", "controller", "", []string{certFile}, trust); err != nil {
+		t.Errorf("OpenKeys() returned error: %v", err)
+	}
+
+	trust = &TrustAnchors{Roots: x509.NewCertPool()}
+	trust.Roots.AddCert(otherCA)
+	if _, err := OpenKeys(ctx, testClientConfig(), "default", "controller", "", []string{certFile}, trust); !errors.Is(err, ErrUntrustedCertificate) {
+		t.Errorf("got error %v, want %v", err, ErrUntrustedCertificate)
+	}
+}